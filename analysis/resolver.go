@@ -0,0 +1,404 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/lczm/as/ast"
+	"github.com/lczm/as/environment"
+	"github.com/lczm/as/errors"
+	"github.com/lczm/as/token"
+)
+
+// The resolver works out where every local variable lives before the
+// program is run, so that the interpreter does not have to search for
+// it by name. Every scope here lines up with an environment that the
+// interpreter creates :
+// - blocks get a scope of their own
+// - function calls get a scope with the parameters, the body shares it
+// - methods get a scope with 'this' around the function scope
+// - methods of a struct with a parent get a scope with 'super' around them
+// - every iteration of a for-in loop gets a scope with the loop variables
+// The top level is not a scope, names declared there are globals and are
+// still looked up by name.
+type Resolver struct {
+	scopes []*scope
+	// Names declared at the top level, and whether they have been
+	// declared yet at the point that the resolver is at
+	globals map[string]bool
+	// Names that are already defined before the statements are run,
+	// i.e. builtins and what was declared in earlier inputs of the repl.
+	// This can be nil.
+	environment *environment.Environment
+	// Greater than 0 while resolving code that does not run straight away,
+	// i.e. function bodies, which can use globals declared after them
+	deferred int
+	// Greater than 0 while resolving the attribute initializers of a struct,
+	// these are only run when the struct is instantiated
+	attributes int
+
+	Errors   []errors.Error
+	Warnings []errors.Error
+}
+
+type scope struct {
+	// The slot of every name that is declared in the scope, names are
+	// given a slot before they are declared so that closures can refer
+	// to locals that are declared after them
+	slots map[string]int
+	// Whether the name has been declared yet at the point that the
+	// resolver is at
+	declared map[string]bool
+	size     int
+	// Function scopes are run later than they are declared in
+	function bool
+}
+
+func newScope(function bool) *scope {
+	return &scope{
+		slots:    make(map[string]int),
+		declared: make(map[string]bool),
+		function: function,
+	}
+}
+
+// Gives a name the next free slot, names that are declared more than
+// once in a scope keep the same slot
+func (s *scope) add(name string) int {
+	if slot, ok := s.slots[name]; ok {
+		return slot
+	}
+	s.slots[name] = s.size
+	s.size++
+	return s.slots[name]
+}
+
+// Declares a name at a slot that the interpreter fills in by position,
+// i.e. parameters and loop variables
+func (s *scope) define(name string, slot int) {
+	s.slots[name] = slot
+	s.declared[name] = true
+	if slot >= s.size {
+		s.size = slot + 1
+	}
+}
+
+// Resolves the statements, the locations are written into the ast
+// and any problems that are found are kept in Errors and Warnings
+func (r *Resolver) Resolve(statements []ast.Statement) {
+	for _, stmt := range statements {
+		ast.Declarations(stmt, func(name string) {
+			if _, ok := r.globals[name]; !ok {
+				r.globals[name] = false
+			}
+		})
+	}
+	for _, stmt := range statements {
+		r.resolveStatement(stmt)
+	}
+
+	sort.SliceStable(r.Errors, func(a, b int) bool {
+		return r.Errors[a].Span().Offset < r.Errors[b].Span().Offset
+	})
+}
+
+func (r *Resolver) resolveStatement(stmt ast.Statement) {
+	switch stmt := stmt.(type) {
+	case *ast.StatementExpression:
+		r.resolveExpression(stmt.Expr)
+	case *ast.PrintStatement:
+		r.resolveExpression(stmt.Expr)
+	case *ast.IfStatement:
+		r.resolveExpression(stmt.Condition)
+		r.resolveStatement(stmt.Then)
+		if stmt.Else != nil {
+			r.resolveStatement(stmt.Else)
+		}
+	case *ast.WhileStatement:
+		r.resolveExpression(stmt.Condition)
+		r.resolveStatement(stmt.Body)
+	case *ast.ForStatement:
+		// The variable is declared in the scope that the loop is in
+		if variable, ok := stmt.Variable.(*ast.VariableStatement); ok {
+			r.resolveExpression(variable.Initializer)
+			variable.Location = r.declare(variable.Name, false)
+		} else if stmt.Variable != nil {
+			r.resolveStatement(stmt.Variable)
+		}
+		r.resolveExpression(stmt.Condition)
+		r.resolveStatement(stmt.Body)
+		r.resolveExpression(stmt.Effect)
+	case *ast.ForInStatement:
+		r.resolveForInStatement(stmt)
+	case *ast.BlockStatement:
+		r.beginScope(newScope(false))
+		r.resolveStatements(stmt.Statements)
+		r.endScope()
+	case *ast.VariableStatement:
+		r.resolveExpression(stmt.Initializer)
+		stmt.Location = r.declare(stmt.Name, true)
+	case *ast.FunctionStatement:
+		// Declared before the body so that the function can call itself
+		stmt.Location = r.declare(stmt.Name, false)
+		r.resolveFunction(stmt.Params, stmt.Body)
+	case *ast.StructStatement:
+		r.resolveStructStatement(stmt)
+	case *ast.ReturnStatement:
+		r.resolveExpression(stmt.Value)
+	case *ast.TryStatement:
+		r.resolveTryStatement(stmt)
+	case *ast.ThrowStatement:
+		r.resolveExpression(stmt.Value)
+	case *ast.ImportStatement:
+		if name, ok := stmt.ModuleName(); ok {
+			r.declare(token.Token{Literal: name}, false)
+		}
+	}
+}
+
+// Resolves statements that share a scope that has just begun
+func (r *Resolver) resolveStatements(statements []ast.Statement) {
+	scope := r.scopes[len(r.scopes)-1]
+	for _, stmt := range statements {
+		ast.Declarations(stmt, func(name string) { scope.add(name) })
+	}
+	for _, stmt := range statements {
+		r.resolveStatement(stmt)
+	}
+}
+
+func (r *Resolver) resolveForInStatement(stmt *ast.ForInStatement) {
+	r.resolveExpression(stmt.Iterable)
+
+	scope := newScope(false)
+	if stmt.Key != nil {
+		scope.define(stmt.Key.Literal, 0)
+		scope.define(stmt.Value.Literal, 1)
+	} else {
+		scope.define(stmt.Value.Literal, 0)
+	}
+	r.beginScope(scope)
+	r.resolveStatements([]ast.Statement{stmt.Body})
+	r.endScope()
+}
+
+// The error is the first slot of the scope of catch
+func (r *Resolver) resolveTryStatement(stmt *ast.TryStatement) {
+	r.resolveStatement(stmt.Body)
+
+	if stmt.Catch != nil {
+		scope := newScope(false)
+		if stmt.Name != nil {
+			scope.define(stmt.Name.Literal, 0)
+		}
+		r.beginScope(scope)
+		r.resolveStatements(stmt.Catch.Statements)
+		r.endScope()
+	}
+
+	if stmt.Finally != nil {
+		r.resolveStatement(stmt.Finally)
+	}
+}
+
+func (r *Resolver) resolveFunction(params []token.Token, body ast.BlockStatement) {
+	r.deferred++
+	scope := newScope(true)
+	for i, param := range params {
+		scope.define(param.Literal, i)
+	}
+	r.beginScope(scope)
+	r.resolveStatements(body.Statements)
+	r.endScope()
+	r.deferred--
+}
+
+func (r *Resolver) resolveStructStatement(stmt *ast.StructStatement) {
+	// The parent is resolved before the struct is declared,
+	// a struct cannot inherit from itself
+	if stmt.Parent != nil {
+		r.resolveExpression(stmt.Parent)
+	}
+
+	// Attribute initializers are run in the scope that the struct is
+	// declared in, whenever the struct is instantiated
+	r.attributes++
+	r.deferred++
+	for _, name := range sortedTokens(stmt.Attributes) {
+		r.resolveExpression(stmt.Attributes[name].(*ast.VariableStatement).Initializer)
+	}
+	r.deferred--
+	r.attributes--
+
+	if stmt.Parent != nil {
+		super := newScope(false)
+		super.define("super", 0)
+		r.beginScope(super)
+	}
+	for _, name := range sortedTokens(stmt.Methods) {
+		method := stmt.Methods[name].(*ast.FunctionStatement)
+		this := newScope(false)
+		this.define("this", 0)
+		r.beginScope(this)
+		r.resolveFunction(method.Params, method.Body)
+		r.endScope()
+	}
+	if stmt.Parent != nil {
+		r.endScope()
+	}
+
+	stmt.Location = r.declare(stmt.Name, false)
+}
+
+func (r *Resolver) resolveExpression(expr ast.Expression) {
+	switch expr := expr.(type) {
+	case *ast.VariableExpression:
+		expr.Location = r.variable(expr.Name)
+	case *ast.AssignmentExpression:
+		r.resolveExpression(expr.Value)
+		expr.Location = r.variable(expr.Name)
+	case *ast.AssignmentIndexExpression:
+		r.resolveExpression(expr.Value)
+		r.resolveExpression(expr.Index)
+		r.resolveExpression(expr.Object)
+	case *ast.AssignmentStruct:
+		r.resolveExpression(expr.Value)
+		r.resolveExpression(expr.Object)
+	case *ast.BinaryExpression:
+		r.resolveExpression(expr.Left)
+		r.resolveExpression(expr.Right)
+	case *ast.UnaryExpression:
+		r.resolveExpression(expr.Right)
+	case *ast.LogicalExpression:
+		r.resolveExpression(expr.Left)
+		r.resolveExpression(expr.Right)
+	case *ast.FunctionExpression:
+		r.resolveFunction(expr.Params, expr.Body)
+	case *ast.InterpolationExpression:
+		for _, part := range expr.Parts {
+			r.resolveExpression(part)
+		}
+	case *ast.ListExpression:
+		for _, value := range expr.Values {
+			r.resolveExpression(value)
+		}
+	case *ast.HashMapExpression:
+		for key, value := range expr.Values {
+			r.resolveExpression(key)
+			r.resolveExpression(value)
+		}
+	case *ast.GroupExpression:
+		r.resolveExpression(expr.Expr)
+	case *ast.CallExpression:
+		r.resolveExpression(expr.Callee)
+		for _, argument := range expr.Arguments {
+			r.resolveExpression(argument)
+		}
+	case *ast.GetExpression:
+		// The attribute is looked up on the object and not in a scope
+		r.resolveExpression(expr.Callee)
+	case *ast.ThisExpression:
+		// 'this' and 'super' outside of a method are left for the
+		// interpreter to report
+		expr.Location, _ = r.lookup("this")
+	case *ast.SuperExpression:
+		expr.Location, _ = r.lookup("super")
+		expr.This, _ = r.lookup("this")
+	}
+}
+
+// Declares a name in the innermost scope, or as a global at the top level.
+// Redeclaring a variable in the same scope is warned about.
+func (r *Resolver) declare(name token.Token, warn bool) *ast.Location {
+	if len(r.scopes) == 0 {
+		if warn && r.globals[name.Literal] {
+			r.Warnings = append(r.Warnings, errors.NewShadowWarning(name))
+		}
+		r.globals[name.Literal] = true
+		return nil
+	}
+
+	scope := r.scopes[len(r.scopes)-1]
+	if warn && scope.declared[name.Literal] {
+		r.Warnings = append(r.Warnings, errors.NewShadowWarning(name))
+	}
+	slot := scope.add(name.Literal)
+	scope.declared[name.Literal] = true
+	return &ast.Location{Depth: 0, Slot: slot}
+}
+
+// Gives back the location of a local, or nil if the name is a global.
+// Names that cannot be found anywhere are reported.
+func (r *Resolver) variable(name token.Token) *ast.Location {
+	location, later := r.lookup(name.Literal)
+	if location != nil {
+		return location
+	}
+
+	declared, isGlobal := r.globals[name.Literal]
+	if isGlobal && (declared || r.deferred > 0) {
+		return nil
+	}
+	if r.environment != nil && r.environment.Get(name.Literal) != nil {
+		return nil
+	}
+
+	if isGlobal || later {
+		r.Errors = append(r.Errors, errors.NewSemanticError(name,
+			fmt.Sprintf("Cannot use '%s' before it is declared", name.Literal)))
+	} else {
+		r.Errors = append(r.Errors, errors.NewSemanticError(name,
+			fmt.Sprintf("Undefined variable '%s'", name.Literal)))
+	}
+	return nil
+}
+
+// Looks for a local from the innermost scope outwards. A local that is
+// declared further down its scope can only be used from within a function
+// (or attribute initializer), as that is only run later on. later is true
+// if a local with the name was found but could not be used yet.
+func (r *Resolver) lookup(name string) (location *ast.Location, later bool) {
+	crossed := r.attributes > 0
+	for depth := 0; depth < len(r.scopes); depth++ {
+		scope := r.scopes[len(r.scopes)-1-depth]
+		if slot, ok := scope.slots[name]; ok {
+			if scope.declared[name] || crossed {
+				return &ast.Location{Depth: depth, Slot: slot}, later
+			}
+			later = true
+		}
+		if scope.function {
+			crossed = true
+		}
+	}
+	return nil, later
+}
+
+func (r *Resolver) beginScope(scope *scope) {
+	r.scopes = append(r.scopes, scope)
+}
+
+func (r *Resolver) endScope() {
+	r.scopes = r.scopes[:len(r.scopes)-1]
+}
+
+// Attributes and methods are kept in maps, they are resolved in
+// the order that they are written in
+func sortedTokens(statements map[token.Token]ast.Statement) []token.Token {
+	names := make([]token.Token, 0, len(statements))
+	for name := range statements {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(a, b int) bool {
+		return names[a].Offset < names[b].Offset
+	})
+	return names
+}
+
+// env holds the names that are already defined, and can be nil
+func NewResolver(env *environment.Environment) *Resolver {
+	return &Resolver{
+		globals:     make(map[string]bool),
+		environment: env,
+	}
+}
@@ -0,0 +1,98 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/lczm/as/ast"
+	"github.com/lczm/as/lexer"
+	"github.com/lczm/as/parser"
+)
+
+func TestResolveLocations(t *testing.T) {
+	tests := []struct {
+		input             string
+		expectedLocations []*ast.Location
+	}{
+		{ // Globals are looked up by name
+			"var a = 1; a;",
+			[]*ast.Location{nil},
+		},
+		{
+			"{ var a = 1; var b = 2; b; a; }",
+			[]*ast.Location{{Depth: 0, Slot: 1}, {Depth: 0, Slot: 0}},
+		},
+		{ // Parameters come first, and the body shares their scope
+			"function f(a, b) { var c; { c; a; } }",
+			[]*ast.Location{{Depth: 1, Slot: 2}, {Depth: 1, Slot: 0}},
+		},
+		{ // A local refers to the outer variable until it is declared
+			"{ var a; { a; var a; a; } }",
+			[]*ast.Location{{Depth: 1, Slot: 0}, {Depth: 0, Slot: 0}},
+		},
+		{ // Functions can refer to locals that are declared after them
+			"{ function f() { a; } var a; }",
+			[]*ast.Location{{Depth: 1, Slot: 1}},
+		},
+		{
+			"for (k, v in []) { v; k; }",
+			[]*ast.Location{{Depth: 1, Slot: 1}, {Depth: 1, Slot: 0}},
+		},
+		{ // The error is the first slot of the scope of catch
+			"try {} catch (e) { var a; a; e; }",
+			[]*ast.Location{{Depth: 0, Slot: 1}, {Depth: 0, Slot: 0}},
+		},
+	}
+
+	lexer := lexer.New()
+	for i, test := range tests {
+		tokens := lexer.Scan(test.input)
+		parser := parser.New(tokens)
+		statements := parser.Parse()
+
+		resolver := NewResolver(nil)
+		resolver.Resolve(statements)
+		if len(resolver.Errors) != 0 {
+			t.Fatalf("Test: [%d] - Unexpected errors : %v", i, resolver.Errors)
+		}
+
+		variables := variablesOf(statements)
+		if len(variables) != len(test.expectedLocations) {
+			t.Fatalf("Test: [%d] - Mismatch amount of variables, expected=%d, got=%d",
+				i, len(test.expectedLocations), len(variables))
+		}
+		for j, variable := range variables {
+			expected := test.expectedLocations[j]
+			got := variable.Location
+			if (expected == nil) != (got == nil) || (expected != nil && *expected != *got) {
+				t.Fatalf("Test: [%d - %d] - Incorrect location of '%s', expected=%v, got=%v",
+					i, j, variable.Name.Literal, expected, got)
+			}
+		}
+	}
+}
+
+// Gives back the variables that are used as expression statements,
+// in the order that they are written in
+func variablesOf(statements []ast.Statement) []*ast.VariableExpression {
+	var variables []*ast.VariableExpression
+	for _, stmt := range statements {
+		switch stmt := stmt.(type) {
+		case *ast.StatementExpression:
+			if variable, ok := stmt.Expr.(*ast.VariableExpression); ok {
+				variables = append(variables, variable)
+			}
+		case *ast.BlockStatement:
+			variables = append(variables, variablesOf(stmt.Statements)...)
+		case *ast.FunctionStatement:
+			variables = append(variables, variablesOf(stmt.Body.Statements)...)
+		case *ast.ForInStatement:
+			variables = append(variables, variablesOf([]ast.Statement{stmt.Body})...)
+		case *ast.TryStatement:
+			variables = append(variables, variablesOf(stmt.Body.Statements)...)
+			if stmt.Catch != nil {
+				variables = append(variables, variablesOf(stmt.Catch.Statements)...)
+			}
+		}
+	}
+	return variables
+}
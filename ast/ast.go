@@ -2,6 +2,8 @@ package ast
 
 import (
 	"fmt"
+	"path/filepath"
+	"strings"
 
 	"github.com/lczm/as/token"
 )
@@ -19,6 +21,15 @@ type Expression interface {
 	String() string
 }
 
+// Where a local variable is stored, filled in by the resolver. Depth is
+// the number of scopes between where the variable is used and where it
+// was declared, and Slot is its position within that scope.
+// Globals are left without a location, and are looked up by name.
+type Location struct {
+	Depth int
+	Slot  int
+}
+
 // Statements
 type StatementExpression struct {
 	Expr Expression
@@ -33,6 +44,7 @@ type PrintStatement struct {
 func (pe *PrintStatement) statement() {}
 
 type IfStatement struct {
+	Keyword   token.Token
 	Condition Expression
 	Then      Statement
 	Else      Statement
@@ -43,11 +55,13 @@ func (is *IfStatement) statement() {}
 type VariableStatement struct {
 	Name        token.Token
 	Initializer Expression
+	Location    *Location
 }
 
 func (vs *VariableStatement) statement() {}
 
 type WhileStatement struct {
+	Keyword   token.Token
 	Condition Expression
 	Body      Statement
 }
@@ -55,6 +69,7 @@ type WhileStatement struct {
 func (ws *WhileStatement) statement() {}
 
 type ForStatement struct {
+	Keyword   token.Token
 	Variable  Statement
 	Condition Expression
 	Effect    Expression
@@ -63,24 +78,43 @@ type ForStatement struct {
 
 func (fs *ForStatement) statement() {}
 
+// for (value in iterable) {}
+// for (key, value in iterable) {}
+// Key is nil when only one name is given, In is the 'in' token.
+type ForInStatement struct {
+	Keyword  token.Token
+	Key      *token.Token
+	Value    token.Token
+	In       token.Token
+	Iterable Expression
+	Body     Statement
+}
+
+func (fis *ForInStatement) statement() {}
+
 type BlockStatement struct {
 	Statements []Statement
 }
 
 func (bs *BlockStatement) statement() {}
 
+// Location is nil for methods, they are not declared as variables
 type FunctionStatement struct {
-	Name   token.Token
-	Params []token.Token
-	Body   BlockStatement
+	Name     token.Token
+	Params   []token.Token
+	Body     BlockStatement
+	Location *Location
 }
 
 func (fs *FunctionStatement) statement() {}
 
+// Parent is nil if the struct does not inherit from another struct
 type StructStatement struct {
 	Name       token.Token
+	Parent     *VariableExpression
 	Attributes map[token.Token]Statement
 	Methods    map[token.Token]Statement
+	Location   *Location
 }
 
 func (ss *StructStatement) statement() {}
@@ -92,10 +126,78 @@ type ReturnStatement struct {
 
 func (rs *ReturnStatement) statement() {}
 
+type BreakStatement struct {
+	Keyword token.Token
+}
+
+func (bs *BreakStatement) statement() {}
+
+type ContinueStatement struct {
+	Keyword token.Token
+}
+
+func (cs *ContinueStatement) statement() {}
+
+// try { } catch (name) { } finally { }
+// Name is nil for catch { }, and either Catch or Finally can be nil.
+type TryStatement struct {
+	Keyword token.Token
+	Body    *BlockStatement
+	Name    *token.Token
+	Catch   *BlockStatement
+	Finally *BlockStatement
+}
+
+func (ts *TryStatement) statement() {}
+
+type ThrowStatement struct {
+	Keyword token.Token
+	Value   Expression
+}
+
+func (ts *ThrowStatement) statement() {}
+
+// import "path";
+// import name from "path";
+// Name is nil when the module is named after the file it is imported from.
+type ImportStatement struct {
+	Keyword token.Token
+	Name    *token.Token
+	Path    token.Token
+}
+
+func (is *ImportStatement) statement() {}
+
+// The name that the module is defined under, false if the module is
+// named after a file that is not a valid identifier
+func (is *ImportStatement) ModuleName() (string, bool) {
+	if is.Name != nil {
+		return is.Name.Literal, true
+	}
+	base := filepath.Base(is.Path.Literal)
+	name := strings.TrimSuffix(base, filepath.Ext(base))
+	return name, isIdentifier(name)
+}
+
+func isIdentifier(name string) bool {
+	if name == "" {
+		return false
+	}
+	for index, ch := range name {
+		isLetter := ch == '_' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
+		isDigit := ch >= '0' && ch <= '9'
+		if !isLetter && !(isDigit && index > 0) {
+			return false
+		}
+	}
+	return true
+}
+
 // Expressions
 type AssignmentExpression struct {
-	Name  token.Token
-	Value Expression
+	Name     token.Token
+	Value    Expression
+	Location *Location
 }
 
 func (ae *AssignmentExpression) expression() {}
@@ -104,28 +206,32 @@ func (ae *AssignmentExpression) String() string {
 		ae.Name.Literal, ae.Value.String())
 }
 
+// object[index] = value;
+// Token is the '[' of the index
 type AssignmentIndexExpression struct {
-	Name  token.Token
-	Index Expression
-	Value Expression
+	Object Expression
+	Index  Expression
+	Value  Expression
+	Token  token.Token
 }
 
 func (aie *AssignmentIndexExpression) expression() {}
 func (aie *AssignmentIndexExpression) String() string {
-	return fmt.Sprintf("(AssignmentIndexExpression) Name : %s, Value : %s Index : %s\n",
-		aie.Name.Literal, aie.Value.String(), aie.Index.String())
+	return fmt.Sprintf("(AssignmentIndexExpression) Object : %s, Value : %s Index : %s\n",
+		aie.Object.String(), aie.Value.String(), aie.Index.String())
 }
 
+// object.attribute = value;
 type AssignmentStruct struct {
-	Name      token.Token
+	Object    Expression
 	Attribute Expression
 	Value     Expression
 }
 
 func (as *AssignmentStruct) expression() {}
 func (as *AssignmentStruct) String() string {
-	return fmt.Sprintf("(AssignmentStruct) Name : %s, Value : %s Attribute : %s\n",
-		as.Name.Literal, as.Value.String(), as.Attribute.String())
+	return fmt.Sprintf("(AssignmentStruct) Object : %s, Value : %s Attribute : %s\n",
+		as.Object.String(), as.Value.String(), as.Attribute.String())
 }
 
 type BinaryExpression struct {
@@ -163,6 +269,25 @@ func (le *LogicalExpression) String() string {
 		le.Left.String(), le.Right.String(), le.Operator.Literal)
 }
 
+// Anonymous functions, both of these are function expressions
+// function(a, b) { return a + b; }
+// (a, b) => a + b
+// Keyword is either the 'function' or the '=>' token.
+type FunctionExpression struct {
+	Keyword token.Token
+	Params  []token.Token
+	Body    BlockStatement
+}
+
+func (fe *FunctionExpression) expression() {}
+func (fe *FunctionExpression) String() string {
+	var params []string
+	for i := 0; i < len(fe.Params); i++ {
+		params = append(params, fe.Params[i].Literal)
+	}
+	return fmt.Sprintf("(FunctionExpression) Params : %s\n", params)
+}
+
 type NumberExpression struct {
 	Value int
 }
@@ -173,6 +298,38 @@ func (ne *NumberExpression) String() string {
 		ne.Value)
 }
 
+// "a ${b} c", the parts are the strings and the expressions in order
+type InterpolationExpression struct {
+	Token token.Token
+	Parts []Expression
+}
+
+func (ie *InterpolationExpression) expression() {}
+func (ie *InterpolationExpression) String() string {
+	var parts []string
+	for _, part := range ie.Parts {
+		parts = append(parts, part.String())
+	}
+	return fmt.Sprintf("(InterpolationExpression) Parts : %s\n", parts)
+}
+
+type NullExpression struct{}
+
+func (ne *NullExpression) expression() {}
+func (ne *NullExpression) String() string {
+	return "(NullExpression)\n"
+}
+
+type FloatExpression struct {
+	Value float64
+}
+
+func (fe *FloatExpression) expression() {}
+func (fe *FloatExpression) String() string {
+	return fmt.Sprintf("(FloatExpression) Value : %g\n",
+		fe.Value)
+}
+
 type ListExpression struct {
 	Values []Expression
 }
@@ -188,6 +345,8 @@ func (le *ListExpression) String() string {
 
 type HashMapExpression struct {
 	Values map[Expression]Expression
+	// The '{' that started off the hashmap
+	Token token.Token
 }
 
 func (hme *HashMapExpression) expression() {}
@@ -231,7 +390,8 @@ func (ge *GroupExpression) String() string {
 }
 
 type VariableExpression struct {
-	Name token.Token
+	Name     token.Token
+	Location *Location
 }
 
 func (ve *VariableExpression) expression() {}
@@ -240,9 +400,37 @@ func (ve *VariableExpression) String() string {
 		ve.Name.Literal)
 }
 
+// The instance that a method is bound to
+type ThisExpression struct {
+	Keyword  token.Token
+	Location *Location
+}
+
+func (te *ThisExpression) expression() {}
+func (te *ThisExpression) String() string {
+	return "(ThisExpression)\n"
+}
+
+// super.method, the method of the parent struct
+// Location is where 'super' is, This is where 'this' is
+type SuperExpression struct {
+	Keyword  token.Token
+	Method   token.Token
+	Location *Location
+	This     *Location
+}
+
+func (se *SuperExpression) expression() {}
+func (se *SuperExpression) String() string {
+	return fmt.Sprintf("(SuperExpression) Method : %s\n", se.Method.Literal)
+}
+
+// Calls and indexing both use this, Token is the '(' or '['
+// that started off the call.
 type CallExpression struct {
 	Callee    Expression
 	Arguments []Expression
+	Token     token.Token
 }
 
 func (ce *CallExpression) expression() {}
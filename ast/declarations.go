@@ -0,0 +1,30 @@
+package ast
+
+// Finds the names that a statement declares in the scope it is in,
+// blocks are skipped as they have a scope of their own
+func Declarations(stmt Statement, declare func(name string)) {
+	switch stmt := stmt.(type) {
+	case *VariableStatement:
+		declare(stmt.Name.Literal)
+	case *FunctionStatement:
+		declare(stmt.Name.Literal)
+	case *StructStatement:
+		declare(stmt.Name.Literal)
+	case *ImportStatement:
+		if name, ok := stmt.ModuleName(); ok {
+			declare(name)
+		}
+	case *IfStatement:
+		Declarations(stmt.Then, declare)
+		if stmt.Else != nil {
+			Declarations(stmt.Else, declare)
+		}
+	case *WhileStatement:
+		Declarations(stmt.Body, declare)
+	case *ForStatement:
+		if stmt.Variable != nil {
+			Declarations(stmt.Variable, declare)
+		}
+		Declarations(stmt.Body, declare)
+	}
+}
@@ -0,0 +1,97 @@
+package ast
+
+import "github.com/lczm/as/token"
+
+// The first token of a statement that has one, which gives the line that
+// the statement starts on. Blocks do not have a position of their own, and
+// neither do expressions that are only made up of literals, i.e. '1;'
+func Position(stmt Statement) (token.Token, bool) {
+	switch stmt := stmt.(type) {
+	case *StatementExpression:
+		return expressionPosition(stmt.Expr)
+	case *PrintStatement:
+		return expressionPosition(stmt.Expr)
+	case *IfStatement:
+		return stmt.Keyword, true
+	case *VariableStatement:
+		return stmt.Name, true
+	case *WhileStatement:
+		return stmt.Keyword, true
+	case *ForStatement:
+		return stmt.Keyword, true
+	case *ForInStatement:
+		return stmt.Keyword, true
+	case *FunctionStatement:
+		return stmt.Name, true
+	case *StructStatement:
+		return stmt.Name, true
+	case *ReturnStatement:
+		return stmt.Keyword, true
+	case *BreakStatement:
+		return stmt.Keyword, true
+	case *ContinueStatement:
+		return stmt.Keyword, true
+	case *TryStatement:
+		return stmt.Keyword, true
+	case *ThrowStatement:
+		return stmt.Keyword, true
+	case *ImportStatement:
+		return stmt.Keyword, true
+	}
+	return token.Token{}, false
+}
+
+// The leftmost token of an expression
+func expressionPosition(expr Expression) (token.Token, bool) {
+	switch expr := expr.(type) {
+	case *AssignmentExpression:
+		return expr.Name, true
+	case *AssignmentIndexExpression:
+		if tok, ok := expressionPosition(expr.Object); ok {
+			return tok, true
+		}
+		return expr.Token, true
+	case *AssignmentStruct:
+		return expressionPosition(expr.Object)
+	case *BinaryExpression:
+		if tok, ok := expressionPosition(expr.Left); ok {
+			return tok, true
+		}
+		return expr.Operator, true
+	case *LogicalExpression:
+		if tok, ok := expressionPosition(expr.Left); ok {
+			return tok, true
+		}
+		return expr.Operator, true
+	case *UnaryExpression:
+		return expr.Operator, true
+	case *FunctionExpression:
+		return expr.Keyword, true
+	case *InterpolationExpression:
+		return expr.Token, true
+	case *HashMapExpression:
+		return expr.Token, true
+	case *ListExpression:
+		for _, value := range expr.Values {
+			if tok, ok := expressionPosition(value); ok {
+				return tok, true
+			}
+		}
+	case *GroupExpression:
+		return expressionPosition(expr.Expr)
+	case *VariableExpression:
+		return expr.Name, true
+	case *ThisExpression:
+		return expr.Keyword, true
+	case *SuperExpression:
+		return expr.Keyword, true
+	case *CallExpression:
+		if tok, ok := expressionPosition(expr.Callee); ok {
+			return tok, true
+		}
+		return expr.Token, true
+	case *GetExpression:
+		return expressionPosition(expr.Callee)
+	}
+	return token.Token{}, false
+}
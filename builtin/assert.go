@@ -0,0 +1,92 @@
+package builtin
+
+import (
+	"fmt"
+
+	"github.com/lczm/as/errors"
+	"github.com/lczm/as/object"
+	"github.com/lczm/as/token"
+)
+
+// These builtin functions are for tests, they raise an AssertionError
+// when what they check does not hold.
+
+// assert(len(list) > 0)
+// assert(len(list) > 0, "the list is empty")
+func AssertFunc() object.Object {
+	function := &object.BuiltinFunction{
+		Name: "assert",
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 && len(args) != 2 {
+				return newError("assert() takes in one or two parameters, the condition and a message.")
+			}
+
+			if object.IsTruthy(args[0]) {
+				return object.NullValue
+			}
+			if len(args) == 2 {
+				return newAssertionError("%s", args[1].String())
+			}
+			return newAssertionError("assert() failed")
+		},
+	}
+	return function
+}
+
+// Values are compared by what they hold, so lists, hashmaps and
+// instances of structs with the same contents are equal.
+// assertEqual(add(1, 2), 3)
+func AssertEqualFunc() object.Object {
+	function := &object.BuiltinFunction{
+		Name: "assertEqual",
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return newError("assertEqual() takes in two parameters, the actual and the expected value.")
+			}
+
+			if object.Equal(args[0], args[1]) {
+				return object.NullValue
+			}
+			return newAssertionError("assertEqual() failed : expected %s, got %s",
+				args[1].FormattedString(), args[0].FormattedString())
+		},
+	}
+	return function
+}
+
+// Calls the function and fails if it does not raise an error, the error
+// is given back as an exception. When a message is given, the error has
+// to have that message.
+// assertThrows(function() { throw "no"; }, "no")
+func AssertThrowsFunc(caller object.Caller) object.Object {
+	function := &object.BuiltinFunction{
+		Name: "assertThrows",
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 && len(args) != 2 {
+				return newError("assertThrows() takes in one or two parameters, the function and a message.")
+			}
+
+			obj := caller.CallFunction(args[0], []object.Object{})
+			errorObj, ok := obj.(*object.Error)
+			if !ok {
+				return newAssertionError("assertThrows() failed : nothing was thrown")
+			}
+			if !errorObj.Catchable() {
+				return errorObj
+			}
+
+			if len(args) == 2 && errorObj.Err.Message() != args[1].String() {
+				return newAssertionError("assertThrows() failed : expected %s to be thrown, got %s",
+					args[1].FormattedString(), (&object.String{Value: errorObj.Err.Message()}).FormattedString())
+			}
+			return object.NewException(errorObj)
+		},
+	}
+	return function
+}
+
+func newAssertionError(format string, a ...interface{}) *object.Error {
+	return &object.Error{
+		Err: errors.NewAssertionError(token.Token{}, fmt.Sprintf(format, a...)),
+	}
+}
@@ -2,10 +2,17 @@ package builtin
 
 import (
 	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/lczm/as/environment"
 	"github.com/lczm/as/errors"
-	"github.com/lczm/as/globals"
 	"github.com/lczm/as/object"
 )
 
@@ -14,9 +21,7 @@ func TypeFunc() object.Object {
 		Name: "type",
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
-				// errors.DefaultError("type() can only take in one parameter at a time.")
-				globals.ErrorList = append(globals.ErrorList,
-					errors.NewDefaultError("type() can only take in one parameter at a time."))
+				return newError("type() can only take in one parameter at a time.")
 			}
 
 			obj := args[0]
@@ -31,31 +36,34 @@ func LenFunc() object.Object {
 		Name: "len",
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
-				globals.ErrorList = append(globals.ErrorList,
-					errors.NewDefaultError("len() needs at least one parameter."))
+				return newError("len() takes in exactly one parameter.")
 			}
 
 			obj := args[0]
 			switch obj := obj.(type) {
-			case *object.Integer:
-				globals.ErrorList = append(globals.ErrorList,
-					errors.NewDefaultError("len() cannot be used on an integer"))
 			case *object.String:
-				return &object.Integer{Value: int64(len(obj.String()))}
+				// The number of characters, and not bytes
+				return &object.Integer{Value: int64(utf8.RuneCountInString(obj.Value))}
 			case *object.List:
 				return &object.Integer{Value: int64(len(obj.Value))}
 			case *object.HashMap:
 				return &object.Integer{Value: int64(len(obj.Value))}
+			case *object.Range:
+				return &object.Integer{Value: obj.Len()}
 			default:
-				return nil
+				return newError("len() cannot be used on %s", obj.Type())
 			}
-			return nil
 		},
 	}
 	return function
 }
 
 func PrintFunc() object.Object {
+	return PrintToFunc(os.Stdout)
+}
+
+// The same as print(), but writes to out instead of standard output
+func PrintToFunc(out io.Writer) object.Object {
 	function := &object.BuiltinFunction{
 		Name: "print",
 		Fn: func(args ...object.Object) object.Object {
@@ -63,8 +71,8 @@ func PrintFunc() object.Object {
 			for _, arg := range args {
 				s += arg.String()
 			}
-			fmt.Println(s)
-			return nil
+			fmt.Fprintln(out, s)
+			return object.NullValue
 		},
 	}
 	return function
@@ -77,15 +85,19 @@ func PrintFunc() object.Object {
 // .append() or .remove() would work seamlessly.
 func AppendFunc() object.Object {
 	function := &object.BuiltinFunction{
+		Name: "append",
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 2 {
-				fmt.Println("append() takes in two parameters, the appendee and the element.")
-				return nil
+				return newError("append() takes in two parameters, the appendee and the element.")
 			}
 
 			// TODO : Support more than just lists, possibly hashmaps
 			// Extract the list value out of the list object
-			list := args[0].(*object.List).Value
+			listObject, ok := args[0].(*object.List)
+			if !ok {
+				return newError("append() can only append to a list, not %s", args[0].Type())
+			}
+			list := listObject.Value
 			element := args[1]
 
 			// Add the list item
@@ -94,6 +106,17 @@ func AppendFunc() object.Object {
 			// Wrap it back into an object
 			return &object.List{Value: list}
 		},
+		// Only the element that is added is counted, the rest of the
+		// list was counted when it was built
+		Size: func(args ...object.Object) int {
+			if len(args) != 2 {
+				return 0
+			}
+			if _, ok := args[0].(*object.List); !ok {
+				return 0
+			}
+			return 16
+		},
 	}
 	return function
 }
@@ -101,14 +124,26 @@ func AppendFunc() object.Object {
 // Removes an element at the specified index
 func RemoveAtFunc() object.Object {
 	function := &object.BuiltinFunction{
+		Name: "removeAt",
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 2 {
-				fmt.Println("removeAt() takes in two parameters, the list object and the index to remove at.")
-				return nil
+				return newError("removeAt() takes in two parameters, the list object and the index to remove at.")
+			}
+
+			listObject, ok := args[0].(*object.List)
+			if !ok {
+				return newError("removeAt() can only remove from a list, not %s", args[0].Type())
+			}
+			indexObject, ok := args[1].(*object.Integer)
+			if !ok {
+				return newError("removeAt() index has to be an integer, not %s", args[1].Type())
 			}
 
-			list := args[0].(*object.List).Value
-			index := args[1].(*object.Integer).Value
+			list := listObject.Value
+			index := indexObject.Value
+			if index < 0 || index >= int64(len(list)) {
+				return newError("removeAt() index %d is out of range", index)
+			}
 
 			list = append(list[:index], list[index+1:]...)
 
@@ -118,10 +153,213 @@ func RemoveAtFunc() object.Object {
 	return function
 }
 
-func PopulateEnvironment(env *environment.Environment) {
+// Converts integers and strings into floats
+func FloatFunc() object.Object {
+	function := &object.BuiltinFunction{
+		Name: "float",
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("float() takes in exactly one parameter.")
+			}
+
+			switch obj := args[0].(type) {
+			case *object.Float:
+				return obj
+			case *object.Integer:
+				return &object.Float{Value: float64(obj.Value)}
+			case *object.String:
+				value, err := strconv.ParseFloat(strings.TrimSpace(obj.Value), 64)
+				if err != nil {
+					return newError("float() cannot convert %s", obj.FormattedString())
+				}
+				return &object.Float{Value: value}
+			default:
+				return newError("float() cannot be used on %s", args[0].Type())
+			}
+		},
+	}
+	return function
+}
+
+// Converts floats and strings into integers, floats are truncated
+// towards zero.
+func IntFunc() object.Object {
+	function := &object.BuiltinFunction{
+		Name: "int",
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("int() takes in exactly one parameter.")
+			}
+
+			switch obj := args[0].(type) {
+			case *object.Integer:
+				return obj
+			case *object.Float:
+				if math.IsNaN(obj.Value) || math.IsInf(obj.Value, 0) ||
+					obj.Value >= math.MaxInt64 || obj.Value < math.MinInt64 {
+					return newError("int() cannot convert %s", obj.String())
+				}
+				return &object.Integer{Value: int64(obj.Value)}
+			case *object.String:
+				value, err := strconv.ParseInt(strings.TrimSpace(obj.Value), 10, 64)
+				if err != nil {
+					return newError("int() cannot convert %s", obj.FormattedString())
+				}
+				return &object.Integer{Value: value}
+			default:
+				return newError("int() cannot be used on %s", args[0].Type())
+			}
+		},
+	}
+	return function
+}
+
+// Produces a range of integers to loop over, the integers are not
+// created until they are looped over.
+// range(end), range(start, end), range(start, end, step)
+func RangeFunc() object.Object {
+	function := &object.BuiltinFunction{
+		Name: "range",
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) < 1 || len(args) > 3 {
+				return newError("range() takes in one to three parameters, the start, end and step.")
+			}
+
+			values := make([]int64, len(args))
+			for i, arg := range args {
+				integer, ok := arg.(*object.Integer)
+				if !ok {
+					return newError("range() parameters have to be integers, not %s", arg.Type())
+				}
+				values[i] = integer.Value
+			}
+
+			r := &object.Range{Start: 0, Step: 1}
+			switch len(values) {
+			case 1:
+				r.End = values[0]
+			case 2:
+				r.Start, r.End = values[0], values[1]
+			case 3:
+				r.Start, r.End, r.Step = values[0], values[1], values[2]
+			}
+
+			if r.Step == 0 {
+				return newError("range() step cannot be 0")
+			}
+			if r.Size() > math.MaxInt64 {
+				return newError("range() from %d to %d has too many integers", r.Start, r.End)
+			}
+			return r
+		},
+	}
+	return function
+}
+
+// Gives back a list of the keys of a hashmap, in the same order
+// that they are looped over in.
+func KeysFunc() object.Object {
+	function := &object.BuiltinFunction{
+		Name: "keys",
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("keys() takes in exactly one parameter.")
+			}
+
+			hashMap, ok := args[0].(*object.HashMap)
+			if !ok {
+				return newError("keys() can only be used on a hashmap, not %s", args[0].Type())
+			}
+
+			keys := make([]object.Object, 0, len(hashMap.Value))
+			for _, pair := range hashMap.Pairs() {
+				keys = append(keys, pair.Key)
+			}
+			return &object.List{Value: keys}
+		},
+	}
+	return function
+}
+
+// Gives back the calls that are running, outermost first, as hashmaps of
+// the function, and the line and file that it was called from.
+// callstack()[0]["function"]
+func CallStackFunc(caller object.Caller) object.Object {
+	function := &object.BuiltinFunction{
+		Name: "callstack",
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 0 {
+				return newError("callstack() does not take in any parameters.")
+			}
+
+			calls := make([]object.Object, 0)
+			for _, frame := range caller.CallStack() {
+				calls = append(calls, hashMapOf(map[string]object.Object{
+					"function": &object.String{Value: frame.Function},
+					"line":     &object.Integer{Value: int64(frame.Line)},
+					"file":     &object.String{Value: frame.File},
+				}))
+			}
+			return &object.List{Value: calls}
+		},
+	}
+	return function
+}
+
+func hashMapOf(values map[string]object.Object) *object.HashMap {
+	hashMap := make(map[object.HashKey]object.HashValue, len(values))
+	for key, value := range values {
+		keyObject := &object.String{Value: key}
+		hashMap[keyObject.Hash()] = object.HashValue{Key: keyObject, Value: value}
+	}
+	return &object.HashMap{Value: hashMap}
+}
+
+// Builtin functions do not know where they are called from, the
+// interpreter will fill in the position of the call.
+func newError(format string, a ...interface{}) *object.Error {
+	return &object.Error{
+		Err: errors.NewDefaultError(fmt.Sprintf(format, a...)),
+	}
+}
+
+// Builtins that reach outside of the program,
+// these are left out when I/O is disabled
+var IO_BUILTINS = []string{"print"}
+
+// The caller is used by builtin functions that call functions
+// passed in as arguments.
+func PopulateEnvironment(env *environment.Environment, caller object.Caller) {
 	env.Define("type", TypeFunc())
 	env.Define("len", LenFunc())
 	env.Define("print", PrintFunc())
 	env.Define("append", AppendFunc())
 	env.Define("removeAt", RemoveAtFunc())
+	env.Define("float", FloatFunc())
+	env.Define("int", IntFunc())
+	env.Define("range", RangeFunc())
+	env.Define("keys", KeysFunc())
+	env.Define("split", SplitFunc())
+	env.Define("join", JoinFunc())
+	env.Define("trim", TrimFunc())
+	env.Define("upper", UpperFunc())
+	env.Define("lower", LowerFunc())
+	env.Define("replace", ReplaceFunc())
+	env.Define("contains", ContainsFunc())
+	env.Define("startsWith", StartsWithFunc())
+	env.Define("endsWith", EndsWithFunc())
+	env.Define("indexOf", IndexOfFunc())
+	env.Define("substring", SubstringFunc())
+	env.Define("repeat", RepeatFunc())
+	env.Define("format", FormatFunc())
+	env.Define("ord", OrdFunc())
+	env.Define("chr", ChrFunc())
+	populateMath(env, rand.New(rand.NewSource(time.Now().UnixNano())))
+	env.Define("map", MapFunc(caller))
+	env.Define("filter", FilterFunc(caller))
+	env.Define("sort", SortFunc(caller))
+	env.Define("callstack", CallStackFunc(caller))
+	env.Define("assert", AssertFunc())
+	env.Define("assertEqual", AssertEqualFunc())
+	env.Define("assertThrows", AssertThrowsFunc(caller))
 }
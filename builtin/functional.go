@@ -0,0 +1,168 @@
+package builtin
+
+import (
+	"sort"
+
+	"github.com/lczm/as/object"
+)
+
+// These builtin functions take in functions as arguments, they use the
+// caller (the interpreter) to call back into them.
+
+// Calls the function on every element of the list, and returns a new
+// list of the results.
+// map([1, 2, 3], x => x * 2)
+func MapFunc(caller object.Caller) object.Object {
+	function := &object.BuiltinFunction{
+		Name: "map",
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return newError("map() takes in two parameters, the list and the function.")
+			}
+
+			list, ok := args[0].(*object.List)
+			if !ok {
+				return newError("map() can only be used on a list, not %s", args[0].Type())
+			}
+
+			mapped := make([]object.Object, 0, len(list.Value))
+			for _, element := range list.Value {
+				obj := caller.CallFunction(args[1], []object.Object{element})
+				if _, ok := obj.(*object.Error); ok {
+					return obj
+				}
+				mapped = append(mapped, obj)
+			}
+			return &object.List{Value: mapped}
+		},
+	}
+	return function
+}
+
+// Returns a new list with only the elements that the function
+// returns true for.
+// filter([1, 2, 3], x => x > 1)
+func FilterFunc(caller object.Caller) object.Object {
+	function := &object.BuiltinFunction{
+		Name: "filter",
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return newError("filter() takes in two parameters, the list and the function.")
+			}
+
+			list, ok := args[0].(*object.List)
+			if !ok {
+				return newError("filter() can only be used on a list, not %s", args[0].Type())
+			}
+
+			filtered := make([]object.Object, 0)
+			for _, element := range list.Value {
+				obj := caller.CallFunction(args[1], []object.Object{element})
+				switch obj := obj.(type) {
+				case *object.Error:
+					return obj
+				case *object.Bool:
+					if obj.Value {
+						filtered = append(filtered, element)
+					}
+				default:
+					return newError("filter() function has to return a bool, not %s", obj.Type())
+				}
+			}
+			return &object.List{Value: filtered}
+		},
+	}
+	return function
+}
+
+// Returns a new sorted list, integers and strings are sorted in
+// ascending order by default.
+// A function can be passed in to compare two elements, it should
+// return true (or a negative integer) when 'a' comes before 'b'.
+// sort(list)
+// sort(list, (a, b) => a > b)
+func SortFunc(caller object.Caller) object.Object {
+	function := &object.BuiltinFunction{
+		Name: "sort",
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 && len(args) != 2 {
+				return newError("sort() takes in a list, and optionally a function to compare with.")
+			}
+
+			list, ok := args[0].(*object.List)
+			if !ok {
+				return newError("sort() can only be used on a list, not %s", args[0].Type())
+			}
+
+			sorted := make([]object.Object, len(list.Value))
+			copy(sorted, list.Value)
+
+			// sort.SliceStable cannot be stopped halfway, so keep the first
+			// error and return it once sorting is done
+			var err object.Object
+			less := func(a, b int) bool {
+				if err != nil {
+					return false
+				}
+
+				var isLess bool
+				if len(args) == 2 {
+					isLess, err = compareWith(caller, args[1], sorted[a], sorted[b])
+				} else {
+					isLess, err = compare(sorted[a], sorted[b])
+				}
+				return isLess
+			}
+			sort.SliceStable(sorted, less)
+
+			if err != nil {
+				return err
+			}
+			return &object.List{Value: sorted}
+		},
+	}
+	return function
+}
+
+// Default ordering used by sort()
+func compare(a object.Object, b object.Object) (bool, object.Object) {
+	switch a := a.(type) {
+	case *object.Integer:
+		switch b := b.(type) {
+		case *object.Integer:
+			return a.Value < b.Value, nil
+		case *object.Float:
+			return float64(a.Value) < b.Value, nil
+		}
+	case *object.Float:
+		switch b := b.(type) {
+		case *object.Integer:
+			return a.Value < float64(b.Value), nil
+		case *object.Float:
+			return a.Value < b.Value, nil
+		}
+	case *object.String:
+		if b, ok := b.(*object.String); ok {
+			return a.Value < b.Value, nil
+		}
+	}
+	return false, newError("sort() cannot compare %s and %s, pass in a function to compare with",
+		a.Type(), b.Type())
+}
+
+// Ordering from a user supplied function
+func compareWith(caller object.Caller, function object.Object,
+	a object.Object, b object.Object) (bool, object.Object) {
+	obj := caller.CallFunction(function, []object.Object{a, b})
+	switch obj := obj.(type) {
+	case *object.Error:
+		return false, obj
+	case *object.Bool:
+		return obj.Value, nil
+	case *object.Integer:
+		return obj.Value < 0, nil
+	default:
+		return false, newError("sort() function has to return a bool or an integer, not %s",
+			obj.Type())
+	}
+}
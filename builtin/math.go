@@ -0,0 +1,346 @@
+package builtin
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/lczm/as/environment"
+	"github.com/lczm/as/object"
+)
+
+// Gives back the absolute value of a number, keeping it as an integer
+// or a float.
+func AbsFunc() object.Object {
+	function := &object.BuiltinFunction{
+		Name: "abs",
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("abs() takes in exactly one parameter.")
+			}
+
+			switch obj := args[0].(type) {
+			case *object.Integer:
+				if obj.Value == math.MinInt64 {
+					return newError("abs() cannot be used on %s", obj.String())
+				}
+				if obj.Value < 0 {
+					return &object.Integer{Value: -obj.Value}
+				}
+				return obj
+			case *object.Float:
+				return &object.Float{Value: math.Abs(obj.Value)}
+			default:
+				return newError("abs() can only be used on numbers, not %s", args[0].Type())
+			}
+		},
+	}
+	return function
+}
+
+// min(a, b, ...) and max(a, b, ...) give back the smallest and largest
+// number given, a single list can be given in place of the numbers.
+func MinFunc() object.Object {
+	return extremumFunc("min", func(a float64, b float64) bool { return a < b })
+}
+
+func MaxFunc() object.Object {
+	return extremumFunc("max", func(a float64, b float64) bool { return a > b })
+}
+
+func extremumFunc(name string, better func(float64, float64) bool) object.Object {
+	function := &object.BuiltinFunction{
+		Name: name,
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) == 1 {
+				if list, ok := args[0].(*object.List); ok {
+					args = list.Value
+				}
+			}
+			if len(args) == 0 {
+				return newError("%s() takes in at least one number, or a list of numbers.", name)
+			}
+
+			var result object.Object
+			var resultValue float64
+			for _, arg := range args {
+				value, err := numberArg(name, arg)
+				if err != nil {
+					return err
+				}
+				if result == nil || better(value, resultValue) {
+					result, resultValue = arg, value
+				}
+			}
+			return result
+		},
+	}
+	return function
+}
+
+// Raises a number to a power, integers raised to a non-negative integer
+// power stay as integers.
+func PowFunc() object.Object {
+	function := &object.BuiltinFunction{
+		Name: "pow",
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return newError("pow() takes in two parameters, the base and the exponent.")
+			}
+
+			base, baseOk := args[0].(*object.Integer)
+			exponent, exponentOk := args[1].(*object.Integer)
+			if baseOk && exponentOk && exponent.Value >= 0 {
+				// Exponentiation by squaring, the base is only squared
+				// again while there is a power left to use it for
+				result, square := int64(1), base.Value
+				for power := exponent.Value; power > 0; power >>= 1 {
+					ok := true
+					if power&1 == 1 {
+						result, ok = multiply(result, square)
+					}
+					if ok && power > 1 {
+						square, ok = multiply(square, square)
+					}
+					if !ok {
+						return newError("pow() of %s and %s is too large to be an integer",
+							base.String(), exponent.String())
+					}
+				}
+				return &object.Integer{Value: result}
+			}
+
+			values, err := numberArgs("pow", args)
+			if err != nil {
+				return err
+			}
+			return &object.Float{Value: math.Pow(values[0], values[1])}
+		},
+	}
+	return function
+}
+
+func SqrtFunc() object.Object {
+	return floatFunc("sqrt", func(value float64) (float64, *object.Error) {
+		if value < 0 {
+			return 0, newError("sqrt() cannot be used on a negative number")
+		}
+		return math.Sqrt(value), nil
+	})
+}
+
+func LogFunc() object.Object {
+	return floatFunc("log", func(value float64) (float64, *object.Error) {
+		if value <= 0 {
+			return 0, newError("log() can only be used on a positive number")
+		}
+		return math.Log(value), nil
+	})
+}
+
+func ExpFunc() object.Object {
+	return floatFunc("exp", wrapFloatFunc(math.Exp))
+}
+
+func SinFunc() object.Object {
+	return floatFunc("sin", wrapFloatFunc(math.Sin))
+}
+
+func CosFunc() object.Object {
+	return floatFunc("cos", wrapFloatFunc(math.Cos))
+}
+
+func TanFunc() object.Object {
+	return floatFunc("tan", wrapFloatFunc(math.Tan))
+}
+
+// floor(), ceil() and round() give back integers, rounding halfway
+// cases away from zero.
+func FloorFunc() object.Object {
+	return roundingFunc("floor", math.Floor)
+}
+
+func CeilFunc() object.Object {
+	return roundingFunc("ceil", math.Ceil)
+}
+
+func RoundFunc() object.Object {
+	return roundingFunc("round", math.Round)
+}
+
+// Gives back a float from 0 up to but not including 1
+func RandomFunc(random *rand.Rand) object.Object {
+	function := &object.BuiltinFunction{
+		Name: "random",
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 0 {
+				return newError("random() does not take in any parameters.")
+			}
+			return &object.Float{Value: random.Float64()}
+		},
+	}
+	return function
+}
+
+// Gives back an integer from the start up to but not including the end,
+// the same as the integers of range(), the start defaults to 0.
+// randomInt(end), randomInt(start, end)
+func RandomIntFunc(random *rand.Rand) object.Object {
+	function := &object.BuiltinFunction{
+		Name: "randomInt",
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 && len(args) != 2 {
+				return newError("randomInt() takes in one or two parameters, the start and end.")
+			}
+
+			values := []int64{0, 0}
+			for i, arg := range args {
+				integer, ok := arg.(*object.Integer)
+				if !ok {
+					return newError("randomInt() parameters have to be integers, not %s", arg.Type())
+				}
+				values[i+2-len(args)] = integer.Value
+			}
+
+			start, end := values[0], values[1]
+			if start >= end || end-start <= 0 {
+				return newError("randomInt() range %d to %d is empty", start, end)
+			}
+			return &object.Integer{Value: start + random.Int63n(end-start)}
+		},
+	}
+	return function
+}
+
+// Seeds the random generator, so that random() and randomInt() give
+// back the same values every time the program is run
+func SeedFunc(random *rand.Rand) object.Object {
+	function := &object.BuiltinFunction{
+		Name: "seed",
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("seed() takes in exactly one parameter.")
+			}
+
+			integer, ok := args[0].(*object.Integer)
+			if !ok {
+				return newError("seed() has to be given an integer, not %s", args[0].Type())
+			}
+			random.Seed(integer.Value)
+			return object.NullValue
+		},
+	}
+	return function
+}
+
+// Defines the math constants and functions, every environment gets a
+// random generator of its own.
+func populateMath(env *environment.Environment, random *rand.Rand) {
+	env.Define("PI", &object.Float{Value: math.Pi})
+	env.Define("E", &object.Float{Value: math.E})
+	env.Define("abs", AbsFunc())
+	env.Define("min", MinFunc())
+	env.Define("max", MaxFunc())
+	env.Define("pow", PowFunc())
+	env.Define("sqrt", SqrtFunc())
+	env.Define("floor", FloorFunc())
+	env.Define("ceil", CeilFunc())
+	env.Define("round", RoundFunc())
+	env.Define("sin", SinFunc())
+	env.Define("cos", CosFunc())
+	env.Define("tan", TanFunc())
+	env.Define("log", LogFunc())
+	env.Define("exp", ExpFunc())
+	env.Define("random", RandomFunc(random))
+	env.Define("randomInt", RandomIntFunc(random))
+	env.Define("seed", SeedFunc(random))
+}
+
+// Builtins that take in a single number and give back a float
+func floatFunc(name string, fn func(float64) (float64, *object.Error)) object.Object {
+	function := &object.BuiltinFunction{
+		Name: name,
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("%s() takes in exactly one parameter.", name)
+			}
+			value, err := numberArg(name, args[0])
+			if err != nil {
+				return err
+			}
+			result, err := fn(value)
+			if err != nil {
+				return err
+			}
+			return &object.Float{Value: result}
+		},
+	}
+	return function
+}
+
+func wrapFloatFunc(fn func(float64) float64) func(float64) (float64, *object.Error) {
+	return func(value float64) (float64, *object.Error) {
+		return fn(value), nil
+	}
+}
+
+// Builtins that take in a single number and give back an integer
+func roundingFunc(name string, fn func(float64) float64) object.Object {
+	function := &object.BuiltinFunction{
+		Name: name,
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("%s() takes in exactly one parameter.", name)
+			}
+
+			switch obj := args[0].(type) {
+			case *object.Integer:
+				return obj
+			case *object.Float:
+				value := fn(obj.Value)
+				if math.IsNaN(value) || math.IsInf(value, 0) ||
+					value >= math.MaxInt64 || value < math.MinInt64 {
+					return newError("%s() cannot convert %s", name, obj.String())
+				}
+				return &object.Integer{Value: int64(value)}
+			default:
+				return newError("%s() can only be used on numbers, not %s", name, args[0].Type())
+			}
+		},
+	}
+	return function
+}
+
+// Multiplies two integers, ok is false if the product overflows
+func multiply(a int64, b int64) (int64, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+	product := a * b
+	if product/b != a || (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) {
+		return 0, false
+	}
+	return product, true
+}
+
+// Checks that the arguments are all numbers, and unwraps them as floats
+func numberArgs(name string, args []object.Object) ([]float64, *object.Error) {
+	values := make([]float64, 0, len(args))
+	for _, arg := range args {
+		value, err := numberArg(name, arg)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+func numberArg(name string, arg object.Object) (float64, *object.Error) {
+	switch arg := arg.(type) {
+	case *object.Integer:
+		return float64(arg.Value), nil
+	case *object.Float:
+		return arg.Value, nil
+	}
+	return 0, newError("%s() can only be used on numbers, not %s", name, arg.Type())
+}
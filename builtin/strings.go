@@ -0,0 +1,489 @@
+package builtin
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/lczm/as/object"
+)
+
+// The largest int, strings cannot be any longer than this
+const maxInt = int(^uint(0) >> 1)
+
+// Splits a string by a separator, an empty separator splits the string
+// into its characters.
+func SplitFunc() object.Object {
+	function := &object.BuiltinFunction{
+		Name: "split",
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return newError("split() takes in two parameters, the string and the separator.")
+			}
+			values, err := stringArgs("split", args)
+			if err != nil {
+				return err
+			}
+
+			parts := strings.Split(values[0], values[1])
+			list := make([]object.Object, 0, len(parts))
+			for _, part := range parts {
+				list = append(list, &object.String{Value: part})
+			}
+			return &object.List{Value: list}
+		},
+	}
+	return function
+}
+
+// Joins the elements of a list together with a separator in between,
+// elements are joined the same way that print() shows them.
+func JoinFunc() object.Object {
+	function := &object.BuiltinFunction{
+		Name: "join",
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return newError("join() takes in two parameters, the list and the separator.")
+			}
+
+			list, ok := args[0].(*object.List)
+			if !ok {
+				return newError("join() can only join a list, not %s", args[0].Type())
+			}
+			separator, ok := args[1].(*object.String)
+			if !ok {
+				return newError("join() separator has to be a string, not %s", args[1].Type())
+			}
+
+			parts := make([]string, 0, len(list.Value))
+			for _, element := range list.Value {
+				parts = append(parts, element.String())
+			}
+			return &object.String{Value: strings.Join(parts, separator.Value)}
+		},
+		Size: func(args ...object.Object) int {
+			if len(args) != 2 {
+				return 0
+			}
+			list, ok := args[0].(*object.List)
+			separator, isString := args[1].(*object.String)
+			if !ok || !isString || len(list.Value) == 0 {
+				return 0
+			}
+
+			length := len(separator.Value) * (len(list.Value) - 1)
+			for _, element := range list.Value {
+				length += len(element.String())
+			}
+			return stringSize(length)
+		},
+	}
+	return function
+}
+
+// Removes the whitespace at the start and end of a string
+func TrimFunc() object.Object {
+	return stringFunc("trim", strings.TrimSpace)
+}
+
+func UpperFunc() object.Object {
+	return stringFunc("upper", strings.ToUpper)
+}
+
+func LowerFunc() object.Object {
+	return stringFunc("lower", strings.ToLower)
+}
+
+// Replaces every occurrence of a string with another
+func ReplaceFunc() object.Object {
+	function := &object.BuiltinFunction{
+		Name: "replace",
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 3 {
+				return newError("replace() takes in three parameters, the string, " +
+					"the string to replace and what to replace it with.")
+			}
+			values, err := stringArgs("replace", args)
+			if err != nil {
+				return err
+			}
+			return &object.String{Value: strings.ReplaceAll(values[0], values[1], values[2])}
+		},
+	}
+	return function
+}
+
+func ContainsFunc() object.Object {
+	return stringPredicateFunc("contains", strings.Contains)
+}
+
+func StartsWithFunc() object.Object {
+	return stringPredicateFunc("startsWith", strings.HasPrefix)
+}
+
+func EndsWithFunc() object.Object {
+	return stringPredicateFunc("endsWith", strings.HasSuffix)
+}
+
+// Gives back the character index of the first occurrence of a string,
+// or -1 if it is not found.
+func IndexOfFunc() object.Object {
+	function := &object.BuiltinFunction{
+		Name: "indexOf",
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return newError("indexOf() takes in two parameters, the string and the string to find.")
+			}
+			values, err := stringArgs("indexOf", args)
+			if err != nil {
+				return err
+			}
+
+			index := strings.Index(values[0], values[1])
+			if index == -1 {
+				return &object.Integer{Value: -1}
+			}
+			return &object.Integer{Value: int64(utf8.RuneCountInString(values[0][:index]))}
+		},
+	}
+	return function
+}
+
+// Gives back the characters of a string from the start index up to but
+// not including the end index, the end defaults to the end of the string.
+// substring(string, start), substring(string, start, end)
+func SubstringFunc() object.Object {
+	function := &object.BuiltinFunction{
+		Name: "substring",
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 && len(args) != 3 {
+				return newError("substring() takes in two or three parameters, " +
+					"the string, the start and the end.")
+			}
+
+			str, ok := args[0].(*object.String)
+			if !ok {
+				return newError("substring() can only be used on a string, not %s", args[0].Type())
+			}
+			characters := []rune(str.Value)
+
+			indexes := []int64{0, int64(len(characters))}
+			for i, arg := range args[1:] {
+				integer, ok := arg.(*object.Integer)
+				if !ok {
+					return newError("substring() indexes have to be integers, not %s", arg.Type())
+				}
+				indexes[i] = integer.Value
+			}
+
+			start, end := indexes[0], indexes[1]
+			if start < 0 || end > int64(len(characters)) || start > end {
+				return newError("substring() range %d to %d is out of range for a string of length %d",
+					start, end, len(characters))
+			}
+			return &object.String{Value: string(characters[start:end])}
+		},
+	}
+	return function
+}
+
+func RepeatFunc() object.Object {
+	function := &object.BuiltinFunction{
+		Name: "repeat",
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return newError("repeat() takes in two parameters, the string and the count.")
+			}
+
+			str, ok := args[0].(*object.String)
+			if !ok {
+				return newError("repeat() can only be used on a string, not %s", args[0].Type())
+			}
+			count, ok := args[1].(*object.Integer)
+			if !ok {
+				return newError("repeat() count has to be an integer, not %s", args[1].Type())
+			}
+			if count.Value < 0 {
+				return newError("repeat() count cannot be negative")
+			}
+			if len(str.Value) > 0 && count.Value > int64(maxInt/len(str.Value)) {
+				return newError("repeat() of %d characters %d times is too long", len(str.Value), count.Value)
+			}
+			return &object.String{Value: strings.Repeat(str.Value, int(count.Value))}
+		},
+		Size: func(args ...object.Object) int {
+			if len(args) != 2 {
+				return 0
+			}
+			str, ok := args[0].(*object.String)
+			count, isInteger := args[1].(*object.Integer)
+			if !ok || !isInteger || count.Value < 0 {
+				return 0
+			}
+			if len(str.Value) > 0 && count.Value > int64(maxInt/len(str.Value)) {
+				return 0
+			}
+			return stringSize(len(str.Value) * int(count.Value))
+		},
+	}
+	return function
+}
+
+// printf-style formatting, i.e. format("%s is %.2f", "pi", 3.14159)
+// Supports the verbs
+// %s - any value, the same way that print() shows it
+// %q - any value, the same way that it is shown in a list
+// %d, %x - integers
+// %f, %e, %g - numbers
+// %% - a literal '%'
+// along with the flags, width and precision that go in between.
+func FormatFunc() object.Object {
+	function := &object.BuiltinFunction{
+		Name: "format",
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) < 1 {
+				return newError("format() takes in at least one parameter, the format string.")
+			}
+			format, ok := args[0].(*object.String)
+			if !ok {
+				return newError("format() format has to be a string, not %s", args[0].Type())
+			}
+			return formatString(format.Value, args[1:])
+		},
+		Size: func(args ...object.Object) int {
+			if len(args) < 1 {
+				return 0
+			}
+			format, ok := args[0].(*object.String)
+			if !ok {
+				return 0
+			}
+			return formatSize(format.Value, args[1:])
+		},
+	}
+	return function
+}
+
+func formatString(format string, args []object.Object) object.Object {
+	var result strings.Builder
+	argIndex := 0
+
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			result.WriteByte(format[i])
+			continue
+		}
+
+		// Flags, width and precision, i.e. %-8.2f
+		end := i + 1
+		for end < len(format) && strings.IndexByte("-+# 0123456789.", format[end]) != -1 {
+			end++
+		}
+		if end >= len(format) {
+			return newError("format() has an incomplete verb at the end of the format string")
+		}
+
+		spec, verb := format[i:end+1], format[end]
+		i = end
+
+		if verb == '%' {
+			result.WriteByte('%')
+			continue
+		}
+
+		if argIndex >= len(args) {
+			return newError("format() is missing a value for %s", spec)
+		}
+		arg := args[argIndex]
+		argIndex++
+
+		switch verb {
+		case 's':
+			result.WriteString(fmt.Sprintf(spec, arg.String()))
+		case 'q':
+			result.WriteString(fmt.Sprintf(spec[:len(spec)-1]+"s", arg.FormattedString()))
+		case 'd', 'x':
+			integer, ok := arg.(*object.Integer)
+			if !ok {
+				return newError("format() %s has to be given an integer, not %s", spec, arg.Type())
+			}
+			result.WriteString(fmt.Sprintf(spec, integer.Value))
+		case 'f', 'e', 'g':
+			switch number := arg.(type) {
+			case *object.Float:
+				result.WriteString(fmt.Sprintf(spec, number.Value))
+			case *object.Integer:
+				result.WriteString(fmt.Sprintf(spec, float64(number.Value)))
+			default:
+				return newError("format() %s has to be given a number, not %s", spec, arg.Type())
+			}
+		default:
+			return newError("format() does not support the verb %s", spec)
+		}
+	}
+
+	if argIndex < len(args) {
+		return newError("format() was given %d values but only uses %d", len(args), argIndex)
+	}
+	return &object.String{Value: result.String()}
+}
+
+// How long formatString() makes the string at most, every verb writes its
+// value padded out to its width or precision, which fmt does not let go
+// over a million characters
+func formatSize(format string, args []object.Object) int {
+	length := len(format)
+	argIndex := 0
+
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+
+		end := i + 1
+		for end < len(format) && strings.IndexByte("-+# 0123456789.", format[end]) != -1 {
+			end++
+		}
+		if end >= len(format) {
+			return 0
+		}
+
+		for _, number := range strings.FieldsFunc(format[i+1:end], isNotDigit) {
+			padding, err := strconv.Atoi(number)
+			if err != nil || padding > 1000000 {
+				padding = 1000000
+			}
+			length += padding
+		}
+
+		verb := format[end]
+		i = end
+		if verb == '%' || argIndex >= len(args) {
+			continue
+		}
+		arg := args[argIndex]
+		argIndex++
+
+		switch verb {
+		case 's':
+			length += len(arg.String())
+		case 'q':
+			length += len(arg.FormattedString())
+		default:
+			// Integers and floats without a precision
+			length += 32
+		}
+	}
+	return stringSize(length)
+}
+
+func isNotDigit(r rune) bool {
+	return r < '0' || r > '9'
+}
+
+// The size that the memory limit counts a string of length bytes as
+func stringSize(length int) int {
+	if length > maxInt-16 {
+		return maxInt
+	}
+	return 16 + length
+}
+
+// Gives back the unicode code point of a single character
+func OrdFunc() object.Object {
+	function := &object.BuiltinFunction{
+		Name: "ord",
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("ord() takes in exactly one parameter.")
+			}
+
+			str, ok := args[0].(*object.String)
+			if !ok {
+				return newError("ord() can only be used on a string, not %s", args[0].Type())
+			}
+			if utf8.RuneCountInString(str.Value) != 1 {
+				return newError("ord() expected a single character, but got a string of length %d",
+					utf8.RuneCountInString(str.Value))
+			}
+
+			character, _ := utf8.DecodeRuneInString(str.Value)
+			return &object.Integer{Value: int64(character)}
+		},
+	}
+	return function
+}
+
+// Gives back the character of a unicode code point
+func ChrFunc() object.Object {
+	function := &object.BuiltinFunction{
+		Name: "chr",
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("chr() takes in exactly one parameter.")
+			}
+
+			integer, ok := args[0].(*object.Integer)
+			if !ok {
+				return newError("chr() can only be used on an integer, not %s", args[0].Type())
+			}
+			if integer.Value < 0 || integer.Value > utf8.MaxRune ||
+				!utf8.ValidRune(rune(integer.Value)) {
+				return newError("chr() %d is not a valid character", integer.Value)
+			}
+			return &object.String{Value: string(rune(integer.Value))}
+		},
+	}
+	return function
+}
+
+// Builtins that take in a single string and give back a string
+func stringFunc(name string, fn func(string) string) object.Object {
+	function := &object.BuiltinFunction{
+		Name: name,
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("%s() takes in exactly one parameter.", name)
+			}
+			values, err := stringArgs(name, args)
+			if err != nil {
+				return err
+			}
+			return &object.String{Value: fn(values[0])}
+		},
+	}
+	return function
+}
+
+// Builtins that take in two strings and give back a bool
+func stringPredicateFunc(name string, fn func(string, string) bool) object.Object {
+	function := &object.BuiltinFunction{
+		Name: name,
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return newError("%s() takes in two parameters, the string and the string to look for.",
+					name)
+			}
+			values, err := stringArgs(name, args)
+			if err != nil {
+				return err
+			}
+			return &object.Bool{Value: fn(values[0], values[1])}
+		},
+	}
+	return function
+}
+
+// Checks that all the arguments are strings, and unwraps them
+func stringArgs(name string, args []object.Object) ([]string, *object.Error) {
+	values := make([]string, 0, len(args))
+	for _, arg := range args {
+		str, ok := arg.(*object.String)
+		if !ok {
+			return nil, newError("%s() can only be used on strings, not %s", name, arg.Type())
+		}
+		values = append(values, str.Value)
+	}
+	return values, nil
+}
@@ -0,0 +1,323 @@
+package compiler
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lczm/as/object"
+	"github.com/lczm/as/token"
+)
+
+type Opcode byte
+
+// Operands are unsigned and big-endian, constants, locals, upvalues and
+// jump targets all take up two bytes.
+const (
+	OpConstant Opcode = iota
+	OpNull
+	// The value of a local before its declaration is reached, it is only
+	// seen by closures that are called before then
+	OpUndefined
+	OpTrue
+	OpFalse
+	OpPop
+
+	// Variables, globals are looked up by name from the constant pool,
+	// locals and upvalues by the slot the compiler resolved them to
+	OpDefineGlobal
+	OpGetGlobal
+	OpSetGlobal
+	OpGetLocal
+	OpSetLocal
+	OpGetUpvalue
+	OpSetUpvalue
+	OpCloseUpvalue
+
+	// Operators
+	OpAdd
+	OpSubtract
+	OpMultiply
+	OpDivide
+	OpModulus
+	OpEqual
+	OpNotEqual
+	OpGreater
+	OpGreaterEqual
+	OpLess
+	OpLessEqual
+	OpMinus
+	OpNot
+	OpTruthy
+
+	// Jumps are to an absolute offset within the function
+	OpJump
+	OpJumpIfFalse
+
+	// Functions, OpClosure is followed by an is-local byte and an index
+	// for every upvalue that the function captures
+	OpCall
+	OpReturn
+	OpClosure
+
+	// Containers
+	OpList
+	OpHashMap
+	OpInterpolate
+	OpSetIndex
+
+	// Structs
+	OpStruct
+	OpInherit
+	OpMethod
+	OpAttributes
+	OpGetAttribute
+	OpSetAttribute
+	OpGetSuper
+
+	// for-in loops
+	OpIterator
+	OpIterate
+
+	OpImport
+
+	// try/catch, OpTry sets a handler that errors jump to until
+	// OpEndTry takes it off, OpThrow raises the value on the stack
+	OpTry
+	OpEndTry
+	OpThrow
+
+	// Raises a runtime error with a message from the constant pool, for
+	// errors that the tree-walker only reports once the code is run
+	OpError
+)
+
+type Definition struct {
+	Name string
+	// The number of bytes that each operand takes up
+	OperandWidths []int
+}
+
+var definitions = map[Opcode]*Definition{
+	OpConstant:  {"OpConstant", []int{2}},
+	OpNull:      {"OpNull", []int{}},
+	OpUndefined: {"OpUndefined", []int{}},
+	OpTrue:      {"OpTrue", []int{}},
+	OpFalse:     {"OpFalse", []int{}},
+	OpPop:       {"OpPop", []int{}},
+
+	OpDefineGlobal: {"OpDefineGlobal", []int{2}},
+	OpGetGlobal:    {"OpGetGlobal", []int{2}},
+	OpSetGlobal:    {"OpSetGlobal", []int{2}},
+	OpGetLocal:     {"OpGetLocal", []int{2}},
+	OpSetLocal:     {"OpSetLocal", []int{2}},
+	OpGetUpvalue:   {"OpGetUpvalue", []int{2}},
+	OpSetUpvalue:   {"OpSetUpvalue", []int{2}},
+	OpCloseUpvalue: {"OpCloseUpvalue", []int{}},
+
+	OpAdd:          {"OpAdd", []int{}},
+	OpSubtract:     {"OpSubtract", []int{}},
+	OpMultiply:     {"OpMultiply", []int{}},
+	OpDivide:       {"OpDivide", []int{}},
+	OpModulus:      {"OpModulus", []int{}},
+	OpEqual:        {"OpEqual", []int{}},
+	OpNotEqual:     {"OpNotEqual", []int{}},
+	OpGreater:      {"OpGreater", []int{}},
+	OpGreaterEqual: {"OpGreaterEqual", []int{}},
+	OpLess:         {"OpLess", []int{}},
+	OpLessEqual:    {"OpLessEqual", []int{}},
+	OpMinus:        {"OpMinus", []int{}},
+	OpNot:          {"OpNot", []int{}},
+	OpTruthy:       {"OpTruthy", []int{}},
+
+	OpJump:        {"OpJump", []int{2}},
+	OpJumpIfFalse: {"OpJumpIfFalse", []int{2}},
+
+	// The number of arguments
+	OpCall:   {"OpCall", []int{1}},
+	OpReturn: {"OpReturn", []int{}},
+	// The function constant and the number of upvalues
+	OpClosure: {"OpClosure", []int{2, 2}},
+
+	// The number of elements, or pairs for hashmaps
+	OpList:        {"OpList", []int{2}},
+	OpHashMap:     {"OpHashMap", []int{2}},
+	OpInterpolate: {"OpInterpolate", []int{2}},
+	OpSetIndex:    {"OpSetIndex", []int{}},
+
+	OpStruct:     {"OpStruct", []int{2}},
+	OpInherit:    {"OpInherit", []int{}},
+	OpMethod:     {"OpMethod", []int{2}},
+	OpAttributes: {"OpAttributes", []int{}},
+	// The name, and whether it is being called as a method
+	OpGetAttribute: {"OpGetAttribute", []int{2, 1}},
+	OpSetAttribute: {"OpSetAttribute", []int{2}},
+	OpGetSuper:     {"OpGetSuper", []int{2}},
+
+	// Whether the loop takes in both the key and value
+	OpIterator: {"OpIterator", []int{1}},
+	// Where to jump to once there are no elements left
+	OpIterate: {"OpIterate", []int{2}},
+
+	OpImport: {"OpImport", []int{2}},
+
+	// Where the handler is, and the slot that the error is put into
+	OpTry:    {"OpTry", []int{2, 2}},
+	OpEndTry: {"OpEndTry", []int{}},
+	OpThrow:  {"OpThrow", []int{}},
+
+	OpError: {"OpError", []int{2}},
+}
+
+func Lookup(op Opcode) (*Definition, error) {
+	definition, ok := definitions[op]
+	if !ok {
+		return nil, fmt.Errorf("opcode %d undefined", op)
+	}
+	return definition, nil
+}
+
+// Encodes an instruction, i.e. Make(OpConstant, 65534)
+func Make(op Opcode, operands ...int) []byte {
+	definition, ok := definitions[op]
+	if !ok {
+		return []byte{}
+	}
+
+	length := 1
+	for _, width := range definition.OperandWidths {
+		length += width
+	}
+
+	instruction := make([]byte, length)
+	instruction[0] = byte(op)
+
+	offset := 1
+	for i, operand := range operands {
+		width := definition.OperandWidths[i]
+		switch width {
+		case 2:
+			binary.BigEndian.PutUint16(instruction[offset:], uint16(operand))
+		case 1:
+			instruction[offset] = byte(operand)
+		}
+		offset += width
+	}
+	return instruction
+}
+
+// Decodes the operands of an instruction, giving back the operands
+// and the number of bytes that they took up
+func ReadOperands(definition *Definition, ins Instructions) ([]int, int) {
+	operands := make([]int, len(definition.OperandWidths))
+	offset := 0
+
+	for i, width := range definition.OperandWidths {
+		switch width {
+		case 2:
+			operands[i] = int(ReadUint16(ins[offset:]))
+		case 1:
+			operands[i] = int(ins[offset])
+		}
+		offset += width
+	}
+	return operands, offset
+}
+
+func ReadUint16(ins Instructions) uint16 {
+	return binary.BigEndian.Uint16(ins)
+}
+
+type Instructions []byte
+
+// Disassembles the instructions, one instruction per line i.e.
+// 0000 OpConstant 0
+// 0003 OpClosure 1 1 [local 0]
+func (ins Instructions) String() string {
+	var out strings.Builder
+
+	i := 0
+	for i < len(ins) {
+		definition, err := Lookup(Opcode(ins[i]))
+		if err != nil {
+			fmt.Fprintf(&out, "ERROR: %s\n", err)
+			i++
+			continue
+		}
+
+		operands, read := ReadOperands(definition, ins[i+1:])
+		fmt.Fprintf(&out, "%04d %s", i, definition.Name)
+		for _, operand := range operands {
+			fmt.Fprintf(&out, " %d", operand)
+		}
+		i += 1 + read
+
+		// The upvalues that a closure captures follow after it
+		if Opcode(ins[i-1-read]) == OpClosure {
+			for j := 0; j < operands[1]; j++ {
+				kind := "upvalue"
+				if ins[i] == 1 {
+					kind = "local"
+				}
+				fmt.Fprintf(&out, " [%s %d]", kind, ReadUint16(ins[i+1:]))
+				i += 3
+			}
+		}
+		out.WriteString("\n")
+	}
+	return out.String()
+}
+
+// Where an instruction came from in the source, so that runtime errors
+// can point to it
+type Position struct {
+	Offset int
+	Token  token.Token
+}
+
+// A function that has been compiled into bytecode, every function has
+// a constant pool of its own. The top level of a file is compiled into
+// a function as well.
+type CompiledFunction struct {
+	Name         string
+	Instructions Instructions
+	Constants    []object.Object
+	// Sorted by offset, not every instruction has a position
+	Positions []Position
+	NumParams int
+	// Whether it sets the attributes of new instances, these are not
+	// called by scripts and are left out of the call stack
+	Attributes bool
+}
+
+func (cf *CompiledFunction) RawType() string {
+	return object.FUNCTION
+}
+
+func (cf *CompiledFunction) Type() string {
+	return fmt.Sprintf("<type: %s>", object.FUNCTION)
+}
+
+func (cf *CompiledFunction) String() string {
+	if cf.Name == "" {
+		return "Function : <anonymous>"
+	}
+	return fmt.Sprintf("Function : <%s>", cf.Name)
+}
+
+func (cf *CompiledFunction) FormattedString() string {
+	return cf.String()
+}
+
+// Gives back the position of the closest instruction at or before
+// the offset
+func (cf *CompiledFunction) Position(offset int) token.Token {
+	index := sort.Search(len(cf.Positions), func(i int) bool {
+		return cf.Positions[i].Offset > offset
+	})
+	if index == 0 {
+		return token.Token{}
+	}
+	return cf.Positions[index-1].Token
+}
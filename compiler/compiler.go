@@ -0,0 +1,874 @@
+package compiler
+
+import (
+	"math"
+	"sort"
+
+	"github.com/lczm/as/ast"
+	"github.com/lczm/as/errors"
+	"github.com/lczm/as/object"
+	"github.com/lczm/as/token"
+)
+
+// The most that can fit into the operands of an instruction
+const (
+	MAX_OPERAND   = math.MaxUint16
+	MAX_ARGUMENTS = math.MaxUint8
+)
+
+// What the first slot of a function holds, it is the function itself
+// for plain functions and the instance for methods
+type functionKind int
+
+const (
+	scriptFunction functionKind = iota
+	plainFunction
+	methodFunction
+	// Sets the attributes of a new instance, the instance cannot be
+	// referred to with 'this' so that it matches the tree-walker
+	attributesFunction
+)
+
+type local struct {
+	name  string
+	depth int
+	// Locals that closures refer to are moved off of the stack
+	// when they go out of scope instead of being popped
+	captured bool
+	// Whether the declaration of the local has been reached, locals are
+	// given their slot at the start of their scope
+	declared bool
+}
+
+type upvalue struct {
+	index   int
+	isLocal bool
+}
+
+type loop struct {
+	// Locals deeper than this are discarded when breaking out of the loop
+	scopeDepth int
+	// Where 'continue' jumps back to, -1 for for loops, as they jump
+	// forward to the effect which has not been compiled yet
+	start     int
+	breaks    []int
+	continues []int
+}
+
+// A try statement that code is being compiled within, leaving it early
+// with return, break or continue has to take off its handler and run
+// finally on the way out
+type try struct {
+	finally *ast.BlockStatement
+	// The number of loops that the try is in
+	loops int
+}
+
+// Compiles the AST into bytecode, there is one compiler for every
+// function, enclosing is the compiler of the function it is declared in.
+type Compiler struct {
+	function  *CompiledFunction
+	enclosing *Compiler
+	// Names of globals and attributes are only added to the
+	// constant pool once
+	names map[string]int
+
+	// Locals are resolved to the slot they take up on the stack
+	locals     []local
+	upvalues   []upvalue
+	scopeDepth int
+	loops      []*loop
+	tries      []*try
+
+	// Shared with the enclosing compilers
+	errors *[]errors.Error
+}
+
+// Compiles the statements of a file into a function that runs them,
+// variables at the top level of the file are globals.
+func Compile(statements []ast.Statement) (*CompiledFunction, error) {
+	errorList := make([]errors.Error, 0)
+	c := newCompiler(nil, scriptFunction, "", &errorList)
+
+	c.statements(statements)
+	c.emit(OpNull)
+	c.emit(OpReturn)
+
+	if len(errorList) > 0 {
+		return nil, errorList[0]
+	}
+	return c.function, nil
+}
+
+func newCompiler(enclosing *Compiler, kind functionKind, name string,
+	errorList *[]errors.Error) *Compiler {
+
+	// The first slot cannot be referred to by name, other than 'this'
+	slot := ""
+	if kind == methodFunction {
+		slot = "this"
+	}
+
+	c := &Compiler{
+		function:  &CompiledFunction{Name: name},
+		enclosing: enclosing,
+		names:     make(map[string]int),
+		locals:    []local{{name: slot, depth: 0, declared: true}},
+		errors:    errorList,
+	}
+	return c
+}
+
+func (c *Compiler) statements(statements []ast.Statement) {
+	for _, stmt := range statements {
+		c.statement(stmt)
+	}
+}
+
+func (c *Compiler) statement(stmt ast.Statement) {
+	switch stmt := stmt.(type) {
+	case *ast.StatementExpression:
+		c.expression(stmt.Expr)
+		c.emit(OpPop)
+	case *ast.VariableStatement:
+		// `var a;`, 'a' will be defined as null when it is not initialized
+		if stmt.Initializer != nil {
+			c.expression(stmt.Initializer)
+		} else {
+			c.emit(OpNull)
+		}
+		c.defineVariable(stmt.Name)
+	case *ast.FunctionStatement:
+		c.functionStatement(stmt)
+	case *ast.StructStatement:
+		c.structStatement(stmt)
+	case *ast.BlockStatement:
+		c.beginScope()
+		c.declareLocals(stmt.Statements)
+		c.statements(stmt.Statements)
+		c.endScope()
+	case *ast.IfStatement:
+		c.ifStatement(stmt)
+	case *ast.WhileStatement:
+		c.whileStatement(stmt)
+	case *ast.ForStatement:
+		c.forStatement(stmt)
+	case *ast.ForInStatement:
+		c.forInStatement(stmt)
+	case *ast.ReturnStatement:
+		c.returnStatement(stmt)
+	case *ast.BreakStatement:
+		loop := c.loops[len(c.loops)-1]
+		c.exitTries(len(c.loops))
+		c.discardLocals(loop.scopeDepth)
+		loop.breaks = append(loop.breaks, c.emitJump(OpJump))
+	case *ast.ContinueStatement:
+		loop := c.loops[len(c.loops)-1]
+		c.exitTries(len(c.loops))
+		c.discardLocals(loop.scopeDepth)
+		if loop.start >= 0 {
+			c.emit(OpJump, loop.start)
+		} else {
+			loop.continues = append(loop.continues, c.emitJump(OpJump))
+		}
+	case *ast.ImportStatement:
+		c.importStatement(stmt)
+	case *ast.TryStatement:
+		c.tryStatement(stmt)
+	case *ast.ThrowStatement:
+		c.expression(stmt.Value)
+		c.emitAt(stmt.Keyword, OpThrow)
+	}
+}
+
+// The value is kept in a local while the finally of the tries that
+// are returned out of run
+func (c *Compiler) returnStatement(stmt *ast.ReturnStatement) {
+	if stmt.Value != nil {
+		c.expression(stmt.Value)
+	} else {
+		c.emit(OpNull)
+	}
+
+	if len(c.tries) == 0 {
+		c.emitAt(stmt.Keyword, OpReturn)
+		return
+	}
+	c.beginScope()
+	slot := c.addLocal(token.Token{})
+	c.exitTries(0)
+	c.emit(OpGetLocal, slot)
+	c.emitAt(stmt.Keyword, OpReturn)
+	c.endScope()
+}
+
+// The error that a handler catches is put into the slot after the locals
+// that are in scope of the try. finally is compiled in wherever the try
+// can be left: after the body, after catch, and in a handler that runs it
+// before raising errors that are not caught again.
+func (c *Compiler) tryStatement(stmt *ast.TryStatement) {
+	slot := len(c.locals)
+	loops := len(c.loops)
+
+	handler := c.emit(OpTry, 0, slot)
+	c.tries = append(c.tries, &try{finally: stmt.Finally, loops: loops})
+	c.statement(stmt.Body)
+	c.tries = c.tries[:len(c.tries)-1]
+	c.emit(OpEndTry)
+	if stmt.Finally != nil {
+		c.statement(stmt.Finally)
+	}
+	exits := []int{c.emitJump(OpJump)}
+
+	if stmt.Catch != nil {
+		c.patchJump(handler)
+		if stmt.Finally != nil {
+			handler = c.emit(OpTry, 0, slot)
+			c.tries = append(c.tries, &try{finally: stmt.Finally, loops: loops})
+		}
+
+		// The error is declared in the same scope as the body of catch
+		name := token.Token{}
+		if stmt.Name != nil {
+			name = *stmt.Name
+		}
+		c.beginScope()
+		c.addLocal(name)
+		c.declareLocals(stmt.Catch.Statements)
+		c.statements(stmt.Catch.Statements)
+		c.endScope()
+
+		if stmt.Finally != nil {
+			c.tries = c.tries[:len(c.tries)-1]
+			c.emit(OpEndTry)
+			c.statement(stmt.Finally)
+		}
+		exits = append(exits, c.emitJump(OpJump))
+	}
+
+	if stmt.Finally != nil {
+		c.patchJump(handler)
+		c.beginScope()
+		caught := c.addLocal(token.Token{})
+		c.statement(stmt.Finally)
+		c.emit(OpGetLocal, caught)
+		c.emitAt(stmt.Keyword, OpThrow)
+		c.endScope()
+	}
+	c.patchJumps(exits)
+}
+
+// Takes off the handlers and runs the finally of the tries that are being
+// left early, innermost first. Tries that are outside of the loop that is
+// being left are not, return leaves all of them with loops as 0.
+func (c *Compiler) exitTries(loops int) {
+	tries := c.tries
+	for i := len(tries) - 1; i >= 0 && tries[i].loops >= loops; i-- {
+		c.emit(OpEndTry)
+		// A return or break within finally only leaves the tries around it
+		c.tries = tries[:i]
+		if tries[i].finally != nil {
+			c.statement(tries[i].finally)
+		}
+	}
+	c.tries = tries
+}
+
+func (c *Compiler) functionStatement(stmt *ast.FunctionStatement) {
+	if c.scopeDepth == 0 {
+		c.compileFunction(plainFunction, stmt.Name.Literal, stmt.Params, stmt.Body.Statements)
+		c.emit(OpDefineGlobal, c.nameConstant(stmt.Name.Literal))
+		return
+	}
+
+	// The local is declared before the function is compiled, so that
+	// the function can refer to itself
+	if slot, ok := c.localInScope(stmt.Name.Literal); ok {
+		c.locals[slot].declared = true
+		c.compileFunction(plainFunction, stmt.Name.Literal, stmt.Params, stmt.Body.Statements)
+		c.emit(OpSetLocal, slot)
+		c.emit(OpPop)
+		return
+	}
+	c.addLocal(stmt.Name)
+	c.compileFunction(plainFunction, stmt.Name.Literal, stmt.Params, stmt.Body.Statements)
+}
+
+// The struct is declared first, and the methods are added on to it.
+// Methods of a struct with a parent are compiled in a scope that has
+// 'super' declared in it, so that they can reach the parent methods.
+func (c *Compiler) structStatement(stmt *ast.StructStatement) {
+	c.emitAt(stmt.Name, OpStruct, c.nameConstant(stmt.Name.Literal))
+	c.defineVariable(stmt.Name)
+
+	if len(stmt.Attributes) > 0 {
+		c.variable(stmt.Name)
+		c.attributes(stmt)
+		c.emit(OpAttributes)
+		c.emit(OpPop)
+	}
+
+	if stmt.Parent != nil {
+		c.beginScope()
+		c.variable(stmt.Parent.Name)
+		c.addLocal(token.Token{Literal: "super"})
+		c.variable(stmt.Name)
+		c.emitAt(stmt.Parent.Name, OpInherit)
+	}
+
+	c.variable(stmt.Name)
+	methods := make([]*ast.FunctionStatement, 0, len(stmt.Methods))
+	for _, method := range stmt.Methods {
+		methods = append(methods, method.(*ast.FunctionStatement))
+	}
+	sort.Slice(methods, func(i, j int) bool {
+		return methods[i].Name.Literal < methods[j].Name.Literal
+	})
+	for _, method := range methods {
+		c.compileFunction(methodFunction, method.Name.Literal, method.Params, method.Body.Statements)
+		c.emit(OpMethod, c.nameConstant(method.Name.Literal))
+	}
+	c.emit(OpPop)
+
+	if stmt.Parent != nil {
+		c.endScope()
+	}
+}
+
+// Compiles the attribute initializers of a struct into a function that
+// sets them on the instance it is called on
+func (c *Compiler) attributes(stmt *ast.StructStatement) {
+	names := make([]token.Token, 0, len(stmt.Attributes))
+	for name := range stmt.Attributes {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return names[i].Literal < names[j].Literal
+	})
+
+	fc := newCompiler(c, attributesFunction, stmt.Name.Literal, c.errors)
+	fc.function.Attributes = true
+	fc.scopeDepth = 1
+	for _, name := range names {
+		// Uninitialized attributes default to null, the same as `var a;` does
+		initializer := stmt.Attributes[name].(*ast.VariableStatement).Initializer
+		if initializer != nil {
+			fc.expression(initializer)
+		} else {
+			fc.emit(OpNull)
+		}
+		fc.emit(OpGetLocal, 0)
+		fc.emitAt(name, OpSetAttribute, fc.nameConstant(name.Literal))
+		fc.emit(OpPop)
+	}
+	fc.emit(OpNull)
+	fc.emit(OpReturn)
+
+	c.closure(fc)
+}
+
+func (c *Compiler) ifStatement(stmt *ast.IfStatement) {
+	c.expression(stmt.Condition)
+	elseJump := c.emitJump(OpJumpIfFalse)
+	c.statement(stmt.Then)
+
+	if stmt.Else == nil {
+		c.patchJump(elseJump)
+		return
+	}
+
+	endJump := c.emitJump(OpJump)
+	c.patchJump(elseJump)
+	c.statement(stmt.Else)
+	c.patchJump(endJump)
+}
+
+func (c *Compiler) whileStatement(stmt *ast.WhileStatement) {
+	start := len(c.function.Instructions)
+	c.expression(stmt.Condition)
+	exitJump := c.emitJump(OpJumpIfFalse)
+
+	loop := c.loopBody(stmt.Body, start)
+	c.emit(OpJump, start)
+
+	c.patchJump(exitJump)
+	c.patchJumps(loop.breaks)
+}
+
+// The variable of a for loop is declared in the scope that the loop is
+// in, the same as the tree-walker does
+func (c *Compiler) forStatement(stmt *ast.ForStatement) {
+	c.statement(stmt.Variable)
+
+	start := len(c.function.Instructions)
+	c.expression(stmt.Condition)
+	exitJump := c.emitJump(OpJumpIfFalse)
+
+	// 'continue' still runs the effect before the next iteration
+	loop := c.loopBody(stmt.Body, -1)
+	c.patchJumps(loop.continues)
+	c.expression(stmt.Effect)
+	c.emit(OpPop)
+	c.emit(OpJump, start)
+
+	c.patchJump(exitJump)
+	c.patchJumps(loop.breaks)
+}
+
+// The iterator is kept in a local that cannot be referred to by name,
+// every iteration declares the loop variables in a scope of its own, so
+// that closures declared in the body hold on to the values of that iteration
+func (c *Compiler) forInStatement(stmt *ast.ForInStatement) {
+	c.beginScope()
+	c.expression(stmt.Iterable)
+	keyed := 0
+	if stmt.Key != nil {
+		keyed = 1
+	}
+	c.emitAt(stmt.In, OpIterator, keyed)
+	iterator := c.addLocal(token.Token{})
+
+	start := len(c.function.Instructions)
+	c.emit(OpGetLocal, iterator)
+	c.function.Positions = append(c.function.Positions,
+		Position{Offset: len(c.function.Instructions), Token: stmt.In})
+	exitJump := c.emitJump(OpIterate)
+
+	c.beginScope()
+	if stmt.Key != nil {
+		c.addLocal(*stmt.Key)
+	}
+	c.addLocal(stmt.Value)
+	c.declareLocals([]ast.Statement{stmt.Body})
+	loop := &loop{scopeDepth: c.scopeDepth - 1, start: start}
+	c.loops = append(c.loops, loop)
+	c.statement(stmt.Body)
+	c.loops = c.loops[:len(c.loops)-1]
+	c.endScope()
+	c.emit(OpJump, start)
+
+	c.patchJump(exitJump)
+	c.patchJumps(loop.breaks)
+	c.endScope()
+}
+
+func (c *Compiler) loopBody(body ast.Statement, start int) *loop {
+	loop := &loop{scopeDepth: c.scopeDepth, start: start}
+	c.loops = append(c.loops, loop)
+	c.statement(body)
+	c.loops = c.loops[:len(c.loops)-1]
+	return loop
+}
+
+// import "path"; defines the module under the name of the file,
+// import name from "path"; defines it under the given name.
+func (c *Compiler) importStatement(stmt *ast.ImportStatement) {
+	name, ok := stmt.ModuleName()
+	if !ok {
+		c.emitError(stmt.Path, "Cannot name a module after \""+stmt.Path.Literal+
+			"\", use 'import name from \""+stmt.Path.Literal+"\";'")
+		return
+	}
+
+	c.emitAt(stmt.Path, OpImport, c.constant(&object.String{Value: stmt.Path.Literal}))
+	c.defineVariable(token.Token{Literal: name})
+}
+
+func (c *Compiler) expression(expr ast.Expression) {
+	switch expr := expr.(type) {
+	case *ast.NumberExpression:
+		c.emit(OpConstant, c.constant(&object.Integer{Value: int64(expr.Value)}))
+	case *ast.FloatExpression:
+		c.emit(OpConstant, c.constant(&object.Float{Value: expr.Value}))
+	case *ast.StringExpression:
+		c.emit(OpConstant, c.constant(&object.String{Value: expr.Value}))
+	case *ast.BoolExpression:
+		if expr.Value {
+			c.emit(OpTrue)
+		} else {
+			c.emit(OpFalse)
+		}
+	case *ast.NullExpression:
+		c.emit(OpNull)
+	case *ast.GroupExpression:
+		c.expression(expr.Expr)
+	case *ast.InterpolationExpression:
+		for _, part := range expr.Parts {
+			c.expression(part)
+		}
+		c.emit(OpInterpolate, len(expr.Parts))
+	case *ast.ListExpression:
+		for _, value := range expr.Values {
+			c.expression(value)
+		}
+		c.emit(OpList, len(expr.Values))
+	case *ast.HashMapExpression:
+		for key, value := range expr.Values {
+			c.expression(key)
+			c.expression(value)
+		}
+		c.emitAt(expr.Token, OpHashMap, len(expr.Values))
+	case *ast.VariableExpression:
+		c.variable(expr.Name)
+	case *ast.AssignmentExpression:
+		c.expression(expr.Value)
+		c.setVariable(expr.Name)
+	case *ast.AssignmentIndexExpression:
+		c.expression(expr.Value)
+		c.expression(expr.Index)
+		c.expression(expr.Object)
+		c.emitAt(expr.Token, OpSetIndex)
+	case *ast.AssignmentStruct:
+		attribute := expr.Attribute.(*ast.VariableExpression)
+		c.expression(expr.Value)
+		c.expression(expr.Object)
+		c.emitAt(attribute.Name, OpSetAttribute, c.nameConstant(attribute.Name.Literal))
+	case *ast.BinaryExpression:
+		c.expression(expr.Left)
+		c.expression(expr.Right)
+		c.emitAt(expr.Operator, binaryOperators[expr.Operator.Type])
+	case *ast.UnaryExpression:
+		c.expression(expr.Right)
+		if expr.Operator.Type == token.BANG {
+			c.emitAt(expr.Operator, OpNot)
+		} else {
+			c.emitAt(expr.Operator, OpMinus)
+		}
+	case *ast.LogicalExpression:
+		c.logicalExpression(expr)
+	case *ast.CallExpression:
+		c.expression(expr.Callee)
+		for _, argument := range expr.Arguments {
+			c.expression(argument)
+		}
+		if len(expr.Arguments) > MAX_ARGUMENTS {
+			c.error(expr.Token, "Cannot have more than 255 arguments")
+		}
+		c.emitAt(expr.Token, OpCall, len(expr.Arguments))
+	case *ast.GetExpression:
+		attribute := expr.Caller.(*ast.VariableExpression)
+		isMethod := 0
+		if expr.IsMethod {
+			isMethod = 1
+		}
+		c.expression(expr.Callee)
+		c.emitAt(attribute.Name, OpGetAttribute, c.nameConstant(attribute.Name.Literal), isMethod)
+	case *ast.FunctionExpression:
+		c.compileFunction(plainFunction, "", expr.Params, expr.Body.Statements)
+	case *ast.ThisExpression:
+		if !c.localVariable(expr.Keyword) {
+			c.emitError(expr.Keyword, "Cannot use 'this' outside of a method")
+		}
+	case *ast.SuperExpression:
+		// super.method is the parent's method, bound to the same instance
+		// that 'this' refers to
+		if !c.localVariable(expr.Keyword) {
+			c.emitError(expr.Keyword, "Cannot use 'super' outside of a struct with a parent")
+			return
+		}
+		this := expr.Keyword
+		this.Literal = "this"
+		if !c.localVariable(this) {
+			c.emitError(expr.Keyword, "Cannot use 'super' outside of a method")
+			return
+		}
+		c.emitAt(expr.Method, OpGetSuper, c.nameConstant(expr.Method.Literal))
+	}
+}
+
+var binaryOperators = map[token.TokenType]Opcode{
+	token.PLUS:     OpAdd,
+	token.MINUS:    OpSubtract,
+	token.ASTERISK: OpMultiply,
+	token.SLASH:    OpDivide,
+	token.MODULUS:  OpModulus,
+	token.EQ:       OpEqual,
+	token.NOT_EQ:   OpNotEqual,
+	token.GT:       OpGreater,
+	token.GT_EQ:    OpGreaterEqual,
+	token.LT:       OpLess,
+	token.LT_EQ:    OpLessEqual,
+}
+
+// && and || short circuit, and always give back a bool
+func (c *Compiler) logicalExpression(expr *ast.LogicalExpression) {
+	c.expression(expr.Left)
+	falseJump := c.emitJump(OpJumpIfFalse)
+
+	if expr.Operator.Type == token.AND {
+		c.expression(expr.Right)
+		c.emit(OpTruthy)
+		endJump := c.emitJump(OpJump)
+		c.patchJump(falseJump)
+		c.emit(OpFalse)
+		c.patchJump(endJump)
+		return
+	}
+
+	c.emit(OpTrue)
+	endJump := c.emitJump(OpJump)
+	c.patchJump(falseJump)
+	c.expression(expr.Right)
+	c.emit(OpTruthy)
+	c.patchJump(endJump)
+}
+
+// Compiles a function in a compiler of its own, and creates a closure
+// of it in this one
+func (c *Compiler) compileFunction(kind functionKind, name string, params []token.Token,
+	body []ast.Statement) {
+
+	fc := newCompiler(c, kind, name, c.errors)
+	fc.function.NumParams = len(params)
+	if len(params) > MAX_ARGUMENTS {
+		c.error(params[MAX_ARGUMENTS], "Cannot have more than 255 parameters")
+	}
+
+	// Parameters and the body share the same scope
+	fc.scopeDepth = 1
+	for _, param := range params {
+		fc.addLocal(param)
+	}
+	fc.declareLocals(body)
+	fc.statements(body)
+
+	// Functions that do not return anything give back null
+	fc.emit(OpNull)
+	fc.emit(OpReturn)
+
+	c.closure(fc)
+}
+
+func (c *Compiler) closure(fc *Compiler) {
+	c.emit(OpClosure, c.constant(fc.function), len(fc.upvalues))
+	for _, upvalue := range fc.upvalues {
+		isLocal := 0
+		if upvalue.isLocal {
+			isLocal = 1
+		}
+		c.function.Instructions = append(c.function.Instructions, byte(isLocal),
+			byte(upvalue.index>>8), byte(upvalue.index))
+	}
+}
+
+// --- Variables
+
+// Defines the value on top of the stack as a variable, declaring a
+// local again in the same scope replaces it.
+func (c *Compiler) defineVariable(name token.Token) {
+	if c.scopeDepth == 0 {
+		c.emit(OpDefineGlobal, c.nameConstant(name.Literal))
+		return
+	}
+
+	if slot, ok := c.localInScope(name.Literal); ok {
+		c.locals[slot].declared = true
+		c.emit(OpSetLocal, slot)
+		c.emit(OpPop)
+		return
+	}
+	c.addLocal(name)
+}
+
+// Gives every name that the statements declare a slot before any of them
+// are compiled, the same as the resolver does, so that functions can refer
+// to locals that are declared after them. The slots hold null until their
+// declaration is reached.
+func (c *Compiler) declareLocals(statements []ast.Statement) {
+	if c.scopeDepth == 0 {
+		return
+	}
+	for _, stmt := range statements {
+		ast.Declarations(stmt, func(name string) {
+			if _, ok := c.localInScope(name); ok {
+				return
+			}
+			c.emit(OpUndefined)
+			slot := c.addLocal(token.Token{Literal: name})
+			c.locals[slot].declared = false
+		})
+	}
+}
+
+// Variables that cannot be resolved to a local or an upvalue are globals
+func (c *Compiler) variable(name token.Token) {
+	if !c.localVariable(name) {
+		c.emitAt(name, OpGetGlobal, c.nameConstant(name.Literal))
+	}
+}
+
+func (c *Compiler) localVariable(name token.Token) bool {
+	if slot, ok := c.resolveLocal(name.Literal, false); ok {
+		c.emit(OpGetLocal, slot)
+		return true
+	}
+	// The local that the upvalue captures might not be defined yet when
+	// the function is called
+	if index, ok := c.resolveUpvalue(name.Literal); ok {
+		c.emitAt(name, OpGetUpvalue, index)
+		return true
+	}
+	return false
+}
+
+func (c *Compiler) setVariable(name token.Token) {
+	if slot, ok := c.resolveLocal(name.Literal, false); ok {
+		c.emit(OpSetLocal, slot)
+		return
+	}
+	if index, ok := c.resolveUpvalue(name.Literal); ok {
+		c.emitAt(name, OpSetUpvalue, index)
+		return
+	}
+	c.emitAt(name, OpSetGlobal, c.nameConstant(name.Literal))
+}
+
+// Gives back the slot of the closest local with the name. Locals whose
+// declaration has not been reached yet can only be used from within
+// functions declared after them, as those are only run later on, later
+// is true when resolving for one of these.
+func (c *Compiler) resolveLocal(name string, later bool) (int, bool) {
+	for i := len(c.locals) - 1; i >= 0; i-- {
+		if c.locals[i].name == name && name != "" && (c.locals[i].declared || later) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// Locals of the enclosing functions are captured as upvalues, through
+// every function in between
+func (c *Compiler) resolveUpvalue(name string) (int, bool) {
+	if c.enclosing == nil {
+		return 0, false
+	}
+
+	if slot, ok := c.enclosing.resolveLocal(name, true); ok {
+		c.enclosing.locals[slot].captured = true
+		return c.addUpvalue(slot, true), true
+	}
+	if index, ok := c.enclosing.resolveUpvalue(name); ok {
+		return c.addUpvalue(index, false), true
+	}
+	return 0, false
+}
+
+func (c *Compiler) addUpvalue(index int, isLocal bool) int {
+	for i, upvalue := range c.upvalues {
+		if upvalue.index == index && upvalue.isLocal == isLocal {
+			return i
+		}
+	}
+
+	if len(c.upvalues) > MAX_OPERAND {
+		c.error(token.Token{}, "Too many variables captured by a closure")
+	}
+	c.upvalues = append(c.upvalues, upvalue{index: index, isLocal: isLocal})
+	return len(c.upvalues) - 1
+}
+
+func (c *Compiler) localInScope(name string) (int, bool) {
+	for i := len(c.locals) - 1; i >= 0 && c.locals[i].depth == c.scopeDepth; i-- {
+		if c.locals[i].name == name {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// Declares a local for the value on top of the stack, giving back its slot
+func (c *Compiler) addLocal(name token.Token) int {
+	if len(c.locals) > MAX_OPERAND {
+		c.error(name, "Too many local variables in function")
+	}
+	c.locals = append(c.locals, local{name: name.Literal, depth: c.scopeDepth, declared: true})
+	return len(c.locals) - 1
+}
+
+func (c *Compiler) beginScope() {
+	c.scopeDepth++
+}
+
+func (c *Compiler) endScope() {
+	c.scopeDepth--
+	c.discardLocals(c.scopeDepth)
+
+	count := len(c.locals)
+	for count > 0 && c.locals[count-1].depth > c.scopeDepth {
+		count--
+	}
+	c.locals = c.locals[:count]
+}
+
+// Takes the locals deeper than the depth off of the stack, without
+// forgetting about them, as break and continue leave the scope early
+func (c *Compiler) discardLocals(depth int) {
+	for i := len(c.locals) - 1; i >= 0 && c.locals[i].depth > depth; i-- {
+		if c.locals[i].captured {
+			c.emit(OpCloseUpvalue)
+		} else {
+			c.emit(OpPop)
+		}
+	}
+}
+
+// --- Emitting bytecode
+
+func (c *Compiler) emit(op Opcode, operands ...int) int {
+	position := len(c.function.Instructions)
+	c.function.Instructions = append(c.function.Instructions, Make(op, operands...)...)
+	return position
+}
+
+// Emits an instruction that can fail, errors point to the token
+func (c *Compiler) emitAt(tok token.Token, op Opcode, operands ...int) int {
+	position := c.emit(op, operands...)
+	c.function.Positions = append(c.function.Positions, Position{Offset: position, Token: tok})
+	return position
+}
+
+func (c *Compiler) emitError(tok token.Token, message string) {
+	c.emitAt(tok, OpError, c.constant(&object.String{Value: message}))
+}
+
+// Jumps are emitted before where they jump to is known, and are patched
+// once it has been compiled
+func (c *Compiler) emitJump(op Opcode) int {
+	return c.emit(op, 0)
+}
+
+func (c *Compiler) patchJump(position int) {
+	target := len(c.function.Instructions)
+	if target > MAX_OPERAND {
+		c.error(token.Token{}, "Too much code to jump over")
+	}
+	c.function.Instructions[position+1] = byte(target >> 8)
+	c.function.Instructions[position+2] = byte(target)
+}
+
+func (c *Compiler) patchJumps(positions []int) {
+	for _, position := range positions {
+		c.patchJump(position)
+	}
+}
+
+func (c *Compiler) constant(obj object.Object) int {
+	if len(c.function.Constants) > MAX_OPERAND {
+		c.error(token.Token{}, "Too many constants in one function")
+	}
+	c.function.Constants = append(c.function.Constants, obj)
+	return len(c.function.Constants) - 1
+}
+
+func (c *Compiler) nameConstant(name string) int {
+	if index, ok := c.names[name]; ok {
+		return index
+	}
+	index := c.constant(&object.String{Value: name})
+	c.names[name] = index
+	return index
+}
+
+func (c *Compiler) error(tok token.Token, message string) {
+	*c.errors = append(*c.errors, errors.NewSyntaxError(tok, message))
+}
@@ -0,0 +1,134 @@
+package compiler
+
+import (
+	"testing"
+
+	"github.com/lczm/as/lexer"
+	"github.com/lczm/as/parser"
+)
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedOutput string
+	}{
+		{
+			"1 + 2;",
+			"0000 OpConstant 0\n" +
+				"0003 OpConstant 1\n" +
+				"0006 OpAdd\n" +
+				"0007 OpPop\n" +
+				"0008 OpNull\n" +
+				"0009 OpReturn\n",
+		},
+		{ // Variables in blocks are locals, they are given their slot at the
+			// start of the block and are popped at the end of it
+			"var a = 1; { var b = a; b = 2; }",
+			"0000 OpConstant 0\n" +
+				"0003 OpDefineGlobal 1\n" +
+				"0006 OpUndefined\n" +
+				"0007 OpGetGlobal 1\n" +
+				"0010 OpSetLocal 1\n" +
+				"0013 OpPop\n" +
+				"0014 OpConstant 2\n" +
+				"0017 OpSetLocal 1\n" +
+				"0020 OpPop\n" +
+				"0021 OpPop\n" +
+				"0022 OpNull\n" +
+				"0023 OpReturn\n",
+		},
+		{ // && gives back a bool, without evaluating the right when the left is false
+			"a && b;",
+			"0000 OpGetGlobal 0\n" +
+				"0003 OpJumpIfFalse 13\n" +
+				"0006 OpGetGlobal 1\n" +
+				"0009 OpTruthy\n" +
+				"0010 OpJump 14\n" +
+				"0013 OpFalse\n" +
+				"0014 OpPop\n" +
+				"0015 OpNull\n" +
+				"0016 OpReturn\n",
+		},
+		{ // Captured locals are closed over instead of being popped
+			"{ var x = 1; function f() { return x; } }",
+			"0000 OpUndefined\n" +
+				"0001 OpUndefined\n" +
+				"0002 OpConstant 0\n" +
+				"0005 OpSetLocal 1\n" +
+				"0008 OpPop\n" +
+				"0009 OpClosure 1 1 [local 1]\n" +
+				"0017 OpSetLocal 2\n" +
+				"0020 OpPop\n" +
+				"0021 OpPop\n" +
+				"0022 OpCloseUpvalue\n" +
+				"0023 OpNull\n" +
+				"0024 OpReturn\n",
+		},
+		{ // The handler of a try puts the error into the next free slot
+			"try { throw 1; } catch (e) { e; }",
+			"0000 OpTry 13 1\n" +
+				"0005 OpConstant 0\n" +
+				"0008 OpThrow\n" +
+				"0009 OpEndTry\n" +
+				"0010 OpJump 21\n" +
+				"0013 OpGetLocal 1\n" +
+				"0016 OpPop\n" +
+				"0017 OpPop\n" +
+				"0018 OpJump 21\n" +
+				"0021 OpNull\n" +
+				"0022 OpReturn\n",
+		},
+	}
+
+	lexer := lexer.New()
+	for i, test := range tests {
+		tokens := lexer.Scan(test.input)
+		parser := parser.New(tokens)
+		statements := parser.Parse()
+
+		function, err := Compile(statements)
+		if err != nil {
+			t.Fatalf("Test: [%d] - Unexpected error : %s", i, err)
+		}
+
+		output := function.Instructions.String()
+		if output != test.expectedOutput {
+			t.Fatalf("Test: [%d] - Incorrect instructions, expected=\n%s\ngot=\n%s",
+				i, test.expectedOutput, output)
+		}
+	}
+}
+
+func TestMake(t *testing.T) {
+	tests := []struct {
+		op             Opcode
+		operands       []int
+		expectedOutput []byte
+	}{
+		{OpConstant, []int{65534}, []byte{byte(OpConstant), 255, 254}},
+		{OpCall, []int{3}, []byte{byte(OpCall), 3}},
+		{OpGetAttribute, []int{1, 1}, []byte{byte(OpGetAttribute), 0, 1, 1}},
+		{OpAdd, []int{}, []byte{byte(OpAdd)}},
+	}
+
+	for i, test := range tests {
+		instruction := Make(test.op, test.operands...)
+		if string(instruction) != string(test.expectedOutput) {
+			t.Fatalf("Test: [%d] - Incorrect instruction, expected=%v, got=%v",
+				i, test.expectedOutput, instruction)
+		}
+
+		definition, _ := Lookup(test.op)
+		operands, read := ReadOperands(definition, instruction[1:])
+		if read != len(instruction)-1 {
+			t.Fatalf("Test: [%d] - Incorrect number of bytes read, expected=%d, got=%d",
+				i, len(instruction)-1, read)
+		}
+		for j, operand := range operands {
+			if operand != test.operands[j] {
+				t.Fatalf("Test: [%d] - Incorrect operand, expected=%d, got=%d",
+					i, test.operands[j], operand)
+			}
+		}
+	}
+}
@@ -0,0 +1,361 @@
+// Package dap is the debug adapter of 'as dap', editors debug programs
+// through it with the Debug Adapter Protocol over stdin and stdout.
+//
+// Programs are run on the tree-walking interpreter, on the same goroutine
+// that reads the requests. Requests are read while the program is paused,
+// and before and after it runs, so a program that is running can only be
+// paused by a breakpoint, not by a 'pause' request.
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/lczm/as/analysis"
+	"github.com/lczm/as/ast"
+	"github.com/lczm/as/debugger"
+	"github.com/lczm/as/errors"
+	"github.com/lczm/as/interpreter"
+	"github.com/lczm/as/lexer"
+	"github.com/lczm/as/object"
+	"github.com/lczm/as/parser"
+	"github.com/lczm/as/token"
+)
+
+// Programs only have the one thread
+const THREAD_ID = 1
+
+type Server struct {
+	reader *bufio.Reader
+	writer io.Writer
+	// The seq of the last message that was sent
+	seq int
+
+	// The absolute path of the program that is launched, and the program
+	program     string
+	statements  []ast.Statement
+	stopOnEntry bool
+	configured  bool
+	// Lines with breakpoints, by the absolute path of their file
+	breakpoints map[string]map[int]bool
+
+	interpreter *interpreter.Interpreter
+	stepper     *debugger.Stepper
+	// Where the program is paused, and whether it is
+	position token.Token
+	paused   bool
+	// What the variable references that were given out since the program
+	// paused refer to, a reference is its index + 1
+	references   []interface{}
+	disconnected bool
+}
+
+func New(in io.Reader, out io.Writer) *Server {
+	return &Server{
+		reader:      bufio.NewReader(in),
+		writer:      out,
+		breakpoints: make(map[string]map[int]bool),
+	}
+}
+
+// Handles requests until the client disconnects or the input ends.
+// The program is run once it has been launched and configured.
+func (s *Server) Run() error {
+	for !s.disconnected {
+		request, err := s.read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		s.handle(request)
+		if s.configured && s.statements != nil && s.interpreter == nil {
+			s.run()
+		}
+	}
+	return nil
+}
+
+func (s *Server) read() (*Request, error) {
+	content, err := readMessage(s.reader)
+	if err != nil {
+		return nil, err
+	}
+	request := &Request{}
+	if err := json.Unmarshal(content, request); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+// Handles a request, gives back true if the program carries on running
+// from where it is paused
+func (s *Server) handle(request *Request) bool {
+	switch request.Command {
+	case "initialize":
+		s.respond(request, Capabilities{
+			SupportsConfigurationDoneRequest: true,
+			SupportsEvaluateForHovers:        true,
+		})
+		s.event("initialized", nil)
+	case "launch":
+		s.launch(request)
+	case "setBreakpoints":
+		s.setBreakpoints(request)
+	case "configurationDone":
+		s.configured = true
+		s.respond(request, nil)
+	case "threads":
+		s.respond(request, map[string]interface{}{
+			"threads": []Thread{{ID: THREAD_ID, Name: "main"}},
+		})
+	case "disconnect":
+		s.disconnected = true
+		s.respond(request, nil)
+		return true
+	case "stackTrace", "scopes", "variables", "evaluate",
+		"continue", "next", "stepIn", "stepOut":
+		if !s.paused {
+			s.fail(request, "The program is not paused")
+			return false
+		}
+		return s.handlePaused(request)
+	default:
+		s.fail(request, fmt.Sprintf("Unknown command '%s'", request.Command))
+	}
+	return false
+}
+
+// Requests that can only be handled while the program is paused
+func (s *Server) handlePaused(request *Request) bool {
+	switch request.Command {
+	case "stackTrace":
+		frames := make([]StackFrame, 0)
+		for _, frame := range s.frames() {
+			frames = append(frames, frame.StackFrame)
+		}
+		s.respond(request, map[string]interface{}{
+			"stackFrames": frames,
+			"totalFrames": len(frames),
+		})
+	case "scopes":
+		s.scopes(request)
+	case "variables":
+		s.variables(request)
+	case "evaluate":
+		s.evaluate(request)
+	case "continue":
+		s.respond(request, map[string]interface{}{"allThreadsContinued": true})
+		s.stepper.Resume(s.interpreter, debugger.RUN)
+		return true
+	case "next":
+		s.respond(request, nil)
+		s.stepper.Resume(s.interpreter, debugger.STEP_OVER)
+		return true
+	case "stepIn":
+		s.respond(request, nil)
+		s.stepper.Resume(s.interpreter, debugger.STEP_INTO)
+		return true
+	case "stepOut":
+		s.respond(request, nil)
+		s.stepper.Resume(s.interpreter, debugger.STEP_OUT)
+		return true
+	}
+	return false
+}
+
+// Compiles the program, it is run once the client is done configuring
+func (s *Server) launch(request *Request) {
+	arguments := LaunchArguments{}
+	if err := json.Unmarshal(request.Arguments, &arguments); err != nil || arguments.Program == "" {
+		s.fail(request, "Expect the path of the program to launch")
+		return
+	}
+
+	program, err := filepath.Abs(arguments.Program)
+	if err != nil {
+		s.fail(request, err.Error())
+		return
+	}
+	data, err := ioutil.ReadFile(program)
+	if err != nil {
+		s.fail(request, err.Error())
+		return
+	}
+
+	lexer := lexer.New()
+	tokens := lexer.Scan(string(data))
+	parser := parser.New(tokens)
+	statements := parser.Parse()
+	semanticAnalyzer := analysis.New(statements)
+	semanticAnalyzer.Analyze()
+
+	errorList := append(lexer.Errors, parser.Errors...)
+	errorList = append(errorList, semanticAnalyzer.Errors...)
+	if len(errorList) > 0 {
+		messages := make([]string, 0, len(errorList))
+		for _, err := range errorList {
+			messages = append(messages, err.Error())
+		}
+		s.fail(request, strings.Join(messages, "\n"))
+		return
+	}
+
+	s.program = program
+	s.statements = statements
+	s.stopOnEntry = arguments.StopOnEntry
+	s.respond(request, nil)
+}
+
+// Replaces the breakpoints of a file
+func (s *Server) setBreakpoints(request *Request) {
+	arguments := SetBreakpointsArguments{}
+	if err := json.Unmarshal(request.Arguments, &arguments); err != nil {
+		s.fail(request, err.Error())
+		return
+	}
+	path, err := filepath.Abs(arguments.Source.Path)
+	if err != nil {
+		s.fail(request, err.Error())
+		return
+	}
+
+	lines := make(map[int]bool)
+	breakpoints := make([]Breakpoint, 0, len(arguments.Breakpoints))
+	for _, breakpoint := range arguments.Breakpoints {
+		lines[breakpoint.Line] = true
+		breakpoints = append(breakpoints, Breakpoint{Verified: true, Line: breakpoint.Line})
+	}
+	s.breakpoints[path] = lines
+	if s.stepper != nil && path == s.program {
+		s.stepper.Breakpoints = lines
+	}
+
+	s.respond(request, map[string]interface{}{"breakpoints": breakpoints})
+}
+
+// Runs the program until it finishes, or until the client disconnects
+func (s *Server) run() {
+	s.interpreter = interpreter.New(s.statements)
+	s.interpreter.File = s.program
+	// What the program prints is sent to the client, as the
+	// messages of the protocol are written to standard output
+	s.interpreter.SetOutput(output{s, "stdout"})
+
+	s.stepper = debugger.NewStepper(s.program)
+	if !s.stopOnEntry {
+		s.stepper.Mode = debugger.RUN
+	}
+	if lines, ok := s.breakpoints[s.program]; ok {
+		s.stepper.Breakpoints = lines
+	}
+	s.interpreter.Debugger = s
+
+	err := s.interpreter.Start()
+	if s.disconnected {
+		return
+	}
+
+	exitCode := 0
+	if _, stopped := err.(errors.Stopped); err != nil && !stopped {
+		s.output("stderr", object.Traceback(s.interpreter.Trace)+err.Error()+"\n")
+		exitCode = 1
+	}
+	s.event("exited", map[string]interface{}{"exitCode": exitCode})
+	s.event("terminated", nil)
+}
+
+// This implements interpreter.Debugger, requests are handled
+// here for as long as the program is paused
+func (s *Server) Statement(i *interpreter.Interpreter, stmt ast.Statement) *object.Error {
+	tok, reason, ok := s.stepper.Pauses(i, stmt)
+	if !ok {
+		return nil
+	}
+
+	s.position = tok
+	s.paused = true
+	defer func() {
+		s.paused = false
+		s.references = nil
+	}()
+
+	s.event("stopped", map[string]interface{}{
+		"reason":            reason,
+		"threadId":          THREAD_ID,
+		"allThreadsStopped": true,
+	})
+	for {
+		request, err := s.read()
+		if err != nil {
+			// The client is gone, so there is no one to carry on for
+			s.disconnected = true
+			return debugger.Stop(tok)
+		}
+		if s.handle(request) {
+			if s.disconnected {
+				return debugger.Stop(tok)
+			}
+			return nil
+		}
+	}
+}
+
+func (s *Server) respond(request *Request, body interface{}) {
+	s.seq++
+	writeMessage(s.writer, Response{
+		Seq:        s.seq,
+		Type:       "response",
+		RequestSeq: request.Seq,
+		Success:    true,
+		Command:    request.Command,
+		Body:       body,
+	})
+}
+
+func (s *Server) fail(request *Request, message string) {
+	s.seq++
+	writeMessage(s.writer, Response{
+		Seq:        s.seq,
+		Type:       "response",
+		RequestSeq: request.Seq,
+		Success:    false,
+		Command:    request.Command,
+		Message:    message,
+	})
+}
+
+func (s *Server) event(name string, body interface{}) {
+	s.seq++
+	writeMessage(s.writer, Event{
+		Seq:   s.seq,
+		Type:  "event",
+		Event: name,
+		Body:  body,
+	})
+}
+
+func (s *Server) output(category string, text string) {
+	s.event("output", map[string]interface{}{
+		"category": category,
+		"output":   text,
+	})
+}
+
+// Sends what is written to it as output events
+type output struct {
+	server   *Server
+	category string
+}
+
+func (o output) Write(p []byte) (int, error) {
+	o.server.output(o.category, string(p))
+	return len(p), nil
+}
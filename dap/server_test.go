@@ -0,0 +1,310 @@
+package dap
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// A request that the client sends, the seq is filled in by session
+type message struct {
+	command   string
+	arguments interface{}
+}
+
+// Runs the server with the requests as its input, and gives back
+// the messages that it sent
+func session(t *testing.T, requests ...message) []map[string]interface{} {
+	var in bytes.Buffer
+	for i, request := range requests {
+		arguments, _ := json.Marshal(request.arguments)
+		writeMessage(&in, Request{
+			Seq:       i + 1,
+			Type:      "request",
+			Command:   request.command,
+			Arguments: arguments,
+		})
+	}
+
+	var out bytes.Buffer
+	if err := New(&in, &out).Run(); err != nil {
+		t.Fatalf("Unexpected error : %s", err)
+	}
+
+	messages := make([]map[string]interface{}, 0)
+	reader := bufio.NewReader(&out)
+	for {
+		content, err := readMessage(reader)
+		if err != nil {
+			break
+		}
+		message := make(map[string]interface{})
+		if err := json.Unmarshal(content, &message); err != nil {
+			t.Fatalf("Unexpected error : %s", err)
+		}
+		messages = append(messages, message)
+	}
+	return messages
+}
+
+// The response to the request with the seq
+func response(t *testing.T, messages []map[string]interface{}, seq int) map[string]interface{} {
+	for _, message := range messages {
+		if message["type"] == "response" && message["request_seq"] == float64(seq) {
+			return message
+		}
+	}
+	t.Fatalf("Expected a response to request %d, got=%v", seq, messages)
+	return nil
+}
+
+// The body of a successful response
+func body(t *testing.T, messages []map[string]interface{}, seq int) interface{} {
+	message := response(t, messages, seq)
+	if message["success"] != true {
+		t.Fatalf("Expected request %d to succeed, got=%v", seq, message)
+	}
+	return message["body"]
+}
+
+// The frames of a stack trace as function:line, innermost first
+func frames(t *testing.T, messages []map[string]interface{}, seq int) string {
+	stackFrames := body(t, messages, seq).(map[string]interface{})["stackFrames"]
+	names := make([]string, 0)
+	for _, stackFrame := range stackFrames.([]interface{}) {
+		stackFrame := stackFrame.(map[string]interface{})
+		names = append(names, fmt.Sprintf("%s:%v", stackFrame["name"], stackFrame["line"]))
+	}
+	return strings.Join(names, " ")
+}
+
+// The events in the order they were sent, with their reason or output
+func events(messages []map[string]interface{}) []string {
+	names := make([]string, 0)
+	for _, message := range messages {
+		if message["type"] != "event" {
+			continue
+		}
+		name := message["event"].(string)
+		if body, ok := message["body"].(map[string]interface{}); ok {
+			for _, key := range []string{"reason", "output", "exitCode"} {
+				if value, ok := body[key]; ok {
+					name += fmt.Sprintf(" %v", value)
+				}
+			}
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+func launch(program string, stopOnEntry bool, lines ...int) []message {
+	breakpoints := make([]map[string]interface{}, 0)
+	for _, line := range lines {
+		breakpoints = append(breakpoints, map[string]interface{}{"line": line})
+	}
+	return []message{
+		{"initialize", map[string]interface{}{"adapterID": "as"}},
+		{"launch", map[string]interface{}{"program": program, "stopOnEntry": stopOnEntry}},
+		{"setBreakpoints", map[string]interface{}{
+			"source":      map[string]interface{}{"path": program},
+			"breakpoints": breakpoints,
+		}},
+		{"configurationDone", nil},
+	}
+}
+
+func TestSession(t *testing.T) {
+	program := filepath.Join("testdata", "main.as")
+	path, _ := filepath.Abs(program)
+	requests := append(launch(program, false, 8),
+		message{"threads", nil},
+		message{"stackTrace", map[string]interface{}{"threadId": 1}},
+		message{"scopes", map[string]interface{}{"frameId": 1}},
+		message{"variables", map[string]interface{}{"variablesReference": 1}},
+		message{"variables", map[string]interface{}{"variablesReference": 3}},
+		message{"variables", map[string]interface{}{"variablesReference": 4}},
+		message{"variables", map[string]interface{}{"variablesReference": 5}},
+		message{"variables", map[string]interface{}{"variablesReference": 6}},
+		message{"evaluate", map[string]interface{}{"expression": `label + "!"`, "frameId": 1}},
+		message{"evaluate", map[string]interface{}{"expression": "p.x * 2", "frameId": 2}},
+		message{"variables", map[string]interface{}{"variablesReference": 2}},
+		message{"continue", nil},
+		message{"disconnect", nil},
+	)
+	messages := session(t, requests...)
+
+	expectedEvents := []string{
+		"initialized", "output start\n", "stopped breakpoint",
+		"output end\n", "exited 0", "terminated",
+	}
+	if !reflect.DeepEqual(events(messages), expectedEvents) {
+		t.Fatalf("Incorrect events, expected=%v, got=%v", expectedEvents, events(messages))
+	}
+
+	expectedBodies := map[int]string{
+		3: `{"breakpoints":[{"verified":true,"line":8}]}`,
+		5: `{"threads":[{"id":1,"name":"main"}]}`,
+		6: `{"stackFrames":[` +
+			`{"id":1,"name":"describe","source":{"name":"main.as","path":"` + path + `"},"line":8,"column":5},` +
+			`{"id":2,"name":"main","source":{"name":"main.as","path":"` + path + `"},"line":15,"column":1}` +
+			`],"totalFrames":2}`,
+		7: `{"scopes":[{"name":"Locals","variablesReference":1,"expensive":false},` +
+			`{"name":"Globals","variablesReference":2,"expensive":false}]}`,
+		// Locals in the order of their slots, lists, hashmaps and instances
+		// are given a reference to their children
+		8: `{"variables":[` +
+			`{"name":"point","value":"Struct: \u003cPoint\u003e","type":"STRUCT","variablesReference":3},` +
+			`{"name":"tags","value":"[a, [\"k\": [1, 2]\n]]","type":"LIST","variablesReference":4},` +
+			`{"name":"label","value":"\"point\"","type":"STRING","variablesReference":0}]}`,
+		9: `{"variables":[` +
+			`{"name":"x","value":"3","type":"INTEGER","variablesReference":0},` +
+			`{"name":"y","value":"0","type":"INTEGER","variablesReference":0}]}`,
+		10: `{"variables":[` +
+			`{"name":"[0]","value":"\"a\"","type":"STRING","variablesReference":0},` +
+			`{"name":"[1]","value":"[\"k\": [1, 2]\n]","type":"HASHMAP","variablesReference":5}]}`,
+		11: `{"variables":[{"name":"\"k\"","value":"[1, 2]","type":"LIST","variablesReference":6}]}`,
+		12: `{"variables":[` +
+			`{"name":"[0]","value":"1","type":"INTEGER","variablesReference":0},` +
+			`{"name":"[1]","value":"2","type":"INTEGER","variablesReference":0}]}`,
+		// Expressions are evaluated in the frame that is asked for
+		13: `{"result":"\"point!\"","variablesReference":0}`,
+		14: `{"result":"6","variablesReference":0}`,
+		// Builtin functions are left out of the globals
+		15: `{"variables":[
+			{"name":"E","value":"2.718281828459045","type":"FLOAT","variablesReference":0},
+			{"name":"PI","value":"3.141592653589793","type":"FLOAT","variablesReference":0},
+			{"name":"Point","value":"Struct: <Point>","type":"STRUCT","variablesReference":0},
+			{"name":"describe","value":"Function : <describe>","type":"FUNCTION","variablesReference":0},
+			{"name":"p","value":"Struct: <Point>","type":"STRUCT","variablesReference":7},
+			{"name":"tags","value":"[a, [\"k\": [1, 2]\n]]","type":"LIST","variablesReference":8}]}`,
+	}
+	for seq, expectedBody := range expectedBodies {
+		var expected interface{}
+		if err := json.Unmarshal([]byte(expectedBody), &expected); err != nil {
+			t.Fatalf("Request %d - Unexpected error : %s", seq, err)
+		}
+		if got := body(t, messages, seq); !reflect.DeepEqual(got, expected) {
+			t.Fatalf("Request %d - Incorrect body, expected=%v, got=%v", seq, expected, got)
+		}
+	}
+}
+
+func TestStepping(t *testing.T) {
+	program := filepath.Join("testdata", "main.as")
+	stackTrace := message{"stackTrace", map[string]interface{}{"threadId": 1}}
+	requests := append(launch(program, true), stackTrace)
+	// Over the declarations and the top level statements up to the call
+	for i := 0; i < 6; i++ {
+		requests = append(requests, message{"next", nil})
+	}
+	requests = append(requests,
+		stackTrace,
+		message{"stepIn", nil},
+		stackTrace,
+		message{"stepOut", nil},
+		stackTrace,
+		message{"continue", nil},
+		stackTrace,
+		message{"disconnect", nil},
+	)
+	messages := session(t, requests...)
+
+	expectedEvents := []string{"initialized", "stopped entry"}
+	for i := 0; i < 5; i++ {
+		expectedEvents = append(expectedEvents, "stopped step")
+	}
+	expectedEvents = append(expectedEvents, "output start\n", "stopped step", "stopped step",
+		"stopped step", "output end\n", "exited 0", "terminated")
+	if !reflect.DeepEqual(events(messages), expectedEvents) {
+		t.Fatalf("Incorrect events, expected=%v, got=%v", expectedEvents, events(messages))
+	}
+
+	expectedFrames := map[int]string{
+		5:  "main:1",
+		12: "main:15",
+		14: "describe:7 main:15",
+		16: "main:16",
+	}
+	for seq, expectedFrame := range expectedFrames {
+		if got := frames(t, messages, seq); got != expectedFrame {
+			t.Fatalf("Request %d - Incorrect frames, expected=%s, got=%s", seq, expectedFrame, got)
+		}
+	}
+	if response(t, messages, 18)["message"] != "The program is not paused" {
+		t.Fatalf("Expected the stack to be asked for once the program finished, got=%v",
+			response(t, messages, 18))
+	}
+}
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		requests       []message
+		seq            int
+		expectedError  string
+		expectedEvents []string
+	}{
+		{
+			[]message{{"launch", map[string]interface{}{"program": "testdata/missing.as"}}},
+			1, "no such file or directory", []string{},
+		},
+		{
+			[]message{{"launch", map[string]interface{}{"program": "testdata/syntax.as"}}},
+			1, "Syntax Error at line '1', column '9' : Expect expression", []string{},
+		},
+		{[]message{{"next", nil}}, 1, "The program is not paused", []string{}},
+		{[]message{{"pause", nil}}, 1, "Unknown command 'pause'", []string{}},
+		{
+			append(launch("testdata/runtime.as", false),
+				message{"disconnect", nil}),
+			0, "",
+			[]string{
+				"initialized",
+				"output Traceback (most recent call last):\n  f() called from " +
+					absolute("testdata/runtime.as") + ", line 4\n" +
+					"Runtime Error at line '2', column '14' : Division by zero\n",
+				"exited 1",
+				"terminated",
+			},
+		},
+		// What imported modules print is sent as output as well
+		{
+			append(launch("testdata/imports.as", false),
+				message{"disconnect", nil}),
+			0, "",
+			[]string{"initialized", "output imported\n", "output main\n", "exited 0", "terminated"},
+		},
+		// Disconnecting stops the program where it is paused
+		{
+			append(launch("testdata/runtime.as", true),
+				message{"disconnect", nil}),
+			0, "",
+			[]string{"initialized", "stopped entry"},
+		},
+	}
+
+	for i, test := range tests {
+		messages := session(t, test.requests...)
+		if test.expectedError != "" {
+			message := response(t, messages, test.seq)
+			if message["success"] != false ||
+				!strings.Contains(fmt.Sprint(message["message"]), test.expectedError) {
+				t.Fatalf("Test: [%d] - Expected the request to fail with %q, got=%v",
+					i, test.expectedError, message)
+			}
+		}
+		if got := events(messages); !reflect.DeepEqual(got, test.expectedEvents) {
+			t.Fatalf("Test: [%d] - Incorrect events, expected=%q, got=%q", i, test.expectedEvents, got)
+		}
+	}
+}
+
+func absolute(path string) string {
+	absolute, _ := filepath.Abs(path)
+	return absolute
+}
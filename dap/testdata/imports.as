@@ -0,0 +1,2 @@
+import "printer.as";
+print(printer.name);
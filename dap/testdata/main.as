@@ -0,0 +1,16 @@
+struct Point {
+    var x = 0;
+    var y = 0;
+}
+
+function describe(point, tags) {
+    var label = "point";
+    return label;
+}
+
+var p = Point();
+p.x = 3;
+var tags = ["a", {"k": [1, 2]}];
+print("start");
+describe(p, tags);
+print("end");
@@ -0,0 +1,2 @@
+var name = "main";
+print("imported");
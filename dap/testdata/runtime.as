@@ -0,0 +1,4 @@
+function f(n) {
+    return n / 0;
+}
+f(1);
@@ -0,0 +1,201 @@
+package dap
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/lczm/as/debugger"
+	"github.com/lczm/as/environment"
+	"github.com/lczm/as/object"
+)
+
+// A call that is running and the environment that it is running in
+type frame struct {
+	StackFrame
+	environment *environment.Environment
+}
+
+// The variables of the environments of a frame, up to the globals
+type locals struct {
+	environment *environment.Environment
+}
+
+// The variables of the environment at the top level
+type globals struct {
+	environment *environment.Environment
+}
+
+// The calls that are running, innermost first. Frames are numbered from 1,
+// the outermost is the top level of the program.
+func (s *Server) frames() []frame {
+	calls := s.interpreter.CallStack()
+	environments := s.interpreter.Environments()
+
+	frames := make([]frame, 0, len(environments))
+	for index := len(environments) - 1; index >= 0; index-- {
+		name := "main"
+		if index > 0 {
+			name = calls[index-1].Function
+		}
+
+		// Every frame other than the innermost is paused at the call
+		// to the frame within it
+		file, line, column := s.interpreter.CurrentFile(), s.position.Line, s.position.Column
+		if index < len(calls) {
+			file, line, column = calls[index].File, calls[index].Line, 1
+		}
+
+		frames = append(frames, frame{
+			StackFrame: StackFrame{
+				ID:     len(frames) + 1,
+				Name:   name,
+				Source: Source{Name: filepath.Base(file), Path: file},
+				Line:   line,
+				Column: column,
+			},
+			environment: environments[index],
+		})
+	}
+	return frames
+}
+
+// The frame with the id, or the innermost frame if the id is 0
+func (s *Server) frame(id int) (frame, bool) {
+	frames := s.frames()
+	if id == 0 {
+		return frames[0], true
+	}
+	if id < 1 || id > len(frames) {
+		return frame{}, false
+	}
+	return frames[id-1], true
+}
+
+func (s *Server) scopes(request *Request) {
+	arguments := ScopesArguments{}
+	json.Unmarshal(request.Arguments, &arguments)
+	frame, ok := s.frame(arguments.FrameID)
+	if !ok {
+		s.fail(request, fmt.Sprintf("Unknown frame %d", arguments.FrameID))
+		return
+	}
+
+	global := frame.environment
+	for global.Parent != nil {
+		global = global.Parent
+	}
+	s.respond(request, map[string]interface{}{
+		"scopes": []Scope{
+			{Name: "Locals", VariablesReference: s.reference(locals{frame.environment})},
+			{Name: "Globals", VariablesReference: s.reference(globals{global})},
+		},
+	})
+}
+
+func (s *Server) variables(request *Request) {
+	arguments := VariablesArguments{}
+	json.Unmarshal(request.Arguments, &arguments)
+	reference := arguments.VariablesReference
+	if reference < 1 || reference > len(s.references) {
+		s.fail(request, fmt.Sprintf("Unknown variables reference %d", reference))
+		return
+	}
+
+	variables := make([]Variable, 0)
+	switch value := s.references[reference-1].(type) {
+	case locals:
+		// Locals of inner environments hide those of outer ones
+		seen := make(map[string]bool)
+		for env := value.environment; env.Parent != nil; env = env.Parent {
+			for _, binding := range debugger.Bindings(env) {
+				if !seen[binding.Name] {
+					seen[binding.Name] = true
+					variables = append(variables, s.variable(binding.Name, binding.Value))
+				}
+			}
+		}
+	case globals:
+		for _, binding := range debugger.Bindings(value.environment) {
+			variables = append(variables, s.variable(binding.Name, binding.Value))
+		}
+	case *object.List:
+		for index, element := range value.Value {
+			variables = append(variables, s.variable(fmt.Sprintf("[%d]", index), element))
+		}
+	case *object.HashMap:
+		for _, pair := range value.Pairs() {
+			variables = append(variables, s.variable(pair.Key.FormattedString(), pair.Value))
+		}
+	case *object.Struct:
+		names := make([]string, 0, len(value.Attributes))
+		for name := range value.Attributes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			variables = append(variables, s.variable(name, value.Attributes[name]))
+		}
+	}
+	s.respond(request, map[string]interface{}{"variables": variables})
+}
+
+// Lists, hashmaps and instances of structs can be expanded into
+// what they hold, they are given a reference for their children
+func (s *Server) variable(name string, value object.Object) Variable {
+	return Variable{
+		Name:               name,
+		Value:              value.FormattedString(),
+		Type:               value.RawType(),
+		VariablesReference: s.children(value),
+	}
+}
+
+func (s *Server) children(value object.Object) int {
+	switch value := value.(type) {
+	case *object.List:
+		if len(value.Value) > 0 {
+			return s.reference(value)
+		}
+	case *object.HashMap:
+		if len(value.Value) > 0 {
+			return s.reference(value)
+		}
+	case *object.Struct:
+		if len(value.Attributes) > 0 {
+			return s.reference(value)
+		}
+	}
+	return 0
+}
+
+func (s *Server) reference(value interface{}) int {
+	s.references = append(s.references, value)
+	return len(s.references)
+}
+
+// Evaluates the expression in the environment of the frame
+func (s *Server) evaluate(request *Request) {
+	arguments := EvaluateArguments{}
+	json.Unmarshal(request.Arguments, &arguments)
+	frame, ok := s.frame(arguments.FrameID)
+	if !ok {
+		s.fail(request, fmt.Sprintf("Unknown frame %d", arguments.FrameID))
+		return
+	}
+
+	paused := s.interpreter.Environment
+	s.interpreter.Environment = frame.environment
+	value, err := debugger.Evaluate(s.interpreter, arguments.Expression)
+	s.interpreter.Environment = paused
+	if err != nil {
+		s.fail(request, err.Message())
+		return
+	}
+
+	s.respond(request, map[string]interface{}{
+		"result":             value.FormattedString(),
+		"variablesReference": s.children(value),
+	})
+}
@@ -0,0 +1,38 @@
+package debugger
+
+import (
+	"sort"
+
+	"github.com/lczm/as/environment"
+	"github.com/lczm/as/object"
+)
+
+// A variable of an environment and its value
+type Binding struct {
+	Name  string
+	Value object.Object
+}
+
+// The variables of a single environment, without its parents. Locals
+// come first in the order of their slots, then the rest by name.
+// Builtins are left out.
+func Bindings(env *environment.Environment) []Binding {
+	bindings := make([]Binding, 0)
+	for slot, name := range env.Names {
+		if env.Slots[slot] != nil {
+			bindings = append(bindings, Binding{Name: name, Value: env.Slots[slot]})
+		}
+	}
+
+	names := make([]string, 0, len(env.Values))
+	for name, value := range env.Values {
+		if _, ok := value.(*object.BuiltinFunction); !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		bindings = append(bindings, Binding{Name: name, Value: env.Values[name]})
+	}
+	return bindings
+}
@@ -0,0 +1,165 @@
+// Package debugger is the terminal debugger of 'as debug file'.
+//
+// It pauses before the first statement of the file, then at breakpoints
+// or after every step, and reads commands from its input while paused :
+//
+//	break 12     pause at line 12, 'delete 12' removes it again
+//	step         run until the next statement, going into calls
+//	next         run until the next statement, stepping over calls
+//	out          run until the function that is running returns
+//	continue     run until the next breakpoint
+//	env          print the variables of every environment in the chain
+//	print a + 1  evaluate an expression in the paused frame
+//	backtrace    print the calls that are running
+//	quit         stop the program
+package debugger
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/lczm/as/ast"
+	"github.com/lczm/as/environment"
+	"github.com/lczm/as/interpreter"
+	"github.com/lczm/as/object"
+	"github.com/lczm/as/token"
+)
+
+const PROMPT = "(debug) "
+
+type Debugger struct {
+	in      *bufio.Scanner
+	out     io.Writer
+	lines   []string
+	stepper *Stepper
+}
+
+// Pauses at the first statement that is run
+func New(in io.Reader, out io.Writer, file string, source string) *Debugger {
+	return &Debugger{
+		in:      bufio.NewScanner(in),
+		out:     out,
+		lines:   strings.Split(source, "\n"),
+		stepper: NewStepper(file),
+	}
+}
+
+// This implements interpreter.Debugger
+func (d *Debugger) Statement(i *interpreter.Interpreter, stmt ast.Statement) *object.Error {
+	tok, _, ok := d.stepper.Pauses(i, stmt)
+	if !ok {
+		return nil
+	}
+	return d.pause(i, tok)
+}
+
+// Reads commands until one of them carries on running the program
+func (d *Debugger) pause(i *interpreter.Interpreter, tok token.Token) *object.Error {
+	fmt.Fprintf(d.out, "Paused at %s, line %d\n", d.stepper.File, tok.Line)
+	d.printLine(tok.Line)
+
+	for {
+		fmt.Fprint(d.out, PROMPT)
+		if !d.in.Scan() {
+			// Nothing more can be read, so the program is left to finish
+			fmt.Fprintln(d.out)
+			d.stepper.Breakpoints = make(map[int]bool)
+			d.stepper.Mode = RUN
+			return nil
+		}
+
+		input := strings.TrimSpace(d.in.Text())
+		command, argument := input, ""
+		if index := strings.IndexAny(input, " \t"); index >= 0 {
+			command, argument = input[:index], strings.TrimSpace(input[index:])
+		}
+
+		switch command {
+		case "":
+		case "step", "s":
+			d.stepper.Resume(i, STEP_INTO)
+			return nil
+		case "next", "n":
+			d.stepper.Resume(i, STEP_OVER)
+			return nil
+		case "out", "o":
+			d.stepper.Resume(i, STEP_OUT)
+			return nil
+		case "continue", "c":
+			d.stepper.Resume(i, RUN)
+			return nil
+		case "break", "b":
+			if line, ok := d.line(argument); ok {
+				d.stepper.Breakpoints[line] = true
+				fmt.Fprintf(d.out, "Breakpoint at line %d\n", line)
+			}
+		case "delete", "d":
+			if line, ok := d.line(argument); ok {
+				delete(d.stepper.Breakpoints, line)
+			}
+		case "env", "e":
+			d.printEnvironment(i.Environment)
+		case "print", "p":
+			d.evaluate(i, argument)
+		case "backtrace", "bt":
+			d.printCallStack(i.CallStack())
+		case "quit", "q":
+			return Stop(tok)
+		case "help", "h":
+			fmt.Fprintln(d.out, "break LINE, delete LINE, step, next, out, continue, "+
+				"env, print EXPRESSION, backtrace, quit")
+		default:
+			fmt.Fprintf(d.out, "Unknown command '%s', 'help' lists the commands\n", command)
+		}
+	}
+}
+
+func (d *Debugger) line(argument string) (int, bool) {
+	line, err := strconv.Atoi(argument)
+	if err != nil || line < 1 || line > len(d.lines) {
+		fmt.Fprintf(d.out, "'%s' is not a line of %s\n", argument, d.stepper.File)
+		return 0, false
+	}
+	return line, true
+}
+
+func (d *Debugger) printLine(line int) {
+	if line < 1 || line > len(d.lines) {
+		return
+	}
+	fmt.Fprintf(d.out, "%d | %s\n", line, strings.TrimRight(d.lines[line-1], "\r"))
+}
+
+// Prints the innermost environment first, builtins are left out
+func (d *Debugger) printEnvironment(env *environment.Environment) {
+	for depth := 0; env != nil; depth++ {
+		variables := make([]string, 0)
+		for _, binding := range Bindings(env) {
+			variables = append(variables, binding.Name+" = "+binding.Value.FormattedString())
+		}
+		fmt.Fprintf(d.out, "[%d] %s\n", depth, strings.Join(variables, ", "))
+		env = env.Parent
+	}
+}
+
+func (d *Debugger) printCallStack(calls []object.Frame) {
+	if len(calls) == 0 {
+		fmt.Fprintln(d.out, "At the top level")
+		return
+	}
+	for index := len(calls) - 1; index >= 0; index-- {
+		fmt.Fprintf(d.out, "  %s\n", calls[index].String())
+	}
+}
+
+func (d *Debugger) evaluate(i *interpreter.Interpreter, source string) {
+	value, err := Evaluate(i, source)
+	if err != nil {
+		fmt.Fprintln(d.out, err.Message())
+		return
+	}
+	fmt.Fprintln(d.out, value.FormattedString())
+}
@@ -0,0 +1,136 @@
+package debugger
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/lczm/as/errors"
+	"github.com/lczm/as/interpreter"
+	"github.com/lczm/as/lexer"
+	"github.com/lczm/as/parser"
+)
+
+const source = `var total = 0;
+function add(a, b) {
+    var sum = a + b;
+    return sum;
+}
+for (var i = 0; i < 3; i = i + 1) {
+    total = add(total, i);
+}
+if (total > 0) { total = total * 2; }`
+
+// Runs the source under the debugger with the commands as its input,
+// and gives back what the debugger wrote out
+func debug(t *testing.T, commands ...string) (*interpreter.Interpreter, string, error) {
+	lexer := lexer.New()
+	tokens := lexer.Scan(source)
+	parser := parser.New(tokens)
+	statements := parser.Parse()
+	if len(lexer.Errors) > 0 || len(parser.Errors) > 0 {
+		t.Fatalf("Unexpected errors parsing the source")
+	}
+
+	var out bytes.Buffer
+	input := strings.NewReader(strings.Join(commands, "\n") + "\n")
+	interpreter := interpreter.New(statements)
+	interpreter.Resolve(statements)
+	interpreter.File = "main.as"
+	interpreter.Debugger = New(input, &out, "main.as", source)
+	err := interpreter.Start()
+	return interpreter, out.String(), err
+}
+
+// The lines that the debugger paused at, in order
+func pausedAt(output string) []string {
+	lines := make([]string, 0)
+	for _, line := range strings.Split(output, "\n") {
+		if index := strings.Index(line, "Paused at main.as, line "); index >= 0 {
+			lines = append(lines, strings.TrimPrefix(line[index:], "Paused at main.as, line "))
+		}
+	}
+	return lines
+}
+
+func TestStepping(t *testing.T) {
+	tests := []struct {
+		commands       []string
+		expectedPauses string
+	}{
+		// The first statement is always paused at
+		{[]string{"continue"}, "1"},
+		{[]string{"step", "step", "step", "step", "step", "step", "continue"}, "1 2 6 6 7 3 4"},
+		// Calls are stepped over
+		{[]string{"next", "next", "next", "next", "next", "continue"}, "1 2 6 6 7 7"},
+		{[]string{"break 3", "continue", "out", "continue"}, "1 3 7 3"},
+		// Breakpoints pause once for every time the line is run
+		{[]string{"break 4", "c", "c", "c", "c"}, "1 4 4 4"},
+		// and once for a line with more than one statement on it
+		{[]string{"break 9", "c", "step", "c"}, "1 9 9"},
+		{[]string{"break 3", "c", "delete 3", "c"}, "1 3"},
+	}
+
+	for i, test := range tests {
+		_, output, err := debug(t, test.commands...)
+		if err != nil {
+			t.Fatalf("Test: [%d] - Unexpected error : %s", i, err)
+		}
+		pauses := strings.Join(pausedAt(output), " ")
+		if pauses != test.expectedPauses {
+			t.Fatalf("Test: [%d] - Paused at the wrong lines, expected=%s, got=%s\n%s",
+				i, test.expectedPauses, pauses, output)
+		}
+	}
+}
+
+func TestInspecting(t *testing.T) {
+	interpreter, output, err := debug(t,
+		"break 4",
+		"continue",
+		"env",
+		"print sum * 10",
+		"print sum = 100",
+		"print undefined",
+		"backtrace",
+		"delete 4",
+		"continue",
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error : %s", err)
+	}
+
+	expectedLines := []string{
+		"4 |     return sum;",
+		// The innermost environment first, without the builtins
+		"[0] a = 0, b = 0, sum = 0",
+		"[1] E = 2.718281828459045, PI = 3.141592653589793, add = Function : <add>, i = 0, total = 0",
+		"(debug) 0",
+		"(debug) 100",
+		"Undefined variable 'undefined'",
+		"add() called from main.as, line 7",
+	}
+	for _, line := range expectedLines {
+		if !strings.Contains(output, line) {
+			t.Fatalf("Expected the output to contain %q, got=\n%s", line, output)
+		}
+	}
+
+	// What is evaluated changes the paused frame
+	if total := interpreter.Environment.Get("total"); total.String() != "206" {
+		t.Fatalf("Expected the assignment to be kept, got=%s", total.String())
+	}
+}
+
+func TestQuit(t *testing.T) {
+	_, _, err := debug(t, "step", "quit")
+	if stopped, ok := err.(errors.Stopped); !ok || stopped.Span().Line != 2 {
+		t.Fatalf("Expected the program to be stopped at line 2, got=%v", err)
+	}
+
+	// The program finishes once there is nothing left to read
+	interpreter, _, err := debug(t)
+	if err != nil || interpreter.Environment.Get("total").String() != "6" {
+		t.Fatalf("Expected the program to finish, got=%v", err)
+	}
+}
@@ -0,0 +1,48 @@
+package debugger
+
+import (
+	"github.com/lczm/as/analysis"
+	"github.com/lczm/as/errors"
+	"github.com/lczm/as/interpreter"
+	"github.com/lczm/as/lexer"
+	"github.com/lczm/as/object"
+	"github.com/lczm/as/parser"
+)
+
+// Evaluates source code in the environment that the program is paused in.
+// The code is resolved against that environment, so it sees the locals
+// that are there. Variables that it declares are kept there.
+// The value of the last statement is given back if it is an expression,
+// or null if it is not.
+func Evaluate(i *interpreter.Interpreter, source string) (object.Object, errors.Error) {
+	lexer := lexer.New()
+	tokens := lexer.Scan(source)
+	// The same as the repl, the last expression does not need a
+	// trailing ';' i.e. 'print a + 1'
+	tokens = parser.TerminateExpression(tokens)
+
+	parser := parser.New(tokens)
+	statements := parser.Parse()
+	errorList := append(lexer.Errors, parser.Errors...)
+	if len(errorList) == 0 {
+		resolver := analysis.NewResolver(i.Environment)
+		resolver.Resolve(statements)
+		errorList = resolver.Errors
+	}
+	if len(errorList) > 0 {
+		return nil, errorList[0]
+	}
+
+	// Functions that are called while evaluating do not pause
+	debugger := i.Debugger
+	i.Debugger = nil
+	defer func() {
+		i.Debugger = debugger
+	}()
+
+	value, errorObj := i.Run(statements)
+	if errorObj != nil {
+		return nil, errorObj.Err
+	}
+	return value, nil
+}
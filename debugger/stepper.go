@@ -0,0 +1,94 @@
+package debugger
+
+import (
+	"github.com/lczm/as/ast"
+	"github.com/lczm/as/errors"
+	"github.com/lczm/as/interpreter"
+	"github.com/lczm/as/object"
+	"github.com/lczm/as/token"
+)
+
+// How the program is run until the debugger pauses again
+const (
+	// Until a breakpoint
+	RUN = iota
+	// Until the next statement
+	STEP_INTO
+	// Until the next statement that is not within a call
+	STEP_OVER
+	// Until the next statement after the function returns
+	STEP_OUT
+)
+
+// Why the program paused
+const (
+	ENTRY      = "entry"
+	STEP       = "step"
+	BREAKPOINT = "breakpoint"
+)
+
+// Works out which statements the program pauses at, for both the
+// terminal debugger and the debug adapter
+type Stepper struct {
+	// The file that is debugged, breakpoints are lines of it
+	File        string
+	Breakpoints map[int]bool
+	Mode        int
+	// How many calls were running when the step was started
+	depth int
+	// The first statement that was run on every line. A line can have more
+	// than one statement on it, i.e. 'if (a) { b(); }', breakpoints only
+	// pause at the first of them so that they pause once for the line.
+	first   map[int]ast.Statement
+	started bool
+}
+
+// Pauses at the first statement that is run
+func NewStepper(file string) *Stepper {
+	return &Stepper{
+		File:        file,
+		Breakpoints: make(map[int]bool),
+		Mode:        STEP_INTO,
+		first:       make(map[int]ast.Statement),
+	}
+}
+
+// Whether the program pauses before stmt, and why. tok is where stmt is.
+func (s *Stepper) Pauses(i *interpreter.Interpreter, stmt ast.Statement) (tok token.Token, reason string, ok bool) {
+	tok, ok = ast.Position(stmt)
+	if !ok || i.CurrentFile() != s.File {
+		return tok, "", false
+	}
+	if _, ok := s.first[tok.Line]; !ok {
+		s.first[tok.Line] = stmt
+	}
+
+	depth := i.Depth()
+	stepped := s.Mode == STEP_INTO ||
+		(s.Mode == STEP_OVER && depth <= s.depth) ||
+		(s.Mode == STEP_OUT && depth < s.depth)
+	switch {
+	case stepped && !s.started:
+		reason = ENTRY
+	case stepped:
+		reason = STEP
+	case s.Breakpoints[tok.Line] && s.first[tok.Line] == stmt:
+		reason = BREAKPOINT
+	default:
+		return tok, "", false
+	}
+	s.started = true
+	return tok, reason, true
+}
+
+// Stops the program from where it is paused, this is not reported
+// as an error of the program
+func Stop(tok token.Token) *object.Error {
+	return &object.Error{Err: errors.NewStopped(tok, "Stopped by the debugger")}
+}
+
+// Carries on running the program in the mode from where it is paused
+func (s *Stepper) Resume(i *interpreter.Interpreter, mode int) {
+	s.Mode = mode
+	s.depth = i.Depth()
+}
@@ -4,8 +4,13 @@ import (
 	"github.com/lczm/as/object"
 )
 
+// Globals are kept in Values by name. Locals are kept in Slots, at the
+// slot that the resolver gave them, Names holds the name of every slot
+// so that locals can still be looked up by name.
 type Environment struct {
 	Values map[string]object.Object
+	Slots  []object.Object
+	Names  []string
 	Parent *Environment
 }
 
@@ -13,89 +18,83 @@ func (e *Environment) Define(name string, value object.Object) {
 	e.Values[name] = value
 }
 
-// This method can potentially take in other context parameters
-// So that there can be a check for something like -Wshadow
-func (e *Environment) Set(name string, value object.Object) {
-	_, ok := e.Values[name]
-	if ok {
-		e.Values[name] = value
-		return
+// Defines a local in a slot, slots that are not defined yet are nil
+func (e *Environment) DefineAt(slot int, name string, value object.Object) {
+	for len(e.Slots) <= slot {
+		e.Slots = append(e.Slots, nil)
+		e.Names = append(e.Names, "")
 	}
+	e.Slots[slot] = value
+	e.Names[slot] = name
+}
 
-	// If it does not exist in the current environment, go up
-	// the parents
-	if e.Parent != nil {
-		e.Parent.Set(name, value)
-		return
+// Returns nil if the local has not been defined yet.
+func (e *Environment) GetAt(depth int, slot int) object.Object {
+	ancestor := e.Ancestor(depth)
+	if slot >= len(ancestor.Slots) {
+		return nil
 	}
-
-	panic("Name not found in environment : Set")
+	return ancestor.Slots[slot]
 }
 
-func (e *Environment) SetIndex(name string, index object.Object, value object.Object) {
-	_, ok := e.Values[name]
-	if ok {
-		list, listOk := e.Values[name].(*object.List)
-		listIndex, listIndexOk := index.(*object.Integer)
-		if listOk && listIndexOk {
-			list.Value[listIndex.Value] = value
-		}
-
-		hash, hashOk := e.Values[name].(*object.HashMap)
-		_, hashable := index.(object.Hashable)
-		if hashOk && hashable {
-			// Construct the hashkey here.
-			hashKey := object.HashKey{
-				Type:  index.RawType(),
-				Value: index.(object.Hashable).Hash().Value,
-			}
-			hashValue := object.HashValue{
-				Key:   index,
-				Value: value,
-			}
-			hash.Value[hashKey] = hashValue
-		}
-		return
+// Returns false if the local has not been defined yet.
+func (e *Environment) SetAt(depth int, slot int, value object.Object) bool {
+	ancestor := e.Ancestor(depth)
+	if slot >= len(ancestor.Slots) || ancestor.Slots[slot] == nil {
+		return false
 	}
+	ancestor.Slots[slot] = value
+	return true
+}
 
-	// If it does not exist in the current environment, go up
-	// the parents
-	if e.Parent != nil {
-		e.Parent.SetIndex(name, index, value)
-		return
+// Gives back the environment depth parents up
+func (e *Environment) Ancestor(depth int) *Environment {
+	environment := e
+	for i := 0; i < depth; i++ {
+		environment = environment.Parent
 	}
-
-	panic("Name not found in environment : SetIndex")
+	return environment
 }
 
-func (e *Environment) SetStruct(name string, attribute string, value object.Object) {
+// This method can potentially take in other context parameters
+// So that there can be a check for something like -Wshadow
+// Returns false if the name has not been declared in any environment.
+func (e *Environment) Set(name string, value object.Object) bool {
 	_, ok := e.Values[name]
 	if ok {
-		structObject := e.Values[name].(*object.Struct)
-		structObject.Attributes[attribute] = value
-		return
+		e.Values[name] = value
+		return true
+	}
+	if slot := e.slotOf(name); slot >= 0 {
+		e.Slots[slot] = value
+		return true
 	}
 
+	// If it does not exist in the current environment, go up
+	// the parents
 	if e.Parent != nil {
-		e.Parent.SetStruct(name, attribute, value)
-		return
+		return e.Parent.Set(name, value)
 	}
+
+	return false
 }
 
+// Returns nil if the name has not been declared in any environment.
 func (e *Environment) Get(name string) object.Object {
 	object, ok := e.Values[name]
 	if ok {
 		return object
 	}
+	if slot := e.slotOf(name); slot >= 0 {
+		return e.Slots[slot]
+	}
 
 	// Go up the parent environments to get the string.
-	// The parent environment will deal with the error handling
-	// through the panic call.
 	if e.Parent != nil {
 		return e.Parent.Get(name)
 	}
 
-	panic("Name not found in environment : Get")
+	return nil
 }
 
 // Check if a name exists within the environment.
@@ -104,7 +103,17 @@ func (e *Environment) Exists(name string) bool {
 	if ok {
 		return true
 	}
-	return false
+	return e.slotOf(name) >= 0
+}
+
+// Gives back the slot of a local that has been defined, or -1
+func (e *Environment) slotOf(name string) int {
+	for slot := len(e.Names) - 1; slot >= 0; slot-- {
+		if e.Names[slot] == name && e.Slots[slot] != nil {
+			return slot
+		}
+	}
+	return -1
 }
 
 func New() *Environment {
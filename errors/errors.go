@@ -2,73 +2,279 @@ package errors
 
 import (
 	"fmt"
+	"strings"
 
-	"github.com/lczm/as/object"
 	"github.com/lczm/as/token"
 )
 
-// TODO : The lexer has to keep track of the tokens
-// line numbers and column numbers, they can then be passed into
-// the specific errors to give more detialed messages
-
 // TODO : Proper exit codes? as these are errors they should not
 // be 0 escapes
 
+// Kinds of errors
+const (
+	SYNTAX_ERROR    = "SyntaxError"
+	RUNTIME_ERROR   = "RuntimeError"
+	SEMANTIC_ERROR  = "SemanticError"
+	LIMIT_EXCEEDED  = "LimitExceeded"
+	THROWN_ERROR    = "ThrownError"
+	ASSERTION_ERROR = "AssertionError"
+	STOPPED         = "Stopped"
+	DEFAULT_ERROR   = "Error"
+	SHADOW_WARNING  = "ShadowWarning"
+)
+
+// Where in the source an error took place.
+// Line and Column are 1-based, a zero Line means that the
+// position is not known, i.e. errors raised by builtin functions.
+type Span struct {
+	Line   int
+	Column int
+	Offset int
+	Length int
+}
+
+func NewSpan(tok token.Token) Span {
+	return Span{
+		Line:   tok.Line,
+		Column: tok.Column,
+		Offset: tok.Offset,
+		Length: len(tok.Literal),
+	}
+}
+
+// All errors and warnings carry their kind, message and position as data,
+// Describe() is only a convenience to print them out.
+// They also implement the standard library error interface.
 type Error interface {
+	Kind() string
+	Message() string
+	Span() Span
+	Error() string
 	Describe()
 }
 
-// Initially this was implemented as with interfaces and structs
-// but there is really no need for that as it would complicate
-// things, so just simple functions to deal with errors would be enough.
-
 // Syntax errors would take in tokens as arguments as
-// it will take place in the parsing phase
+// it will take place in the lexing and parsing phase
 type SyntaxError struct {
-	Error
-	token   token.Token
+	span    Span
 	message string
 }
 
 func NewSyntaxError(token token.Token, message string) SyntaxError {
 	se := SyntaxError{
-		token:   token,
+		span:    NewSpan(token),
 		message: message,
 	}
 	return se
 }
 
+func (se SyntaxError) Kind() string    { return SYNTAX_ERROR }
+func (se SyntaxError) Message() string { return se.message }
+func (se SyntaxError) Span() Span      { return se.span }
+
+func (se SyntaxError) Error() string {
+	return fmt.Sprintf("Syntax Error at line '%d', column '%d' : %s",
+		se.span.Line, se.span.Column, se.message)
+}
+
 func (se SyntaxError) Describe() {
-	fmt.Printf("Syntax Error at line '%d' : %s\n", se.token.Line, se.message)
+	fmt.Println(se.Error())
 }
 
-// Runtime errors will take in objects as they are
-// taken place during the interpreting phase
+// Runtime errors take in the token closest to where the error
+// took place during the interpreting phase
 type RuntimeError struct {
-	Error
-	object  object.Object
+	span    Span
 	message string
 }
 
-func NewRuntimeError(obj object.Object, message string) RuntimeError {
+func NewRuntimeError(token token.Token, message string) RuntimeError {
 	re := RuntimeError{
-		object:  obj,
+		span:    NewSpan(token),
 		message: message,
 	}
 	return re
 }
 
+func (re RuntimeError) Kind() string    { return RUNTIME_ERROR }
+func (re RuntimeError) Message() string { return re.message }
+func (re RuntimeError) Span() Span      { return re.span }
+
+func (re RuntimeError) Error() string {
+	return fmt.Sprintf("Runtime Error at line '%d', column '%d' : %s",
+		re.span.Line, re.span.Column, re.message)
+}
+
 func (re RuntimeError) Describe() {
-	fmt.Printf("Runtime Error : %s at %s\n", re.object.String(), re.message)
+	fmt.Println(re.Error())
+}
+
+// Semantic errors are found by the resolver, after parsing and before
+// anything is run, i.e. variables that are never declared
+type SemanticError struct {
+	span    Span
+	message string
+}
+
+func NewSemanticError(token token.Token, message string) SemanticError {
+	se := SemanticError{
+		span:    NewSpan(token),
+		message: message,
+	}
+	return se
+}
+
+func (se SemanticError) Kind() string    { return SEMANTIC_ERROR }
+func (se SemanticError) Message() string { return se.message }
+func (se SemanticError) Span() Span      { return se.span }
+
+func (se SemanticError) Error() string {
+	return fmt.Sprintf("Semantic Error at line '%d', column '%d' : %s",
+		se.span.Line, se.span.Column, se.message)
+}
+
+func (se SemanticError) Describe() {
+	fmt.Println(se.Error())
+}
+
+// Raised when a program goes over one of the limits that it is run with,
+// or is cancelled. The position is not known when the limit is not
+// reached at a particular call, i.e. running out of steps.
+type LimitExceeded struct {
+	span    Span
+	message string
+}
+
+func NewLimitExceeded(token token.Token, message string) LimitExceeded {
+	le := LimitExceeded{
+		span:    NewSpan(token),
+		message: message,
+	}
+	return le
+}
+
+func (le LimitExceeded) Kind() string    { return LIMIT_EXCEEDED }
+func (le LimitExceeded) Message() string { return le.message }
+func (le LimitExceeded) Span() Span      { return le.span }
+
+func (le LimitExceeded) Error() string {
+	if le.span.Line == 0 {
+		return fmt.Sprintf("Limit Exceeded : %s", le.message)
+	}
+	return fmt.Sprintf("Limit Exceeded at line '%d', column '%d' : %s",
+		le.span.Line, le.span.Column, le.message)
+}
+
+func (le LimitExceeded) Describe() {
+	fmt.Println(le.Error())
 }
 
-// This is for error messages that are not the easiest to pass
-// objects into, i.e.
+// Raised by `throw`, the position is that of the throw statement
+type ThrownError struct {
+	span    Span
+	message string
+}
+
+func NewThrownError(token token.Token, message string) ThrownError {
+	te := ThrownError{
+		span:    NewSpan(token),
+		message: message,
+	}
+	return te
+}
+
+func (te ThrownError) Kind() string    { return THROWN_ERROR }
+func (te ThrownError) Message() string { return te.message }
+func (te ThrownError) Span() Span      { return te.span }
+
+func (te ThrownError) Error() string {
+	return fmt.Sprintf("Thrown Error at line '%d', column '%d' : %s",
+		te.span.Line, te.span.Column, te.message)
+}
+
+func (te ThrownError) Describe() {
+	fmt.Println(te.Error())
+}
+
+// Raised by the assert builtins when what they check does not hold
+type AssertionError struct {
+	span    Span
+	message string
+}
+
+func NewAssertionError(token token.Token, message string) AssertionError {
+	ae := AssertionError{
+		span:    NewSpan(token),
+		message: message,
+	}
+	return ae
+}
+
+func (ae AssertionError) Kind() string    { return ASSERTION_ERROR }
+func (ae AssertionError) Message() string { return ae.message }
+func (ae AssertionError) Span() Span      { return ae.span }
+
+func (ae AssertionError) Error() string {
+	if ae.span.Line == 0 {
+		return fmt.Sprintf("Assertion Error : %s", ae.message)
+	}
+	return fmt.Sprintf("Assertion Error at line '%d', column '%d' : %s",
+		ae.span.Line, ae.span.Column, ae.message)
+}
+
+func (ae AssertionError) Describe() {
+	fmt.Println(ae.Error())
+}
+
+// The program was stopped from outside of it, i.e. by quitting the
+// debugger. This is not a problem with the program, so it is not reported.
+type Stopped struct {
+	span    Span
+	message string
+}
+
+func NewStopped(token token.Token, message string) Stopped {
+	st := Stopped{
+		span:    NewSpan(token),
+		message: message,
+	}
+	return st
+}
+
+func (st Stopped) Kind() string    { return STOPPED }
+func (st Stopped) Message() string { return st.message }
+func (st Stopped) Span() Span      { return st.span }
+
+func (st Stopped) Error() string {
+	return fmt.Sprintf("Stopped at line '%d', column '%d' : %s",
+		st.span.Line, st.span.Column, st.message)
+}
+
+func (st Stopped) Describe() {
+	fmt.Println(st.Error())
+}
+
+// Points an error that was raised without a position, i.e. by a builtin
+// function, to tok. Errors other than those that say why the program
+// had to stop become runtime errors.
+func Locate(err Error, tok token.Token) Error {
+	switch err.(type) {
+	case LimitExceeded:
+		return NewLimitExceeded(tok, err.Message())
+	case AssertionError:
+		return NewAssertionError(tok, err.Message())
+	case Stopped:
+		return NewStopped(tok, err.Message())
+	}
+	return NewRuntimeError(tok, err.Message())
+}
+
+// This is for error messages that do not have a position that can
+// be pointed to, i.e.
 // in the case where there is a need to handle multiple parameters
 // and they are not entirely relevant (builtin functions)
 // A simple message to show is okay.
 type DefaultError struct {
-	Error
 	message string
 }
 
@@ -79,24 +285,113 @@ func NewDefaultError(message string) DefaultError {
 	return de
 }
 
+func (de DefaultError) Kind() string    { return DEFAULT_ERROR }
+func (de DefaultError) Message() string { return de.message }
+func (de DefaultError) Span() Span      { return Span{} }
+
+func (de DefaultError) Error() string {
+	return fmt.Sprintf("Error : %s", de.message)
+}
+
 func (de DefaultError) Describe() {
-	fmt.Printf("Error : %s\n", de.message)
+	fmt.Println(de.Error())
 }
 
 type ShadowWarning struct {
-	Error
-	line         int
+	span         Span
 	variableName string
 }
 
-func NewShadowWarning(line int, variableName string) ShadowWarning {
+func NewShadowWarning(name token.Token) ShadowWarning {
 	sw := ShadowWarning{
-		line:         line,
-		variableName: variableName,
+		span:         NewSpan(name),
+		variableName: name.Literal,
 	}
 	return sw
 }
 
+func (sw ShadowWarning) Kind() string { return SHADOW_WARNING }
+func (sw ShadowWarning) Span() Span   { return sw.span }
+
+func (sw ShadowWarning) Message() string {
+	return fmt.Sprintf("Declaring an already declared variable: \"%s\"", sw.variableName)
+}
+
+func (sw ShadowWarning) Error() string {
+	return fmt.Sprintf("Shadow warning at line %d, %s", sw.span.Line, sw.Message())
+}
+
 func (sw ShadowWarning) Describe() {
-	fmt.Printf("Shadow warning at line %d, Declaring an already declared variable: \"%s\"", sw.line, sw.variableName)
+	fmt.Println(sw.Error())
+}
+
+// Excerpt returns the line of source that the span points to, with the
+// span underlined with carets, i.e.
+//
+//	2 | var a = 10 +;
+//	  |             ^
+//
+// An empty string is returned if the span does not point into the source.
+func Excerpt(source string, span Span) string {
+	if span.Line <= 0 {
+		return ""
+	}
+
+	lines := strings.Split(source, "\n")
+	if span.Line > len(lines) {
+		return ""
+	}
+	line := strings.TrimRight(lines[span.Line-1], "\r")
+
+	column := span.Column
+	if column < 1 {
+		column = 1
+	}
+	if column > len(line)+1 {
+		column = len(line) + 1
+	}
+
+	// Keep any tabs so that the carets line up with the source line,
+	// and only count the start of every utf-8 character once.
+	var padding strings.Builder
+	for _, ch := range line[:column-1] {
+		if ch == '\t' {
+			padding.WriteRune('\t')
+		} else {
+			padding.WriteRune(' ')
+		}
+	}
+
+	length := span.Length
+	if length < 1 {
+		length = 1
+	}
+
+	gutter := fmt.Sprintf("%d", span.Line)
+	blank := strings.Repeat(" ", len(gutter))
+	return fmt.Sprintf("%s | %s\n%s | %s%s\n",
+		gutter, line, blank, padding.String(), strings.Repeat("^", length))
+}
+
+// Report is a machine readable version of an error, meant to be
+// serialized for tooling, i.e. to JSON
+type Report struct {
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Offset  int    `json:"offset"`
+	Length  int    `json:"length"`
+}
+
+func NewReport(err Error) Report {
+	span := err.Span()
+	return Report{
+		Kind:    err.Kind(),
+		Message: err.Message(),
+		Line:    span.Line,
+		Column:  span.Column,
+		Offset:  span.Offset,
+		Length:  span.Length,
+	}
 }
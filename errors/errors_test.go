@@ -0,0 +1,62 @@
+package errors
+
+import (
+	"testing"
+
+	"github.com/lczm/as/token"
+)
+
+func TestExcerpt(t *testing.T) {
+	tests := []struct {
+		source         string
+		token          token.Token
+		expectedOutput string
+	}{
+		{
+			"var a = 10;\nvar b = a +;",
+			token.Token{Type: token.SEMICOLON, Literal: ";", Line: 2, Column: 12, Offset: 23},
+			"2 | var b = a +;\n  |            ^\n",
+		},
+		{ // Tabs are kept so that the carets line up
+			"\tprint(abc);",
+			token.Token{Type: token.IDENTIFIER, Literal: "abc", Line: 1, Column: 8, Offset: 7},
+			"1 | \tprint(abc);\n  | \t      ^^^\n",
+		},
+		{ // Multi-byte characters only take up a single column
+			"\"é\" + x;",
+			token.Token{Type: token.PLUS, Literal: "+", Line: 1, Column: 6, Offset: 5},
+			"1 | \"é\" + x;\n  |     ^\n",
+		},
+		{ // Spans outside of the source have no excerpt
+			"a;",
+			token.Token{Type: token.IDENTIFIER, Literal: "a", Line: 4, Column: 1},
+			"",
+		},
+	}
+
+	for i, test := range tests {
+		err := NewSyntaxError(test.token, "message")
+		output := Excerpt(test.source, err.Span())
+		if output != test.expectedOutput {
+			t.Fatalf("Test : [%d] - Mismatch in excerpt, expected=%q, got=%q",
+				i, test.expectedOutput, output)
+		}
+	}
+}
+
+func TestReport(t *testing.T) {
+	tok := token.Token{Type: token.IDENTIFIER, Literal: "abc", Line: 3, Column: 5, Offset: 20}
+	report := NewReport(NewRuntimeError(tok, "Undefined variable 'abc'"))
+
+	expected := Report{
+		Kind:    RUNTIME_ERROR,
+		Message: "Undefined variable 'abc'",
+		Line:    3,
+		Column:  5,
+		Offset:  20,
+		Length:  3,
+	}
+	if report != expected {
+		t.Fatalf("Mismatch in report, expected=%+v, got=%+v", expected, report)
+	}
+}
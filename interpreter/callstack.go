@@ -0,0 +1,70 @@
+package interpreter
+
+import (
+	"github.com/lczm/as/environment"
+	"github.com/lczm/as/object"
+	"github.com/lczm/as/token"
+)
+
+// A call that is running, file is where the function is declared,
+// the calls made from within it are from that file. environment is
+// where the call was made from.
+type frame struct {
+	call        object.Frame
+	file        string
+	environment *environment.Environment
+}
+
+// tok is where the function is called from
+func (i *Interpreter) push(function *object.Function, tok token.Token) {
+	i.frames = append(i.frames, frame{
+		call: object.Frame{
+			Function: function.Name(),
+			Line:     tok.Line,
+			File:     i.file(),
+		},
+		file:        function.File,
+		environment: i.Environment,
+	})
+}
+
+func (i *Interpreter) pop() {
+	i.frames = i.frames[:len(i.frames)-1]
+}
+
+// The file of the code that is running
+func (i *Interpreter) file() string {
+	if len(i.frames) == 0 {
+		return i.File
+	}
+	return i.frames[len(i.frames)-1].file
+}
+
+// The calls that are running, outermost first.
+// This implements object.Caller
+func (i *Interpreter) CallStack() []object.Frame {
+	calls := make([]object.Frame, 0, len(i.frames))
+	for _, frame := range i.frames {
+		calls = append(calls, frame.call)
+	}
+	return calls
+}
+
+// The environment that each of the calls is running in, outermost first.
+// The first is the top level, so there is one more than there are calls.
+func (i *Interpreter) Environments() []*environment.Environment {
+	environments := make([]*environment.Environment, 0, len(i.frames)+1)
+	for _, frame := range i.frames {
+		environments = append(environments, frame.environment)
+	}
+	return append(environments, i.Environment)
+}
+
+// Holds on to the calls that an error was raised within, as they are
+// gone by the time that the error reaches the top. This is called as the
+// error leaves each function, only the innermost one has all of them.
+func (i *Interpreter) trace(errorObj *object.Error) {
+	if errorObj.Trace == nil {
+		errorObj.Trace = i.CallStack()
+	}
+}
@@ -0,0 +1,25 @@
+package interpreter
+
+import (
+	"github.com/lczm/as/ast"
+	"github.com/lczm/as/object"
+)
+
+// Is told about every statement before it is run, the statement only
+// runs once Statement returns, so a debugger can pause the program there.
+// Giving back an error stops the program with it. The top level of
+// imported files is not run through the debugger.
+type Debugger interface {
+	Statement(i *Interpreter, stmt ast.Statement) *object.Error
+}
+
+// The file of the code that is running, this is the file that the
+// function that is running was declared in
+func (i *Interpreter) CurrentFile() string {
+	return i.file()
+}
+
+// How many calls are running
+func (i *Interpreter) Depth() int {
+	return len(i.frames)
+}
@@ -0,0 +1,48 @@
+package interpreter
+
+import (
+	"github.com/lczm/as/ast"
+	"github.com/lczm/as/environment"
+	"github.com/lczm/as/object"
+	"github.com/lczm/as/vm"
+)
+
+// Every test is run on both the tree-walker and the vm, as both of
+// them have to give back the same results
+type engine struct {
+	name string
+	new  func(statements []ast.Statement) *program
+}
+
+// What the tests need from either of them, Value gives back the value
+// of the first statement
+type program struct {
+	Environment *environment.Environment
+	Start       func() error
+	Value       func() object.Object
+}
+
+var engines = []engine{
+	{"interpreter", func(statements []ast.Statement) *program {
+		interpreter := New(statements)
+		interpreter.Resolve(statements)
+		return &program{
+			Environment: interpreter.Environment,
+			Start:       interpreter.Start,
+			Value: func() object.Object {
+				return interpreter.Eval(statements[0])
+			},
+		}
+	}},
+	{"vm", func(statements []ast.Statement) *program {
+		machine := vm.New(statements)
+		return &program{
+			Environment: machine.Environment,
+			Start:       machine.Start,
+			Value: func() object.Object {
+				machine.Start()
+				return machine.LastPopped()
+			},
+		}
+	}},
+}
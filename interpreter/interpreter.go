@@ -1,11 +1,17 @@
 package interpreter
 
 import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/lczm/as/analysis"
 	"github.com/lczm/as/ast"
 	"github.com/lczm/as/builtin"
 	"github.com/lczm/as/environment"
 	"github.com/lczm/as/errors"
-	"github.com/lczm/as/globals"
+	"github.com/lczm/as/modules"
 	"github.com/lczm/as/object"
 	"github.com/lczm/as/token"
 )
@@ -13,41 +19,116 @@ import (
 type Interpreter struct {
 	Environment *environment.Environment
 	Statements  []ast.Statement
+	// The file that the statements come from, imports are looked for
+	// relative to it. Empty when there is no file, i.e. in the repl.
+	File string
+	// Modules that have been imported so far
+	modules *modules.Modules
+	// The program is stopped when this is cancelled, nil if it never is
+	Context context.Context
+	limits  Limits
+	usage   *usage
+	// Whether the builtins that reach outside of the program,
+	// and imports, have been disabled
+	noIO bool
+	// Where print() writes to, nil if it is standard output
+	output io.Writer
+	// The calls that are running
+	frames []frame
+	// The calls that the error that stopped Start() was raised within
+	Trace []object.Frame
+	// Pauses the program between statements, nil if it is not debugged
+	Debugger Debugger
 }
 
-func (i *Interpreter) Start() {
-	if len(i.Statements) < 0 {
-		panic("Interpreter needs at least one statement to start")
+// Runs all the statements, stopping at the first runtime error.
+func (i *Interpreter) Start() error {
+	i.Trace = nil
+	for _, stmt := range i.Statements {
+		obj := i.Eval(stmt)
+		if errorObj, ok := obj.(*object.Error); ok {
+			i.Trace = errorObj.Trace
+			return errorObj.Err
+		}
 	}
+	return nil
+}
 
-	for _, stmt := range i.Statements {
-		i.Eval(stmt)
+// Leaves the calls and blocks that were running, for when a run has been
+// given up on part of the way through, env is the top level to go back to
+func (i *Interpreter) Reset(env *environment.Environment) {
+	i.Environment = env
+	i.frames = nil
+}
+
+// Runs statements in the current environment, stopping at the first
+// runtime error. The value of the last statement is given back if it
+// is an expression, or null if it is not.
+func (i *Interpreter) Run(statements []ast.Statement) (object.Object, *object.Error) {
+	var value object.Object = object.NullValue
+	for _, stmt := range statements {
+		obj := i.Eval(stmt)
+		if errorObj, ok := obj.(*object.Error); ok {
+			return nil, errorObj
+		}
+
+		value = object.NullValue
+		if _, ok := stmt.(*ast.StatementExpression); ok && obj != nil {
+			value = obj
+		}
 	}
+	return value, nil
 }
 
 // Eval has to take in an astNode and not an ast.Statement because
 // this function will have to run recursively and deal with
 // ast.Expression at times.
+// Runtime errors are returned as an *object.Error
 func (i *Interpreter) Eval(astNode ast.AstNode) object.Object {
+	if err := i.step(); err != nil {
+		return err
+	}
+	if i.Debugger != nil {
+		if stmt, ok := astNode.(ast.Statement); ok {
+			if err := i.Debugger.Statement(i, stmt); err != nil {
+				return err
+			}
+		}
+	}
+
 	switch node := astNode.(type) {
 	case *ast.StatementExpression:
 		return i.Eval(node.Expr)
 	case *ast.IfStatement:
 		return i.evalIfStatement(node)
 	case *ast.ForStatement:
-		i.evalForStatement(node)
+		return i.evalForStatement(node)
+	case *ast.ForInStatement:
+		return i.evalForInStatement(node)
 	case *ast.WhileStatement:
-		i.evalWhileStatement(node)
+		return i.evalWhileStatement(node)
 	case *ast.BlockStatement:
 		return i.evalBlockStatement(node)
 	case *ast.FunctionStatement:
 		i.evalFunctionStatement(node)
+	case *ast.FunctionExpression:
+		return i.evalFunctionExpression(node)
 	case *ast.StructStatement:
-		i.evalStructStatement(node)
+		return i.evalStructStatement(node)
 	case *ast.ReturnStatement:
 		return i.evalReturnStatement(node)
+	case *ast.BreakStatement:
+		return &object.Break{}
+	case *ast.ContinueStatement:
+		return &object.Continue{}
+	case *ast.ImportStatement:
+		return i.evalImportStatement(node)
+	case *ast.TryStatement:
+		return i.evalTryStatement(node)
+	case *ast.ThrowStatement:
+		return i.evalThrowStatement(node)
 	case *ast.VariableStatement:
-		i.evalVariableStatement(node)
+		return i.evalVariableStatement(node)
 	case *ast.VariableExpression:
 		return i.evalVariableExpression(node)
 	case *ast.AssignmentExpression:
@@ -64,12 +145,18 @@ func (i *Interpreter) Eval(astNode ast.AstNode) object.Object {
 		return i.evalLogicalExpression(node)
 	case *ast.NumberExpression:
 		return &object.Integer{Value: int64(node.Value)}
+	case *ast.FloatExpression:
+		return &object.Float{Value: node.Value}
+	case *ast.NullExpression:
+		return object.NullValue
 	case *ast.ListExpression:
 		return i.evalListExpression(node)
 	case *ast.HashMapExpression:
 		return i.evalHashMapExpression(node)
 	case *ast.StringExpression:
-		return &object.String{Value: node.Value}
+		return i.evalStringExpression(node)
+	case *ast.InterpolationExpression:
+		return i.evalInterpolationExpression(node)
 	case *ast.BoolExpression:
 		return &object.Bool{Value: node.Value}
 	case *ast.GroupExpression:
@@ -78,13 +165,22 @@ func (i *Interpreter) Eval(astNode ast.AstNode) object.Object {
 		return i.evalCallExpression(node)
 	case *ast.GetExpression:
 		return i.evalGetExpression(node)
+	case *ast.ThisExpression:
+		return i.evalThisExpression(node)
+	case *ast.SuperExpression:
+		return i.evalSuperExpression(node)
 	}
 
 	return nil
 }
 
 func (i *Interpreter) evalIfStatement(stmt *ast.IfStatement) object.Object {
-	if i.IsTruthy(i.Eval(stmt.Condition)) {
+	condition := i.Eval(stmt.Condition)
+	if isError(condition) {
+		return condition
+	}
+
+	if i.IsTruthy(condition) {
 		return i.Eval(stmt.Then)
 	}
 
@@ -94,24 +190,108 @@ func (i *Interpreter) evalIfStatement(stmt *ast.IfStatement) object.Object {
 	return nil
 }
 
-func (i *Interpreter) evalForStatement(stmt *ast.ForStatement) {
+func (i *Interpreter) evalForStatement(stmt *ast.ForStatement) object.Object {
 	// Initialize the variable first.
-	i.Eval(stmt.Variable)
+	variable := i.Eval(stmt.Variable)
+	if isError(variable) {
+		return variable
+	}
+
+	for {
+		condition := i.Eval(stmt.Condition)
+		if isError(condition) {
+			return condition
+		}
+		if !i.IsTruthy(condition) {
+			break
+		}
 
-	for i.IsTruthy(i.Eval(stmt.Condition)) {
 		// Evaluate the body expression
-		i.Eval(stmt.Body)
+		// 'continue' still runs the effect before the next iteration
+		body := i.Eval(stmt.Body)
+		switch body.(type) {
+		case *object.Return, *object.Error:
+			return body
+		case *object.Break:
+			return nil
+		}
 
 		// Afterwards run the effect
 		// This is also where a pre vs post increment can be done.
-		i.Eval(stmt.Effect)
+		effect := i.Eval(stmt.Effect)
+		if isError(effect) {
+			return effect
+		}
 	}
+	return nil
 }
 
-func (i *Interpreter) evalWhileStatement(stmt *ast.WhileStatement) {
-	for i.IsTruthy(i.Eval(stmt.Condition)) {
-		i.Eval(stmt.Body)
+func (i *Interpreter) evalForInStatement(stmt *ast.ForInStatement) object.Object {
+	iterable := i.Eval(stmt.Iterable)
+	if isError(iterable) {
+		return iterable
+	}
+
+	iterator, err := i.iterator(iterable, stmt.In)
+	if err != nil {
+		return err
 	}
+
+	// A single name over a hashmap gets the keys, everything else
+	// gives back their elements
+	_, isHashMap := iterable.(*object.HashMap)
+
+	for {
+		key, value, ok := iterator.Next()
+		if !ok {
+			break
+		}
+		if isError(value) {
+			return value
+		}
+
+		// Every iteration has its own environment, so that closures
+		// declared in the body hold on to the values of that iteration
+		environment := environment.NewChildEnvironment(i.Environment)
+		if stmt.Key != nil {
+			environment.DefineAt(0, stmt.Key.Literal, key)
+			environment.DefineAt(1, stmt.Value.Literal, value)
+		} else if isHashMap {
+			environment.DefineAt(0, stmt.Value.Literal, key)
+		} else {
+			environment.DefineAt(0, stmt.Value.Literal, value)
+		}
+
+		body := i.ExecuteBlockStatements([]ast.Statement{stmt.Body}, environment)
+		switch body.(type) {
+		case *object.Return, *object.Error:
+			return body
+		case *object.Break:
+			return nil
+		}
+	}
+	return nil
+}
+
+func (i *Interpreter) evalWhileStatement(stmt *ast.WhileStatement) object.Object {
+	for {
+		condition := i.Eval(stmt.Condition)
+		if isError(condition) {
+			return condition
+		}
+		if !i.IsTruthy(condition) {
+			break
+		}
+
+		body := i.Eval(stmt.Body)
+		switch body.(type) {
+		case *object.Return, *object.Error:
+			return body
+		case *object.Break:
+			return nil
+		}
+	}
+	return nil
 }
 
 func (i *Interpreter) evalBlockStatement(stmt *ast.BlockStatement) object.Object {
@@ -121,243 +301,361 @@ func (i *Interpreter) evalBlockStatement(stmt *ast.BlockStatement) object.Object
 }
 
 func (i *Interpreter) evalFunctionStatement(stmt *ast.FunctionStatement) {
+	// Functions hold on to the environment they are declared in, so that
+	// they can still refer to it after it has been exited.
 	functionObject := &object.Function{
 		FunctionStatement: *stmt,
+		Closure:           i.Environment,
+		File:              i.file(),
 	}
-	i.Environment.Define(stmt.Name.Literal, functionObject)
+	i.define(stmt.Name.Literal, stmt.Location, functionObject)
+}
+
+// Anonymous functions are the same as functions, except that they
+// do not have a name and are not defined in the environment.
+func (i *Interpreter) evalFunctionExpression(expr *ast.FunctionExpression) object.Object {
+	functionObject := &object.Function{
+		FunctionStatement: ast.FunctionStatement{
+			Name:   token.Token{Type: token.IDENTIFIER, Line: expr.Keyword.Line},
+			Params: expr.Params,
+			Body:   expr.Body,
+		},
+		Closure: i.Environment,
+		File:    i.file(),
+	}
+	return functionObject
 }
 
 func (i *Interpreter) evalMethodStatement(stmt *ast.FunctionStatement) object.Object {
 	functionObject := &object.Function{
 		FunctionStatement: *stmt,
+		Closure:           i.Environment,
+		File:              i.file(),
 	}
 	return functionObject
 }
 
-func (i *Interpreter) evalStructStatement(stmt *ast.StructStatement) {
-	attributes := make(map[string]object.Object)
+func (i *Interpreter) evalStructStatement(stmt *ast.StructStatement) object.Object {
+	var parent *object.Struct
+	if stmt.Parent != nil {
+		obj := i.Eval(stmt.Parent)
+		if isError(obj) {
+			return obj
+		}
+		structObject, ok := obj.(*object.Struct)
+		if !ok {
+			return newError(stmt.Parent.Name, "Parent of a struct has to be a struct, not %s",
+				obj.RawType())
+		}
+		parent = structObject
+	}
+
 	methods := make(map[string]object.Object)
+	initializers := make(map[string]ast.Expression)
+
+	// `var a;` attributes have no initializer, and are left as nil here
+	// to be initialized to null
+	for attributeName, attributeStmt := range stmt.Attributes {
+		initializers[attributeName.Literal] = attributeStmt.(*ast.VariableStatement).Initializer
+	}
 
-	for attributeStmt := range stmt.Attributes {
-		attributes[attributeStmt.Literal] = i.Eval(attributeStmt)
+	// Methods of a struct with a parent are declared in an environment
+	// with 'super' defined in it, so that they can reach the parent methods
+	previousEnvironment := i.Environment
+	if parent != nil {
+		i.Environment = environment.NewChildEnvironment(i.Environment)
+		i.Environment.DefineAt(0, "super", parent)
 	}
 
-	// Check if the user included an initialization method
-	hasInit := false
+	// Check if the user included an initialization method,
+	// init is inherited as well if the struct does not define one
+	hasInit := parent != nil && parent.HasInit
 	for methodName, methodStmt := range stmt.Methods {
 		if methodName.Literal == "init" {
 			hasInit = true
 		}
 		methods[methodName.Literal] = i.evalMethodStatement(methodStmt.(*ast.FunctionStatement))
 	}
+	i.Environment = previousEnvironment
 
 	structObject := &object.Struct{
-		Name:       stmt.Name.Literal,
-		HasInit:    hasInit,
-		Attributes: attributes,
-		Methods:    methods,
+		Name:         stmt.Name.Literal,
+		Parent:       parent,
+		HasInit:      hasInit,
+		Attributes:   make(map[string]object.Object),
+		Methods:      methods,
+		Initializers: initializers,
+		Closure:      i.Environment,
 	}
-	i.Environment.Define(stmt.Name.Literal, structObject)
+	i.define(stmt.Name.Literal, stmt.Location, structObject)
+	return nil
 }
 
 func (i *Interpreter) evalReturnStatement(stmt *ast.ReturnStatement) object.Object {
 	if stmt.Value == nil {
-		return nil
+		return &object.Return{Value: object.NullValue}
+	}
+
+	value := i.Eval(stmt.Value)
+	if isError(value) {
+		return value
 	}
-	return &object.Return{Value: i.Eval(stmt.Value)}
+	return &object.Return{Value: value}
 }
 
-func (i *Interpreter) evalVariableStatement(stmt *ast.VariableStatement) {
-	// Create a default object, this also defines what a variable value/type will
-	// be when it is not initialized
-	// `var a;`, 'a' will be defined to what the default object is.
-	// In this case, I think setting it to a 0 integer should be fine for now.
-	// Perhaps in the future, this can be changed to a null value of some sort.
+// Errors raised in the body are caught by catch, and finally runs however
+// the body and catch are left. Whatever finally does takes the place of
+// what they did, i.e. returning from within finally.
+func (i *Interpreter) evalTryStatement(stmt *ast.TryStatement) object.Object {
+	result := i.Eval(stmt.Body)
+
+	errorObj, ok := result.(*object.Error)
+	if ok && !errorObj.Catchable() {
+		return result
+	}
+	if ok && stmt.Catch != nil {
+		environment := environment.NewChildEnvironment(i.Environment)
+		if stmt.Name != nil {
+			environment.DefineAt(0, stmt.Name.Literal, object.NewException(errorObj))
+		}
+		result = i.ExecuteBlockStatements(stmt.Catch.Statements, environment)
+	}
+
+	if stmt.Finally != nil {
+		if finally := i.Eval(stmt.Finally); finally != nil {
+			return finally
+		}
+	}
+	return result
+}
+
+func (i *Interpreter) evalThrowStatement(stmt *ast.ThrowStatement) object.Object {
+	value := i.Eval(stmt.Value)
+	if isError(value) {
+		return value
+	}
+	return object.Throw(value, stmt.Keyword)
+}
+
+func (i *Interpreter) evalVariableStatement(stmt *ast.VariableStatement) object.Object {
+	// `var a;`, 'a' will be defined as null when it is not initialized
 	if stmt.Initializer != nil {
 		initializerValue := i.Eval(stmt.Initializer)
-		i.Environment.Define(stmt.Name.Literal, initializerValue)
+		if isError(initializerValue) {
+			return initializerValue
+		}
+		i.define(stmt.Name.Literal, stmt.Location, initializerValue)
 	} else {
-		i.Environment.Define(stmt.Name.Literal, &object.Integer{Value: 0})
+		i.define(stmt.Name.Literal, stmt.Location, object.NullValue)
 	}
+	return nil
 }
 
 func (i *Interpreter) evalVariableExpression(expr *ast.VariableExpression) object.Object {
-	return i.Environment.Get(expr.Name.Literal)
+	value := i.lookUp(expr.Name.Literal, expr.Location)
+	if value == nil {
+		return newError(expr.Name, "Undefined variable '%s'", expr.Name.Literal)
+	}
+	return value
 }
 
 func (i *Interpreter) evalAssignmentExpression(expr *ast.AssignmentExpression) object.Object {
 	value := i.Eval(expr.Value)
-	i.Environment.Set(expr.Name.Literal, value)
+	if isError(value) {
+		return value
+	}
 
+	if !i.assign(expr.Name.Literal, expr.Location, value) {
+		return newError(expr.Name, "Undefined variable '%s'", expr.Name.Literal)
+	}
 	return value
 }
 
+// Defines a variable in the slot that the resolver gave it,
+// globals do not have one and are defined by name
+func (i *Interpreter) define(name string, location *ast.Location, value object.Object) {
+	if location == nil {
+		i.Environment.Define(name, value)
+		return
+	}
+	i.Environment.DefineAt(location.Slot, name, value)
+}
+
+// Gets a variable from where the resolver found it, or by name for globals.
+// A local that has not been declared yet at this point refers to whatever
+// the name refers to outside of its scope instead.
+// Returns nil if the variable cannot be found.
+func (i *Interpreter) lookUp(name string, location *ast.Location) object.Object {
+	if location == nil {
+		return i.Environment.Get(name)
+	}
+	if value := i.Environment.GetAt(location.Depth, location.Slot); value != nil {
+		return value
+	}
+	if parent := i.Environment.Ancestor(location.Depth).Parent; parent != nil {
+		return parent.Get(name)
+	}
+	return nil
+}
+
+// The same as lookUp, but sets the variable instead.
+// Returns false if the variable cannot be found.
+func (i *Interpreter) assign(name string, location *ast.Location, value object.Object) bool {
+	if location == nil {
+		return i.Environment.Set(name, value)
+	}
+	if i.Environment.SetAt(location.Depth, location.Slot, value) {
+		return true
+	}
+	if parent := i.Environment.Ancestor(location.Depth).Parent; parent != nil {
+		return parent.Set(name, value)
+	}
+	return false
+}
+
 func (i *Interpreter) evalAssignmentIndexExpression(expr *ast.AssignmentIndexExpression) object.Object {
 	value := i.Eval(expr.Value)
+	if isError(value) {
+		return value
+	}
 	index := i.Eval(expr.Index)
+	if isError(index) {
+		return index
+	}
 
-	i.Environment.SetIndex(expr.Name.Literal, index, value)
+	container := i.Eval(expr.Object)
+	if isError(container) {
+		return container
+	}
+
+	if message := object.SetIndexOperation(container, index, value); message != "" {
+		return newError(expr.Token, "%s", message)
+	}
 	return value
 }
 
 func (i *Interpreter) evalAssignmentStruct(expr *ast.AssignmentStruct) object.Object {
 	value := i.Eval(expr.Value)
+	if isError(value) {
+		return value
+	}
+
 	// Need to convert from a generic 'Expression' into a ast.VariableExpression
 	// to access Name.Literal
-	variableExpression := expr.Attribute.(*ast.VariableExpression)
-	i.Environment.SetStruct(expr.Name.Literal, variableExpression.Name.Literal, value)
+	attribute := expr.Attribute.(*ast.VariableExpression)
+
+	structObject := i.Eval(expr.Object)
+	if isError(structObject) {
+		return structObject
+	}
+
+	switch structObject := structObject.(type) {
+	case *object.Struct:
+		structObject.Attributes[attribute.Name.Literal] = value
+	default:
+		return newError(attribute.Name, "Cannot set attribute '%s' on %s",
+			attribute.Name.Literal, structObject.RawType())
+	}
 	return value
 }
 
 func (i *Interpreter) evalBinaryExpression(expr *ast.BinaryExpression) object.Object {
 	left := i.Eval(expr.Left)
+	if isError(left) {
+		return left
+	}
 	right := i.Eval(expr.Right)
-
-	switch expr.Operator.Type {
-	case token.PLUS: // Add
-		// Integers
-		if left.RawType() == object.INTEGER && right.RawType() == object.INTEGER {
-			leftValue := left.(*object.Integer).Value
-			rightValue := right.(*object.Integer).Value
-			return &object.Integer{Value: leftValue + rightValue}
-		}
-		// Strings
-		if left.RawType() == object.STRING && right.RawType() == object.STRING {
-			leftValue := left.(*object.String).Value
-			rightValue := right.(*object.String).Value
-			return &object.String{Value: leftValue + rightValue}
-		}
-	case token.MINUS: // Subtract
-		if left.RawType() == object.INTEGER && right.RawType() == object.INTEGER {
-			leftValue := left.(*object.Integer).Value
-			rightValue := right.(*object.Integer).Value
-			return &object.Integer{Value: leftValue - rightValue}
-		}
-	case token.ASTERISK: // Multiply
-		if left.RawType() == object.INTEGER && right.RawType() == object.INTEGER {
-			leftValue := left.(*object.Integer).Value
-			rightValue := right.(*object.Integer).Value
-
-			return &object.Integer{Value: leftValue * rightValue}
-		}
-	case token.SLASH: // Divide
-		if left.RawType() == object.INTEGER && right.RawType() == object.INTEGER {
-			leftValue := left.(*object.Integer).Value
-			rightValue := right.(*object.Integer).Value
-
-			return &object.Integer{Value: leftValue / rightValue}
-		}
-	case token.MODULUS: // Modulus
-		if left.RawType() == object.INTEGER && right.RawType() == object.INTEGER {
-			leftValue := left.(*object.Integer).Value
-			rightValue := right.(*object.Integer).Value
-
-			return &object.Integer{Value: leftValue % rightValue}
-		}
-	case token.GT: // Greater than
-		if left.RawType() == object.INTEGER && right.RawType() == object.INTEGER {
-			leftValue := left.(*object.Integer).Value
-			rightValue := right.(*object.Integer).Value
-			return &object.Bool{Value: leftValue > rightValue}
-		}
-	case token.GT_EQ: // Greater equal than
-		if left.RawType() == object.INTEGER && right.RawType() == object.INTEGER {
-			leftValue := left.(*object.Integer).Value
-			rightValue := right.(*object.Integer).Value
-			return &object.Bool{Value: leftValue >= rightValue}
-		}
-	case token.LT: // Lesser than
-		if left.RawType() == object.INTEGER && right.RawType() == object.INTEGER {
-			leftValue := left.(*object.Integer).Value
-			rightValue := right.(*object.Integer).Value
-			return &object.Bool{Value: leftValue < rightValue}
-		}
-	case token.LT_EQ: // Lesser equal than
-		if left.RawType() == object.INTEGER && right.RawType() == object.INTEGER {
-			leftValue := left.(*object.Integer).Value
-			rightValue := right.(*object.Integer).Value
-			return &object.Bool{Value: leftValue <= rightValue}
-		}
-	case token.EQ: // Equals '=='
-		// Integers
-		if left.RawType() == object.INTEGER && right.RawType() == object.INTEGER {
-			leftValue := left.(*object.Integer).Value
-			rightValue := right.(*object.Integer).Value
-			return &object.Bool{Value: leftValue == rightValue}
-		}
-		// Strings
-		if left.RawType() == object.STRING && right.RawType() == object.STRING {
-			leftValue := left.(*object.String).Value
-			rightValue := right.(*object.String).Value
-			return &object.Bool{Value: leftValue == rightValue}
-		}
-		// Bools
-		if left.RawType() == object.BOOL && right.RawType() == object.BOOL {
-			leftValue := left.(*object.Bool).Value
-			rightValue := right.(*object.Bool).Value
-			return &object.Bool{Value: leftValue == rightValue}
-		}
-	case token.NOT_EQ: // Not equals '!='
-		// Integers
-		if left.RawType() == object.INTEGER && right.RawType() == object.INTEGER {
-			leftValue := left.(*object.Integer).Value
-			rightValue := right.(*object.Integer).Value
-			return &object.Bool{Value: leftValue != rightValue}
-		}
-		// Strings
-		if left.RawType() == object.STRING && right.RawType() == object.STRING {
-			leftValue := left.(*object.String).Value
-			rightValue := right.(*object.String).Value
-			return &object.Bool{Value: leftValue != rightValue}
-		}
+	if isError(right) {
+		return right
 	}
 
-	return nil
+	result, message := object.BinaryOperation(expr.Operator, left, right)
+	if message != "" {
+		return newError(expr.Operator, "%s", message)
+	}
+	if err := i.allocate(result, expr.Operator); err != nil {
+		return err
+	}
+	return result
 }
 
 func (i *Interpreter) evalUnaryExpression(expr *ast.UnaryExpression) object.Object {
 	right := i.Eval(expr.Right)
+	if isError(right) {
+		return right
+	}
 
-	switch expr.Operator.Type {
-	case token.MINUS:
-		// Inverse the value
-		if right.RawType() == object.INTEGER {
-			rightValue := right.(*object.Integer).Value
-			return &object.Integer{Value: -rightValue}
-		}
-	case token.BANG:
-		// If evaluated condition is true, inverse the result
-		if i.IsTruthy(right) {
-			return &object.Bool{Value: false}
-		}
-		// If it is not true, inverse the result here
-		return &object.Bool{Value: true}
+	result, message := object.UnaryOperation(expr.Operator, right)
+	if message != "" {
+		return newError(expr.Operator, "%s", message)
 	}
-	return nil
+	return result
 }
 
 func (i *Interpreter) evalLogicalExpression(expr *ast.LogicalExpression) object.Object {
-	if expr.Operator.Type == token.AND {
-		return &object.Bool{
-			Value: i.IsTruthy(i.Eval(expr.Left)) && i.IsTruthy(i.Eval(expr.Right)),
-		}
-	} else if expr.Operator.Type == token.OR {
-		return &object.Bool{
-			Value: i.IsTruthy(i.Eval(expr.Left)) || i.IsTruthy(i.Eval(expr.Right)),
+	left := i.Eval(expr.Left)
+	if isError(left) {
+		return left
+	}
+
+	// Short circuit, the right side does not have to be evaluated
+	if expr.Operator.Type == token.AND && !i.IsTruthy(left) {
+		return &object.Bool{Value: false}
+	} else if expr.Operator.Type == token.OR && i.IsTruthy(left) {
+		return &object.Bool{Value: true}
+	}
+
+	right := i.Eval(expr.Right)
+	if isError(right) {
+		return right
+	}
+	return &object.Bool{Value: i.IsTruthy(right)}
+}
+
+// Every part is evaluated and joined together the same way that
+// print() would show it
+func (i *Interpreter) evalInterpolationExpression(expr *ast.InterpolationExpression) object.Object {
+	var value strings.Builder
+	for _, part := range expr.Parts {
+		obj := i.Eval(part)
+		if isError(obj) {
+			return obj
 		}
-	} else {
-		panic("LogicalExpression has a operator that is not supported")
+		value.WriteString(obj.String())
 	}
+
+	str := &object.String{Value: value.String()}
+	if err := i.allocate(str, expr.Token); err != nil {
+		return err
+	}
+	return str
+}
+
+func (i *Interpreter) evalStringExpression(expr *ast.StringExpression) object.Object {
+	str := &object.String{Value: expr.Value}
+	if err := i.allocate(str, token.Token{}); err != nil {
+		return err
+	}
+	return str
 }
 
 func (i *Interpreter) evalListExpression(expr *ast.ListExpression) object.Object {
 	var evaluatedExpressions []object.Object
 	for _, expression := range expr.Values {
-		evaluatedExpressions = append(evaluatedExpressions, i.Eval(expression))
+		evaluated := i.Eval(expression)
+		if isError(evaluated) {
+			return evaluated
+		}
+		evaluatedExpressions = append(evaluatedExpressions, evaluated)
 	}
-	return &object.List{
+
+	list := &object.List{
 		Value: evaluatedExpressions,
 	}
+	if err := i.allocate(list, token.Token{}); err != nil {
+		return err
+	}
+	return list
 }
 
 func (i *Interpreter) evalHashMapExpression(expr *ast.HashMapExpression) object.Object {
@@ -365,12 +663,19 @@ func (i *Interpreter) evalHashMapExpression(expr *ast.HashMapExpression) object.
 	hashMap = make(map[object.HashKey]object.HashValue)
 	for k, v := range expr.Values {
 		evaluatedKey := i.Eval(k)
+		if isError(evaluatedKey) {
+			return evaluatedKey
+		}
 		evaluatedKeyHashable, ok := evaluatedKey.(object.Hashable)
 		if !ok {
-			panic("Object is not hashable")
+			return newError(expr.Token, "Object of %s cannot be used as a hashmap key",
+				evaluatedKey.RawType())
 		}
 
 		evaluatedValue := i.Eval(v)
+		if isError(evaluatedValue) {
+			return evaluatedValue
+		}
 
 		objHash := evaluatedKeyHashable.Hash()
 		hashValue := object.HashValue{
@@ -379,156 +684,345 @@ func (i *Interpreter) evalHashMapExpression(expr *ast.HashMapExpression) object.
 		}
 		hashMap[objHash] = hashValue
 	}
-	return &object.HashMap{
+
+	hashMapObject := &object.HashMap{
 		Value: hashMap,
 	}
+	if err := i.allocate(hashMapObject, expr.Token); err != nil {
+		return err
+	}
+	return hashMapObject
 }
 
 func (i *Interpreter) evalCallExpression(expr *ast.CallExpression) object.Object {
-	switch callee := i.Eval(expr.Callee).(type) {
+	callee := i.Eval(expr.Callee)
+	if isError(callee) {
+		return callee
+	}
+
+	switch callee := callee.(type) {
 	// If it is a function that the user has defined somewhere,
 	// evaluate the arguments in the environment and pass the
 	// arguments over to the function
 	case *object.Function:
-		// function, ok := i.Eval(expr.Callee).(*object.Function)
-		// if !ok {
-		// 	panic("Call expression callee is not a declared function")
-		// }
-
-		var evaluatedArguments []object.Object
-		for _, argument := range expr.Arguments {
-			evaluatedArguments = append(evaluatedArguments, i.Eval(argument))
+		evaluatedArguments, err := i.evalArguments(expr.Arguments)
+		if err != nil {
+			return err
 		}
 
-		environment := environment.NewChildEnvironment(i.Environment)
-		for i, argument := range evaluatedArguments {
-			environment.Define(callee.FunctionStatement.Params[i].Literal,
-				argument)
-		}
-
-		obj := i.ExecuteBlockStatements(callee.FunctionStatement.Body.Statements, environment)
-		// If the object is a return value
-		returnObj, ok := obj.(*object.Return)
-		if ok {
-			return returnObj.Value
-		}
-		return obj
+		return at(i.callFunction(callee, evaluatedArguments, expr.Token), expr.Token)
 		// If it is a builtin function that is being called, evaluate the arguments
 		// and pass it to the built in function
 	case *object.BuiltinFunction:
-		var evaluatedArguments []object.Object
-		for _, argument := range expr.Arguments {
-			evaluatedArguments = append(evaluatedArguments, i.Eval(argument))
+		evaluatedArguments, err := i.evalArguments(expr.Arguments)
+		if err != nil {
+			return err
+		}
+
+		if err := i.allocateBuiltin(callee, evaluatedArguments, expr.Token); err != nil {
+			return err
 		}
 
 		// Pass the array as a variadic argument
 		obj := callee.Fn(evaluatedArguments...)
+
+		// Builtin functions do not know where they are called from,
+		// so point their errors to the call instead
+		if isError(obj) {
+			return at(obj, expr.Token)
+		}
+
 		// If the object is a return value
 		returnObj, ok := obj.(*object.Return)
 		if ok {
 			return returnObj.Value
 		}
+		if callee.Size != nil {
+			return obj
+		}
+		if err := i.allocateResult(obj, evaluatedArguments, expr.Token); err != nil {
+			return err
+		}
 		return obj
-	// This is to initialize a struct from nothing-ness
-	// TODO: Add in parameters when initializing the structs
-	// the parameters have to match up with a init function in the
-	// struct itself. Reference can be how python objects are initialized
+	// This is to initialize a struct from nothing-ness, the arguments
+	// are passed over to the init method if there is one.
 	case *object.Struct:
-		// Make a copy of the object, as this is pointer based
-		// this deep copies all the values over, except methods
-		newCallee := &object.Struct{
-			Name:       callee.Name,
-			HasInit:    callee.HasInit,
-			Attributes: make(map[string]object.Object),
-			// Methods can refer to the same function block, as there is
-			// it will function the same regardless
-			Methods: callee.Methods,
+		if callee.Instance {
+			return newError(expr.Token, "Can only call structs and functions")
+		}
+		evaluatedArguments, err := i.evalArguments(expr.Arguments)
+		if err != nil {
+			return err
 		}
 
-		// Reset all values to integer 0
-		for k := range newCallee.Attributes {
-			newCallee.Attributes[k] = &object.Integer{Value: 0}
+		return at(i.instantiate(callee, evaluatedArguments, expr.Token), expr.Token)
+	// If the callee is a list, hashmap or string, then the following is what is parsed
+	// (List)[1]
+	// Where the '1' is now the argument to the 'callee', it is known that
+	// there is only one expression
+	case *object.List, *object.HashMap, *object.String:
+		if len(expr.Arguments) == 0 {
+			return newError(expr.Token, "Object of %s cannot be called", callee.RawType())
+		}
+		index := i.Eval(expr.Arguments[0])
+		if isError(index) {
+			return index
 		}
 
-		// If the struct has an initialization method, this is where
-		// it should be called
-		if newCallee.HasInit {
-			initFunction := newCallee.Methods["init"].(*object.Function)
-			i.ExecuteBlockStatements(initFunction.FunctionStatement.Body.Statements,
-				i.Environment)
+		obj, message := object.IndexOperation(callee, index)
+		if message != "" {
+			return newError(expr.Token, "%s", message)
 		}
+		return obj
+	default:
+		if expr.Token.Type == token.LBRACKET {
+			return newError(expr.Token, "Object of %s cannot be indexed", callee.RawType())
+		}
+		return newError(expr.Token, "Object of %s cannot be called", callee.RawType())
+	}
+}
 
-		return newCallee
-	// If the callee is a list, then the following is what is parsed
-	// (List)[1]
-	// Where the '1' is now the argument to the 'callee'
-	case *object.List:
-		// Can do this as we know that it will only parse a single expression
-		evaluatedIndex := i.Eval(expr.Arguments[0])
+func (i *Interpreter) evalGetExpression(expr *ast.GetExpression) object.Object {
+	callee := i.Eval(expr.Callee)
+	if isError(callee) {
+		return callee
+	}
 
-		intIndex, ok := evaluatedIndex.(*object.Integer)
-		if !ok {
-			globals.ErrorList = append(globals.ErrorList,
-				errors.NewRuntimeError(evaluatedIndex, "Indexed operation on a list expression is not an integer"))
+	attribute := expr.Caller.(*ast.VariableExpression)
+	switch callee := callee.(type) {
+	case *object.Struct:
+		// Attributes take precedence over methods, so that a function
+		// stored in an attribute can still be called
+		obj, ok := callee.Attributes[attribute.Name.Literal]
+		if ok {
+			return obj
 		}
 
-		obj := callee.Value[intIndex.Value]
-		return obj
-	case *object.HashMap:
-		// Get the object
-		objectIndex := i.Eval(expr.Arguments[0])
+		// Methods are bound to the instance that they are accessed from
+		method, ok := callee.FindMethod(attribute.Name.Literal)
+		if ok {
+			return i.bind(method.(*object.Function), callee)
+		}
 
-		// TODO : Refactor this
-		objectHashable, ok := objectIndex.(object.Hashable)
+		if expr.IsMethod {
+			return newError(attribute.Name, "Undefined method '%s' on %s",
+				attribute.Name.Literal, callee.String())
+		}
+		return newError(attribute.Name, "Undefined attribute '%s' on %s",
+			attribute.Name.Literal, callee.String())
+	case *object.Module:
+		obj, ok := callee.Values[attribute.Name.Literal]
 		if ok {
-			obj, found := callee.Value[objectHashable.Hash()]
-			if found {
-				return obj.Value
-			}
+			return obj
 		}
+		return newError(attribute.Name, "Undefined name '%s' in %s",
+			attribute.Name.Literal, callee.String())
+	case *object.Exception:
+		obj, ok := callee.Attribute(attribute.Name.Literal)
+		if ok {
+			return obj
+		}
+		return newError(attribute.Name, "Undefined attribute '%s' on %s",
+			attribute.Name.Literal, callee.RawType())
+	default:
+		return newError(attribute.Name, "Object of %s has no attribute '%s'",
+			callee.RawType(), attribute.Name.Literal)
+	}
+}
 
-		// object not found
-		return nil
-	case *object.String:
-		// Same as above, on the list, by the time it reaches here,
-		// it is known that there is only one expression
-		evaluatedIndex := i.Eval(expr.Arguments[0])
+// Creates a new instance of a struct, tok is where the struct is
+// being instantiated from.
+func (i *Interpreter) instantiate(structObject *object.Struct,
+	arguments []object.Object, tok token.Token) object.Object {
+
+	// The number of arguments has to match up with the init method,
+	// structs without one do not take in any arguments
+	expected := 0
+	initMethod, hasInit := structObject.FindMethod("init")
+	if hasInit {
+		expected = len(initMethod.(*object.Function).FunctionStatement.Params)
+	}
+	if len(arguments) != expected {
+		return newError(tok, "%s expected %d arguments but got %d",
+			structObject.String(), expected, len(arguments))
+	}
 
-		intIndex, ok := evaluatedIndex.(*object.Integer)
-		if !ok {
-			globals.ErrorList = append(globals.ErrorList,
-				errors.NewRuntimeError(evaluatedIndex, "Indexed operation on a list expression is not an integer"))
+	// Methods can refer to the same function block, as they are
+	// bound to the instance when they are accessed
+	instance := &object.Struct{
+		Name:       structObject.Name,
+		Parent:     structObject.Parent,
+		HasInit:    structObject.HasInit,
+		Attributes: make(map[string]object.Object),
+		Methods:    structObject.Methods,
+		Instance:   true,
+	}
+	if err := i.allocate(instance, tok); err != nil {
+		return err
+	}
+
+	obj := i.initializeAttributes(instance, structObject)
+	if isError(obj) {
+		return obj
+	}
+
+	// Whatever init returns is discarded, the instance is always
+	// what is given back
+	if hasInit {
+		initFunction := i.bind(initMethod.(*object.Function), instance)
+		obj := i.callFunction(initFunction, arguments, tok)
+		if isError(obj) {
+			return obj
 		}
+	}
 
-		return &object.String{Value: string(callee.Value[intIndex.Value])}
-	default:
-		return nil
+	return instance
+}
+
+// Evaluates the attribute initializers of a struct for a new instance.
+// The parent attributes are initialized first so that the struct can
+// override them.
+func (i *Interpreter) initializeAttributes(instance *object.Struct,
+	structObject *object.Struct) object.Object {
+
+	if structObject.Parent != nil {
+		obj := i.initializeAttributes(instance, structObject.Parent)
+		if isError(obj) {
+			return obj
+		}
 	}
+
+	// Uninitialized attributes default to null, the same as
+	// `var a;` does
+	closure := structObject.Closure.(*environment.Environment)
+	for name, initializer := range structObject.Initializers {
+		if initializer == nil {
+			instance.Attributes[name] = object.NullValue
+			continue
+		}
+
+		previousEnvironment := i.Environment
+		i.Environment = closure
+		value := i.Eval(initializer)
+		i.Environment = previousEnvironment
+		if isError(value) {
+			return value
+		}
+		instance.Attributes[name] = value
+	}
+	return nil
 }
 
-func (i *Interpreter) evalGetExpression(expr *ast.GetExpression) object.Object {
-	switch callee := i.Eval(expr.Callee).(type) {
-	case *object.Struct:
-		// Check if attribute exists
-		switch attribute := expr.Caller.(type) {
-		case *ast.VariableExpression:
-			if expr.IsMethod { // Method cases
-				methodName := attribute.Name.Literal
-				obj, ok := callee.Methods[methodName]
-				if ok {
-					return obj
-				}
-			} else { // Attribute cases
-				obj, ok := callee.Attributes[attribute.Name.Literal]
-				if ok {
-					return obj
-				}
-			}
+func (i *Interpreter) evalThisExpression(expr *ast.ThisExpression) object.Object {
+	value := i.lookUp("this", expr.Location)
+	if value == nil {
+		return newError(expr.Keyword, "Cannot use 'this' outside of a method")
+	}
+	return value
+}
+
+// super.method is the parent's method, bound to the same instance
+// that 'this' refers to
+func (i *Interpreter) evalSuperExpression(expr *ast.SuperExpression) object.Object {
+	parent, ok := i.lookUp("super", expr.Location).(*object.Struct)
+	if !ok {
+		return newError(expr.Keyword, "Cannot use 'super' outside of a struct with a parent")
+	}
+	instance, ok := i.lookUp("this", expr.This).(*object.Struct)
+	if !ok {
+		return newError(expr.Keyword, "Cannot use 'super' outside of a method")
+	}
+
+	method, ok := parent.FindMethod(expr.Method.Literal)
+	if !ok {
+		return newError(expr.Method, "Undefined method '%s' on %s",
+			expr.Method.Literal, parent.String())
+	}
+	return i.bind(method.(*object.Function), instance)
+}
+
+// Binds a method to an instance, the method gets its own environment
+// with 'this' defined in it, enclosed by the environment the method
+// was declared in.
+func (i *Interpreter) bind(method *object.Function, instance *object.Struct) *object.Function {
+	environment := environment.NewChildEnvironment(method.Closure.(*environment.Environment))
+	environment.DefineAt(0, "this", instance)
+	return &object.Function{
+		FunctionStatement: method.FunctionStatement,
+		Closure:           environment,
+		File:              method.File,
+	}
+}
+
+// Calls a user defined function with arguments that have already been evaluated,
+// tok is where the function is being called from.
+func (i *Interpreter) callFunction(function *object.Function,
+	arguments []object.Object, tok token.Token) object.Object {
+
+	if len(arguments) != len(function.FunctionStatement.Params) {
+		return newError(tok, "%s expected %d arguments but got %d",
+			function.String(), len(function.FunctionStatement.Params), len(arguments))
+	}
+
+	if err := i.enter(tok); err != nil {
+		return err
+	}
+	defer i.leave()
+	i.push(function, tok)
+	defer i.pop()
+
+	// The function is evaluated within the environment it was declared
+	// in, and not where it is being called from
+	closure := function.Closure.(*environment.Environment)
+	environment := environment.NewChildEnvironment(closure)
+	for i, argument := range arguments {
+		environment.DefineAt(i, function.FunctionStatement.Params[i].Literal,
+			argument)
+	}
+
+	obj := i.ExecuteBlockStatements(function.FunctionStatement.Body.Statements, environment)
+	// If the object is a return value
+	returnObj, ok := obj.(*object.Return)
+	if ok {
+		return returnObj.Value
+	}
+	if errorObj, ok := obj.(*object.Error); ok {
+		i.trace(errorObj)
+		return obj
+	}
+	// Functions that do not return anything give back null
+	return object.NullValue
+}
+
+// This is for builtin functions that take in functions as arguments,
+// i.e. map(list, function(x) { return x * 2; })
+// This implements object.Caller
+func (i *Interpreter) CallFunction(function object.Object, arguments []object.Object) object.Object {
+	switch function := function.(type) {
+	case *object.Function:
+		return i.callFunction(function, arguments, token.Token{})
+	case *object.BuiltinFunction:
+		if err := i.allocateBuiltin(function, arguments, token.Token{}); err != nil {
+			return err
 		}
-		return nil
+		return function.Fn(arguments...)
 	default:
-		return nil
+		return newError(token.Token{}, "Object of %s cannot be called", function.RawType())
+	}
+}
+
+// Evaluates the arguments to a call from left to right, stopping
+// at the first error.
+func (i *Interpreter) evalArguments(arguments []ast.Expression) ([]object.Object, object.Object) {
+	var evaluatedArguments []object.Object
+	for _, argument := range arguments {
+		evaluated := i.Eval(argument)
+		if isError(evaluated) {
+			return nil, evaluated
+		}
+		evaluatedArguments = append(evaluatedArguments, evaluated)
 	}
+	return evaluatedArguments, nil
 }
 
 // ---  Utility functions
@@ -541,52 +1035,86 @@ func (i *Interpreter) ExecuteBlockStatements(
 	// Go does everything by value and not reference so this is fine.
 	previousEnvironment := i.Environment
 	i.Environment = environment
+	// Reset the environment back to the previous one.
+	defer func() {
+		i.Environment = previousEnvironment
+	}()
 
 	for _, stmt := range statements {
 		obj := i.Eval(stmt)
 
-		// If the object returned from evaluation is a return object
-		// break out of the evaluation loop and return it.
-		returnObj, ok := obj.(*object.Return)
-		if ok {
-			// Reset the environment back to the previous one.
-			i.Environment = previousEnvironment
-			return returnObj
+		// If the object returned from evaluation is a return object,
+		// an error, or a break or continue out of a loop, stop evaluating
+		// the block and pass it back up.
+		switch obj := obj.(type) {
+		case *object.Return, *object.Error, *object.Break, *object.Continue:
+			return obj
 		}
 	}
 
-	i.Environment = previousEnvironment
 	return nil
 }
 
-// This is where it is important to define what is truthy and what is not.
+// What is truthy is shared with the vm, see object.IsTruthy
 func (i *Interpreter) IsTruthy(obj object.Object) bool {
-	// Check for booleans
-	if obj.RawType() == object.BOOL {
-		return obj.(*object.Bool).Value
+	return object.IsTruthy(obj)
+}
+
+// Creates a runtime error that points to the token it took place at
+func newError(tok token.Token, format string, a ...interface{}) *object.Error {
+	return &object.Error{
+		Err: errors.NewRuntimeError(tok, fmt.Sprintf(format, a...)),
 	}
+}
 
-	if obj.RawType() == object.INTEGER {
-		// If the value of the object is 0, it is falsey
-		if obj.(*object.Integer).Value == 0 {
-			return false
-		}
-		// If the value of the object is not 0, it is truthy
-		return true
+// Errors that do not know where they took place, i.e. those of builtin
+// functions, are pointed to tok instead
+func at(obj object.Object, tok token.Token) object.Object {
+	errorObj, ok := obj.(*object.Error)
+	if !ok || errorObj.Err.Span().Line != 0 {
+		return obj
 	}
+	return &object.Error{
+		Err:   errors.Locate(errorObj.Err, tok),
+		Trace: errorObj.Trace,
+	}
+}
 
-	return false
+func isError(obj object.Object) bool {
+	_, ok := obj.(*object.Error)
+	return ok
 }
 
+// The statements have to be resolved before the interpreter is started
 func New(statements []ast.Statement) *Interpreter {
 	environment := environment.New()
 
-	// Populate the environment with all the built in functions
-	builtin.PopulateEnvironment(environment)
-
 	i := &Interpreter{
 		Statements:  statements,
 		Environment: environment,
+		modules:     modules.New(),
+		usage:       &usage{},
 	}
+
+	// Populate the environment with all the built in functions,
+	// the interpreter is passed in for the builtins that call functions
+	builtin.PopulateEnvironment(environment, i)
 	return i
 }
+
+// Sends what print() writes to out instead of standard output,
+// the modules that are imported write to out as well
+func (i *Interpreter) SetOutput(out io.Writer) {
+	i.output = out
+	if !i.noIO {
+		i.Environment.Define("print", builtin.PrintToFunc(out))
+	}
+}
+
+// Works out where the local variables of the statements live, this has to
+// be done before they are run. Statements that have been through the
+// semantic analyzer are already resolved.
+// Problems that the resolver finds are left to be reported at runtime.
+func (i *Interpreter) Resolve(statements []ast.Statement) {
+	analysis.NewResolver(i.Environment).Resolve(statements)
+}
@@ -4,6 +4,7 @@ import (
 	"strconv"
 	"testing"
 
+	"github.com/lczm/as/errors"
 	"github.com/lczm/as/lexer"
 	"github.com/lczm/as/object"
 	"github.com/lczm/as/parser"
@@ -42,20 +43,22 @@ func TestIntegerExpressions(t *testing.T) {
 		parser := parser.New(tokens)
 		statements := parser.Parse()
 
-		interpreter := New(statements)
-		// Directly hook into the eval function instead, as
-		// the Start() method is self contained
-		object := interpreter.Eval(statements[0])
-		str := object.String()
-
-		value, err := strconv.Atoi(str)
-		if err != nil {
-			panic(err)
-		}
+		for _, engine := range engines {
+			interpreter := engine.new(statements)
+			// Directly hook into the eval function instead, as
+			// the Start() method is self contained
+			object := interpreter.Value()
+			str := object.String()
+
+			value, err := strconv.Atoi(str)
+			if err != nil {
+				panic(err)
+			}
 
-		if test.expectedOutput != value {
-			t.Fatalf("Test : [%d] - Mismatch in values, expected=%d, got=%q",
-				i, test.expectedOutput, str)
+			if test.expectedOutput != value {
+				t.Fatalf(engine.name+" : Test : [%d] - Mismatch in values, expected=%d, got=%q",
+					i, test.expectedOutput, str)
+			}
 		}
 	}
 }
@@ -82,16 +85,18 @@ func TestListIndexExpressions(t *testing.T) {
 		parser := parser.New(tokens)
 		statements := parser.Parse()
 
-		interpreter := New(statements)
-		// Directly hook into the eval function instead, as
-		// the Start() method is self contained
-		interpreter.Start()
+		for _, engine := range engines {
+			interpreter := engine.new(statements)
+			// Directly hook into the eval function instead, as
+			// the Start() method is self contained
+			interpreter.Start()
 
-		if interpreter.Environment.Exists(outputVariable) {
-			obj := interpreter.Environment.Get(outputVariable)
-			if test.expectedOutput != obj.String() {
-				t.Fatalf("Test : [%d] - Mismatch in values, expected=%s, got=%s",
-					i, test.expectedOutput, obj.String())
+			if interpreter.Environment.Exists(outputVariable) {
+				obj := interpreter.Environment.Get(outputVariable)
+				if test.expectedOutput != obj.String() {
+					t.Fatalf(engine.name+" : Test : [%d] - Mismatch in values, expected=%s, got=%s",
+						i, test.expectedOutput, obj.String())
+				}
 			}
 		}
 	}
@@ -120,30 +125,32 @@ func TestHashMapIndexExpressions(t *testing.T) {
 		parser := parser.New(tokens)
 		statements := parser.Parse()
 
-		interpreter := New(statements)
-		// Directly hook into the eval function instead, as
-		// the Start() method is self contained
-		interpreter.Start()
+		for _, engine := range engines {
+			interpreter := engine.new(statements)
+			// Directly hook into the eval function instead, as
+			// the Start() method is self contained
+			interpreter.Start()
 
-		if interpreter.Environment.Exists(outputVariable) {
-			obj := interpreter.Environment.Get(outputVariable)
+			if interpreter.Environment.Exists(outputVariable) {
+				obj := interpreter.Environment.Get(outputVariable)
 
-			for j := 0; j < len(test.expectedOutput); j++ {
-				key := test.keys[j]
-				expectedOutput := test.expectedOutput[j]
+				for j := 0; j < len(test.expectedOutput); j++ {
+					key := test.keys[j]
+					expectedOutput := test.expectedOutput[j]
 
-				obj := obj.(*object.HashMap)
-				intValue, _ := strconv.Atoi(key)
-				keyStr := &object.Integer{Value: int64(intValue)}
+					obj := obj.(*object.HashMap)
+					intValue, _ := strconv.Atoi(key)
+					keyStr := &object.Integer{Value: int64(intValue)}
 
-				hashKey := object.HashKey{
-					Type:  keyStr.RawType(),
-					Value: keyStr.Hash().Value,
-				}
-				hashValue := obj.Value[hashKey]
-				if expectedOutput != hashValue.Value.String() {
-					t.Fatalf("Test : [%d] - Mismatch in values, expected=%s, got=%s",
-						i, expectedOutput, hashValue.Value.String())
+					hashKey := object.HashKey{
+						Type:  keyStr.RawType(),
+						Value: keyStr.Hash().Value,
+					}
+					hashValue := obj.Value[hashKey]
+					if expectedOutput != hashValue.Value.String() {
+						t.Fatalf(engine.name+" : Test : [%d] - Mismatch in values, expected=%s, got=%s",
+							i, expectedOutput, hashValue.Value.String())
+					}
 				}
 			}
 		}
@@ -183,25 +190,27 @@ func TestStructAssignment(t *testing.T) {
 		parser := parser.New(tokens)
 		statements := parser.Parse()
 
-		interpreter := New(statements)
-		// Directly hook into the eval function instead, as
-		// the Start() method is self contained
-		interpreter.Start()
-
-		counter := 0
-
-		for j := 0; j < len(outputVariables); j++ {
-			if interpreter.Environment.Exists(outputVariables[j]) {
-				obj := interpreter.Environment.Get(outputVariables[j]).(*object.Struct)
-				for k := 0; k < len(outputAttributes); k++ {
-					attribute := obj.Attributes[outputAttributes[k]]
-					value, _ := strconv.Atoi(test.expectedOutput[counter])
-					intValue := &object.Integer{Value: int64(value)}
-					if attribute.String() != intValue.String() {
-						t.Fatalf("Test : [%d] - Mismatch in values, expected=%s, got=%s",
-							i, attribute.String(), intValue.String())
+		for _, engine := range engines {
+			interpreter := engine.new(statements)
+			// Directly hook into the eval function instead, as
+			// the Start() method is self contained
+			interpreter.Start()
+
+			counter := 0
+
+			for j := 0; j < len(outputVariables); j++ {
+				if interpreter.Environment.Exists(outputVariables[j]) {
+					obj := interpreter.Environment.Get(outputVariables[j]).(*object.Struct)
+					for k := 0; k < len(outputAttributes); k++ {
+						attribute := obj.Attributes[outputAttributes[k]]
+						value, _ := strconv.Atoi(test.expectedOutput[counter])
+						intValue := &object.Integer{Value: int64(value)}
+						if attribute.String() != intValue.String() {
+							t.Fatalf(engine.name+" : Test : [%d] - Mismatch in values, expected=%s, got=%s",
+								i, attribute.String(), intValue.String())
+						}
+						counter++
 					}
-					counter++
 				}
 			}
 		}
@@ -237,21 +246,23 @@ func TestIncrementDecrement(t *testing.T) {
 		parser := parser.New(tokens)
 		statements := parser.Parse()
 
-		interpreter := New(statements)
-		interpreter.Start()
+		for _, engine := range engines {
+			interpreter := engine.new(statements)
+			interpreter.Start()
 
-		// Directly hook into the environment to check for output variable.
-		if interpreter.Environment.Exists(outputVariable) {
-			obj := interpreter.Environment.Get(outputVariable)
+			// Directly hook into the environment to check for output variable.
+			if interpreter.Environment.Exists(outputVariable) {
+				obj := interpreter.Environment.Get(outputVariable)
 
-			value, err := strconv.Atoi(obj.String())
-			if err != nil {
-				panic(err)
-			}
+				value, err := strconv.Atoi(obj.String())
+				if err != nil {
+					panic(err)
+				}
 
-			if value != test.expectedOutput {
-				t.Fatalf("Test: [%d] - Incorrect value, expected=%d, got=%d",
-					i, test.expectedOutput, value)
+				if value != test.expectedOutput {
+					t.Fatalf(engine.name+" : Test: [%d] - Incorrect value, expected=%d, got=%d",
+						i, test.expectedOutput, value)
+				}
 			}
 		}
 	}
@@ -290,12 +301,14 @@ func TestTruthy(t *testing.T) {
 		parser := parser.New(tokens)
 		statements := parser.Parse()
 
-		interpreter := New(statements)
-		b := interpreter.IsTruthy(interpreter.Eval(statements[0]))
+		for _, engine := range engines {
+			interpreter := engine.new(statements)
+			b := object.IsTruthy(interpreter.Value())
 
-		if b != test.expectedOutput {
-			t.Fatalf("Test: [%d] - Incorrect value, expected=%t, got=%t",
-				i, test.expectedOutput, b)
+			if b != test.expectedOutput {
+				t.Fatalf(engine.name+" : Test: [%d] - Incorrect value, expected=%t, got=%t",
+					i, test.expectedOutput, b)
+			}
 		}
 	}
 }
@@ -342,15 +355,17 @@ func TestIfStatements(t *testing.T) {
 		parser := parser.New(tokens)
 		statements := parser.Parse()
 
-		interpreter := New(statements)
-		interpreter.Start()
+		for _, engine := range engines {
+			interpreter := engine.new(statements)
+			interpreter.Start()
 
-		// Directly hook into the environment to check for output variable.
-		if interpreter.Environment.Exists(outputVariable) {
-			obj := interpreter.Environment.Get(outputVariable)
-			if obj.String() != test.expectedOutput {
-				t.Fatalf("Test: [%d] - Incorrect value, expected=%s, got=%s",
-					i, test.expectedOutput, obj.String())
+			// Directly hook into the environment to check for output variable.
+			if interpreter.Environment.Exists(outputVariable) {
+				obj := interpreter.Environment.Get(outputVariable)
+				if obj.String() != test.expectedOutput {
+					t.Fatalf(engine.name+" : Test: [%d] - Incorrect value, expected=%s, got=%s",
+						i, test.expectedOutput, obj.String())
+				}
 			}
 		}
 	}
@@ -378,15 +393,17 @@ func TestWhileStatements(t *testing.T) {
 		parser := parser.New(tokens)
 		statements := parser.Parse()
 
-		interpreter := New(statements)
-		interpreter.Start()
+		for _, engine := range engines {
+			interpreter := engine.new(statements)
+			interpreter.Start()
 
-		// Directly hook into the environment to check for output variable
-		if interpreter.Environment.Exists(outputVariable) {
-			obj := interpreter.Environment.Get(outputVariable)
-			if obj.String() != test.expectedOutput {
-				t.Fatalf("Test: [%d] - Incorrect value, expected=%s, got=%s",
-					i, test.expectedOutput, obj.String())
+			// Directly hook into the environment to check for output variable
+			if interpreter.Environment.Exists(outputVariable) {
+				obj := interpreter.Environment.Get(outputVariable)
+				if obj.String() != test.expectedOutput {
+					t.Fatalf(engine.name+" : Test: [%d] - Incorrect value, expected=%s, got=%s",
+						i, test.expectedOutput, obj.String())
+				}
 			}
 		}
 	}
@@ -434,15 +451,17 @@ func TestForStatements(t *testing.T) {
 		parser := parser.New(tokens)
 		statements := parser.Parse()
 
-		interpreter := New(statements)
-		interpreter.Start()
+		for _, engine := range engines {
+			interpreter := engine.new(statements)
+			interpreter.Start()
 
-		// Directly hook into the environment to check for outpit variable
-		if interpreter.Environment.Exists(outputVariable) {
-			obj := interpreter.Environment.Get(outputVariable)
-			if obj.String() != test.expectedOutput {
-				t.Fatalf("Test: [%d] - Incorrect value, expected=%s, got=%s",
-					i, test.expectedOutput, obj.String())
+			// Directly hook into the environment to check for outpit variable
+			if interpreter.Environment.Exists(outputVariable) {
+				obj := interpreter.Environment.Get(outputVariable)
+				if obj.String() != test.expectedOutput {
+					t.Fatalf(engine.name+" : Test: [%d] - Incorrect value, expected=%s, got=%s",
+						i, test.expectedOutput, obj.String())
+				}
 			}
 		}
 	}
@@ -475,15 +494,1333 @@ func TestFunctionStatements(t *testing.T) {
 		parser := parser.New(tokens)
 		statements := parser.Parse()
 
-		interpreter := New(statements)
-		interpreter.Start()
+		for _, engine := range engines {
+			interpreter := engine.new(statements)
+			interpreter.Start()
+
+			// Directly hook into the environment to check for outpit variable
+			if interpreter.Environment.Exists(outputVariable) {
+				obj := interpreter.Environment.Get(outputVariable)
+				if obj.String() != test.expectedOutput {
+					t.Fatalf(engine.name+" : Test: [%d] - Incorrect value, expected=%s, got=%s",
+						i, test.expectedOutput, obj.String())
+				}
+			}
+		}
+	}
+}
+
+func TestClosures(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedOutput string
+	}{
+		{ // Returned closures keep mutating their captured variable
+			`
+			function counter() {
+				var count = 0;
+				function increment() {
+					count++;
+					return count;
+				}
+				return increment;
+			}
+			var next = counter();
+			next();
+			next();
+			var output = next();
+			`,
+			"3",
+		},
+		{ // Local functions can call themselves through the variable they are in
+			`
+			function f() {
+				var fact = (n) => {
+					if (n <= 1) {
+						return 1;
+					}
+					return n * fact(n - 1);
+				};
+				return fact(5);
+			}
+			var output = f();
+			`,
+			"120",
+		},
+		{ // Local functions can call those that are declared after them
+			`
+			function f() {
+				function isEven(n) {
+					if (n == 0) {
+						return true;
+					}
+					return isOdd(n - 1);
+				}
+				function isOdd(n) {
+					if (n == 0) {
+						return false;
+					}
+					return isEven(n - 1);
+				}
+				return isEven(10);
+			}
+			var output = f();
+			`,
+			"true",
+		},
+		{ // Closures see locals that are declared after them once they are set
+			`
+			function f() {
+				var get = () => later;
+				var later = 5;
+				return get();
+			}
+			var output = f();
+			`,
+			"5",
+		},
+		{ // Until its declaration, a local does not hide the one outside of it
+			`
+			function f() {
+				var a = 1;
+				{
+					var b = a;
+					var a = 2;
+					return [b, a];
+				}
+			}
+			var output = f();
+			`,
+			"[1, 2]",
+		},
+		{ // Each call creates its own captured environment
+			`
+			function counter() {
+				var count = 0;
+				function increment() {
+					count += 1;
+					return count;
+				}
+				return increment;
+			}
+			var a = counter();
+			var b = counter();
+			a();
+			a();
+			b();
+			var output = [a(), b()];
+			`,
+			"[3, 2]",
+		},
+		{ // Nested functions read their arguments through enclosing scopes
+			`
+			function adder(a) {
+				function add(b) {
+					function addBoth(c) {
+						return a + b + c;
+					}
+					return addBoth;
+				}
+				return add;
+			}
+			var output = adder(1)(2)(3);
+			`,
+			"6",
+		},
+		{ // Functions are lexically scoped, not scoped to where they are called
+			`
+			var x = "global";
+			function show() {
+				return x;
+			}
+			function caller() {
+				var x = "local";
+				return show();
+			}
+			var output = caller();
+			`,
+			"global",
+		},
+		{ // Closures see updates made to the captured variable from outside
+			`
+			function make() {
+				var value = 1;
+				function get() {
+					return value;
+				}
+				value = 10;
+				return get;
+			}
+			var output = make()();
+			`,
+			"10",
+		},
+		{ // Locals refer to the outer variable until they are declared
+			`
+			var x = "global";
+			var output;
+			{
+				var before = x;
+				var x = "local";
+				output = [before, x];
+			}
+			`,
+			"[global, local]",
+		},
+	}
+
+	outputVariable := "output"
+	lexer := lexer.New()
+
+	for i, test := range tests {
+		tokens := lexer.Scan(test.input)
+		parser := parser.New(tokens)
+		statements := parser.Parse()
+
+		for _, engine := range engines {
+			interpreter := engine.new(statements)
+			if err := interpreter.Start(); err != nil {
+				t.Fatalf(engine.name+" : Test: [%d] - Unexpected error : %s", i, err)
+			}
+
+			obj := interpreter.Environment.Get(outputVariable)
+			if obj == nil || obj.String() != test.expectedOutput {
+				t.Fatalf(engine.name+" : Test: [%d] - Incorrect value, expected=%s, got=%v",
+					i, test.expectedOutput, obj)
+			}
+		}
+	}
+}
+
+func TestFunctionExpressions(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedOutput string
+	}{
+		{
+			`
+			var add = function(a, b) { return a + b; };
+			var output = add(1, 2);
+			`,
+			"3",
+		},
+		{
+			`
+			var add = (a, b) => a + b;
+			var output = add(2, 3);
+			`,
+			"5",
+		},
+		{
+			`
+			var double = x => x * 2;
+			var output = double(4);
+			`,
+			"8",
+		},
+		{
+			`
+			var seven = () => { var a = 3; return a + 4; };
+			var output = seven();
+			`,
+			"7",
+		},
+		{ // Functions can be stored in containers
+			`
+			var ops = {"add": (a, b) => a + b, "sub": (a, b) => a - b};
+			var list = [x => x + 1];
+			var output = ops["sub"](ops["add"](5, 5), list[0](2));
+			`,
+			"7",
+		},
+		{ // Called immediately
+			`var output = (function(a) { return a * a; })(3);`,
+			"9",
+		},
+		{ // Passed into other functions and capture their scope
+			`
+			function apply(f, value) {
+				return f(value);
+			}
+			function multiplier(n) {
+				return x => x * n;
+			}
+			var output = apply(multiplier(3), 5);
+			`,
+			"15",
+		},
+		{
+			`var output = function() {};`,
+			"Function : <anonymous>",
+		},
+	}
+
+	outputVariable := "output"
+	lexer := lexer.New()
+
+	for i, test := range tests {
+		tokens := lexer.Scan(test.input)
+		parser := parser.New(tokens)
+		statements := parser.Parse()
+
+		for _, engine := range engines {
+			interpreter := engine.new(statements)
+			if err := interpreter.Start(); err != nil {
+				t.Fatalf(engine.name+" : Test: [%d] - Unexpected error : %s", i, err)
+			}
+
+			obj := interpreter.Environment.Get(outputVariable)
+			if obj == nil || obj.String() != test.expectedOutput {
+				t.Fatalf(engine.name+" : Test: [%d] - Incorrect value, expected=%s, got=%v",
+					i, test.expectedOutput, obj)
+			}
+		}
+	}
+}
+
+func TestStructMethods(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedOutput string
+	}{
+		{
+			`
+			struct Counter {
+				var count = 0;
+				increment(by) {
+					this.count = this.count + by;
+				}
+			}
+			var counter = Counter();
+			counter.increment(2);
+			counter.increment(3);
+			var output = counter.count;
+			`,
+			"5",
+		},
+		{ // Methods calling other methods
+			`
+			struct Rectangle {
+				var width = 2;
+				var height = 3;
+				area() {
+					return this.width * this.height;
+				}
+				double() {
+					return this.area() * 2;
+				}
+			}
+			var output = Rectangle().double();
+			`,
+			"12",
+		},
+		{ // Instances do not share their attributes
+			`
+			struct Bag {
+				var items = [];
+				add(item) {
+					this.items = append(this.items, item);
+				}
+			}
+			var a = Bag();
+			var b = Bag();
+			a.add(1);
+			a.add(2);
+			b.add(3);
+			var output = len(a.items) + len(b.items);
+			`,
+			"3",
+		},
+		{ // Methods stay bound when stored elsewhere
+			`
+			struct Greeter {
+				var name = "as";
+				greet() {
+					return "hello " + this.name;
+				}
+			}
+			var greet = Greeter().greet;
+			var output = greet();
+			`,
+			"hello as",
+		},
+		{ // Index assignment through attributes
+			`
+			struct Grid {
+				var cells = [0, 0];
+				set(i, value) {
+					this.cells[i] = value;
+				}
+			}
+			var grid = Grid();
+			grid.set(1, 7);
+			var output = grid.cells[1];
+			`,
+			"7",
+		},
+		{ // 'this' is captured by closures within methods
+			`
+			struct Adder {
+				var base = 10;
+				adder() {
+					return x => this.base + x;
+				}
+			}
+			var output = Adder().adder()(5);
+			`,
+			"15",
+		},
+	}
+
+	outputVariable := "output"
+	lexer := lexer.New()
+
+	for i, test := range tests {
+		tokens := lexer.Scan(test.input)
+		parser := parser.New(tokens)
+		statements := parser.Parse()
+
+		for _, engine := range engines {
+			interpreter := engine.new(statements)
+			if err := interpreter.Start(); err != nil {
+				t.Fatalf(engine.name+" : Test: [%d] - Unexpected error : %s", i, err)
+			}
+
+			obj := interpreter.Environment.Get(outputVariable)
+			if obj == nil || obj.String() != test.expectedOutput {
+				t.Fatalf(engine.name+" : Test: [%d] - Incorrect value, expected=%s, got=%v",
+					i, test.expectedOutput, obj)
+			}
+		}
+	}
+}
+
+func TestStructInit(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedOutput string
+	}{
+		{
+			`
+			struct Point {
+				var x;
+				var y;
+				init(x, y) {
+					this.x = x;
+					this.y = y;
+				}
+			}
+			var point = Point(3, 4);
+			var output = point.x * point.y;
+			`,
+			"12",
+		},
+		{ // init can call other methods, and its return value is discarded
+			`
+			struct Account {
+				var balance = 0;
+				init(amount) {
+					this.deposit(amount);
+					return 0;
+				}
+				deposit(amount) {
+					this.balance = this.balance + amount;
+				}
+			}
+			var output = Account(50).balance;
+			`,
+			"50",
+		},
+		{ // Attributes are initialized before init runs
+			`
+			struct Stack {
+				var items = [];
+				init(first) {
+					this.items = append(this.items, first);
+				}
+			}
+			var output = len(Stack(1).items);
+			`,
+			"1",
+		},
+		{
+			`
+			struct Empty {}
+			var output = Empty();
+			`,
+			"Struct: <Empty>",
+		},
+	}
+
+	outputVariable := "output"
+	lexer := lexer.New()
+
+	for i, test := range tests {
+		tokens := lexer.Scan(test.input)
+		parser := parser.New(tokens)
+		statements := parser.Parse()
+
+		for _, engine := range engines {
+			interpreter := engine.new(statements)
+			if err := interpreter.Start(); err != nil {
+				t.Fatalf(engine.name+" : Test: [%d] - Unexpected error : %s", i, err)
+			}
+
+			obj := interpreter.Environment.Get(outputVariable)
+			if obj == nil || obj.String() != test.expectedOutput {
+				t.Fatalf(engine.name+" : Test: [%d] - Incorrect value, expected=%s, got=%v",
+					i, test.expectedOutput, obj)
+			}
+		}
+	}
+}
+
+func TestStructInheritance(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedOutput string
+	}{
+		{ // Attributes and methods are inherited
+			`
+			struct Shape {
+				var sides = 0;
+				describe() {
+					return this.sides;
+				}
+			}
+			struct Square : Shape {
+				var length = 2;
+			}
+			var square = Square();
+			square.sides = 4;
+			var output = square.describe() * square.length;
+			`,
+			"8",
+		},
+		{ // Methods and attributes can be overridden
+			`
+			struct Shape {
+				var sides = 0;
+				area() {
+					return 0;
+				}
+			}
+			struct Square : Shape {
+				var sides = 4;
+				area() {
+					return 9;
+				}
+			}
+			var square = Square();
+			var output = square.area() + square.sides;
+			`,
+			"13",
+		},
+		{ // init is inherited
+			`
+			struct Named {
+				var name;
+				init(name) {
+					this.name = name;
+				}
+			}
+			struct Person : Named {}
+			var output = Person("as").name;
+			`,
+			"as",
+		},
+		{ // super reaches the parent implementation, through multiple levels
+			`
+			struct A {
+				value() {
+					return 1;
+				}
+			}
+			struct B : A {
+				value() {
+					return super.value() + 10;
+				}
+			}
+			struct C : B {
+				value() {
+					return super.value() + 100;
+				}
+			}
+			var output = C().value();
+			`,
+			"111",
+		},
+		{ // Parent methods call the overriding methods through this
+			`
+			struct Base {
+				name() {
+					return "base";
+				}
+				greet() {
+					return "hello " + this.name();
+				}
+			}
+			struct Derived : Base {
+				name() {
+					return "derived";
+				}
+			}
+			var output = Derived().greet();
+			`,
+			"hello derived",
+		},
+	}
+
+	outputVariable := "output"
+	lexer := lexer.New()
+
+	for i, test := range tests {
+		tokens := lexer.Scan(test.input)
+		parser := parser.New(tokens)
+		statements := parser.Parse()
+
+		for _, engine := range engines {
+			interpreter := engine.new(statements)
+			if err := interpreter.Start(); err != nil {
+				t.Fatalf(engine.name+" : Test: [%d] - Unexpected error : %s", i, err)
+			}
+
+			obj := interpreter.Environment.Get(outputVariable)
+			if obj == nil || obj.String() != test.expectedOutput {
+				t.Fatalf(engine.name+" : Test: [%d] - Incorrect value, expected=%s, got=%v",
+					i, test.expectedOutput, obj)
+			}
+		}
+	}
+}
+
+func TestStructInstanceCall(t *testing.T) {
+	tests := []string{
+		`
+		struct A {}
+		var a = A();
+		a();
+		`,
+		`
+		struct Point {
+			var x = 1;
+			init(x) {
+				this.x = x;
+			}
+		}
+		var point = Point(2);
+		point(3);
+		`,
+	}
+
+	lexer := lexer.New()
+	for i, input := range tests {
+		tokens := lexer.Scan(input)
+		parser := parser.New(tokens)
+		statements := parser.Parse()
+
+		interpreter := New(statements)
+		interpreter.Resolve(statements)
+		err := interpreter.Start()
+		if err == nil {
+			t.Fatalf("Test: [%d] - Expected an error, got none", i)
+		}
+		runtimeError := err.(errors.Error)
+		if runtimeError.Kind() != errors.RUNTIME_ERROR ||
+			runtimeError.Message() != "Can only call structs and functions" {
+			t.Fatalf("Test: [%d] - Wrong error, got=%s : %q", i,
+				runtimeError.Kind(), runtimeError.Message())
+		}
+	}
+}
+
+func TestFloatExpressions(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedOutput string
+	}{
+		{`var output = 3.14;`, "3.14"},
+		{`var output = 1e-9;`, "1e-09"},
+		{`var output = 2.5e3;`, "2500.0"},
+		{`var output = 1.5 + 1.5;`, "3.0"},
+		{`var output = 1 + 0.5;`, "1.5"},
+		{`var output = 0.5 * 4;`, "2.0"},
+		{`var output = 10 / 4.0;`, "2.5"},
+		{`var output = 10 / 4;`, "2"},
+		{`var output = 7.5 % 2;`, "1.5"},
+		{`var output = -2.5 - 1;`, "-3.5"},
+		{`var output = 1 == 1.0;`, "true"},
+		{`var output = 1.5 != 1.5;`, "false"},
+		{`var output = 2 > 1.5;`, "true"},
+		{`var output = 0.1 <= 0.1;`, "true"},
+		{`var output = 1.5; output++;`, "2.5"},
+		{`var output = 1; output += 0.5;`, "1.5"},
+		{ // Whole floats and integers are the same hashmap key
+			`
+			var names = {1: "one", 2.5: "two and a half"};
+			var output = names[1.0] + names[2.5];
+			`,
+			"onetwo and a half",
+		},
+	}
+
+	outputVariable := "output"
+	lexer := lexer.New()
+
+	for i, test := range tests {
+		tokens := lexer.Scan(test.input)
+		parser := parser.New(tokens)
+		statements := parser.Parse()
+
+		for _, engine := range engines {
+			interpreter := engine.new(statements)
+			if err := interpreter.Start(); err != nil {
+				t.Fatalf(engine.name+" : Test: [%d] - Unexpected error : %s", i, err)
+			}
+
+			obj := interpreter.Environment.Get(outputVariable)
+			if obj == nil || obj.String() != test.expectedOutput {
+				t.Fatalf(engine.name+" : Test: [%d] - Incorrect value, expected=%s, got=%v",
+					i, test.expectedOutput, obj)
+			}
+		}
+	}
+}
+
+func TestNull(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedOutput string
+	}{
+		{`var output;`, "null"},
+		{`var output = null;`, "null"},
+		{`var output = nil;`, "null"},
+		{`var output = null == nil;`, "true"},
+		{`var a; var output = a == null;`, "true"},
+		{`var output = 0 == null;`, "false"},
+		{`var output = "" != null;`, "true"},
+		{`var output = {"a": 1}["b"];`, "null"},
+		{`var output = [null, 1];`, "[null, 1]"},
+		{`var output = type(null);`, "<type: NULL>"},
+		{ // Null is not truthy
+			`
+			var output = 1;
+			if (null) {
+				output = 2;
+			}
+			`,
+			"1",
+		},
+		{ // Functions that do not return anything give back null
+			`
+			function f() {}
+			var output = f();
+			`,
+			"null",
+		},
+		{
+			`
+			function f() {
+				return;
+				print("unreachable");
+			}
+			var output = f();
+			`,
+			"null",
+		},
+		{
+			`
+			struct A {
+				var a;
+			}
+			var output = A().a;
+			`,
+			"null",
+		},
+	}
+
+	outputVariable := "output"
+	lexer := lexer.New()
+
+	for i, test := range tests {
+		tokens := lexer.Scan(test.input)
+		parser := parser.New(tokens)
+		statements := parser.Parse()
+
+		for _, engine := range engines {
+			interpreter := engine.new(statements)
+			if err := interpreter.Start(); err != nil {
+				t.Fatalf(engine.name+" : Test: [%d] - Unexpected error : %s", i, err)
+			}
+
+			obj := interpreter.Environment.Get(outputVariable)
+			if obj == nil || obj.String() != test.expectedOutput {
+				t.Fatalf(engine.name+" : Test: [%d] - Incorrect value, expected=%s, got=%v",
+					i, test.expectedOutput, obj)
+			}
+		}
+	}
+}
+
+func TestLoopControl(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedOutput string
+	}{
+		{
+			`
+			var output = 0;
+			while (true) {
+				output++;
+				if (output == 5) {
+					break;
+				}
+			}
+			`,
+			"5",
+		},
+		{ // continue still runs the effect of a for loop
+			`
+			var output = 0;
+			for (var i = 0; i < 10; i++) {
+				if (i % 2 == 0) {
+					continue;
+				}
+				output += i;
+			}
+			`,
+			"25",
+		},
+		{ // Only the innermost loop is broken out of
+			`
+			var output = 0;
+			for (var i = 0; i < 3; i++) {
+				for (var j = 0; j < 3; j++) {
+					if (j == 1) {
+						break;
+					}
+					output++;
+				}
+			}
+			`,
+			"3",
+		},
+		{
+			`
+			var output = 0;
+			var i = 0;
+			while (i < 5) {
+				i++;
+				if (i == 2) {
+					continue;
+				}
+				output += i;
+			}
+			`,
+			"13",
+		},
+		{ // return propagates out of loops
+			`
+			function find(list, target) {
+				for (var i = 0; i < len(list); i++) {
+					if (list[i] == target) {
+						return i;
+					}
+				}
+				return -1;
+			}
+			var output = find([4, 5, 6], 6);
+			`,
+			"2",
+		},
+		{
+			`
+			function count() {
+				var i = 0;
+				while (true) {
+					i++;
+					if (i == 3) return i;
+				}
+			}
+			var output = count();
+			`,
+			"3",
+		},
+	}
+
+	outputVariable := "output"
+	lexer := lexer.New()
+
+	for i, test := range tests {
+		tokens := lexer.Scan(test.input)
+		parser := parser.New(tokens)
+		statements := parser.Parse()
+
+		for _, engine := range engines {
+			interpreter := engine.new(statements)
+			if err := interpreter.Start(); err != nil {
+				t.Fatalf(engine.name+" : Test: [%d] - Unexpected error : %s", i, err)
+			}
+
+			obj := interpreter.Environment.Get(outputVariable)
+			if obj == nil || obj.String() != test.expectedOutput {
+				t.Fatalf(engine.name+" : Test: [%d] - Incorrect value, expected=%s, got=%v",
+					i, test.expectedOutput, obj)
+			}
+		}
+	}
+}
+
+func TestForInStatements(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedOutput string
+	}{
+		{
+			`
+			var output = 0;
+			for (x in [1, 2, 3]) {
+				output += x;
+			}
+			`,
+			"6",
+		},
+		{
+			`
+			var output = 0;
+			for (i, x in [5, 5, 5]) {
+				output += i * x;
+			}
+			`,
+			"15",
+		},
+		{ // A single name gets the keys of a hashmap, in order
+			`
+			var output = "";
+			for (key in {"b": 1, "c": 2, "a": 3}) {
+				output += key;
+			}
+			`,
+			"abc",
+		},
+		{
+			`
+			var output = 0;
+			for (key, value in {"a": 1, "b": 2}) {
+				output += value;
+			}
+			`,
+			"3",
+		},
+		{ // Strings are looped over by character
+			`
+			var output = "";
+			for (ch in "héllo") {
+				output = ch + output;
+			}
+			`,
+			"olléh",
+		},
+		{
+			`
+			var output = [];
+			for (i in range(10, 0, -3)) {
+				output = append(output, i);
+			}
+			`,
+			"[10, 7, 4, 1]",
+		},
+		{ // break and continue
+			`
+			var output = 0;
+			for (i in range(100)) {
+				if (i % 2 == 1) {
+					continue;
+				}
+				if (i > 6) {
+					break;
+				}
+				output += i;
+			}
+			`,
+			"12",
+		},
+		{ // Every iteration has its own variable
+			`
+			var functions = [];
+			for (i in range(3)) {
+				functions = append(functions, () => i);
+			}
+			var output = functions[0]() + functions[2]();
+			`,
+			"2",
+		},
+		{ // Structs that are iterators
+			`
+			struct Countdown {
+				var n;
+				init(n) {
+					this.n = n;
+				}
+				hasNext() {
+					return this.n > 0;
+				}
+				next() {
+					this.n -= 1;
+					return this.n + 1;
+				}
+			}
+			var output = [];
+			for (i in Countdown(3)) {
+				output = append(output, i);
+			}
+			`,
+			"[3, 2, 1]",
+		},
+		{ // Structs that give back something to loop over
+			`
+			struct Bag {
+				var items = [4, 5];
+				iterator() {
+					return this.items;
+				}
+			}
+			var output = 0;
+			for (x in Bag()) {
+				output += x;
+			}
+			`,
+			"9",
+		},
+		{ // return propagates out of for-in loops
+			`
+			function first(list) {
+				for (x in list) {
+					return x;
+				}
+			}
+			var output = first([8, 9]);
+			`,
+			"8",
+		},
+	}
+
+	outputVariable := "output"
+	lexer := lexer.New()
+
+	for i, test := range tests {
+		tokens := lexer.Scan(test.input)
+		parser := parser.New(tokens)
+		statements := parser.Parse()
+
+		for _, engine := range engines {
+			interpreter := engine.new(statements)
+			if err := interpreter.Start(); err != nil {
+				t.Fatalf(engine.name+" : Test: [%d] - Unexpected error : %s", i, err)
+			}
+
+			obj := interpreter.Environment.Get(outputVariable)
+			if obj == nil || obj.String() != test.expectedOutput {
+				t.Fatalf(engine.name+" : Test: [%d] - Incorrect value, expected=%s, got=%v",
+					i, test.expectedOutput, obj)
+			}
+		}
+	}
+}
+
+func TestStringIndexing(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedOutput string
+	}{
+		{`var output = "hello"[1];`, "e"},
+		{`var output = "héllo"[1];`, "é"},
+		{`var output = "héllo"[4];`, "o"},
+		{`var output = len("héllo");`, "5"},
+		{`var output = len("😀");`, "1"},
+		{`var output = 'a' + "b" + ` + "`c`" + `;`, "abc"},
+		{`var output = "a\tb" == 'a	b';`, "true"},
+	}
+
+	outputVariable := "output"
+	lexer := lexer.New()
+
+	for i, test := range tests {
+		tokens := lexer.Scan(test.input)
+		parser := parser.New(tokens)
+		statements := parser.Parse()
+
+		for _, engine := range engines {
+			interpreter := engine.new(statements)
+			if err := interpreter.Start(); err != nil {
+				t.Fatalf(engine.name+" : Test: [%d] - Unexpected error : %s", i, err)
+			}
+
+			obj := interpreter.Environment.Get(outputVariable)
+			if obj == nil || obj.String() != test.expectedOutput {
+				t.Fatalf(engine.name+" : Test: [%d] - Incorrect value, expected=%s, got=%v",
+					i, test.expectedOutput, obj)
+			}
+		}
+	}
+}
+
+func TestStringInterpolation(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedOutput string
+	}{
+		{`var a = 2; var b = 3; var output = "total: ${a + b}";`, "total: 5"},
+		{`var output = "${1}${2} and ${"three"}";`, "12 and three"},
+		{`var output = 'single ${1.5 * 2} quotes';`, "single 3.0 quotes"},
+		{`var output = "${[1, 2]} ${null} ${true}";`, "[1, 2] null true"},
+		{`var output = "nested ${"a ${1 + 1} b"}";`, "nested a 2 b"},
+		{`var m = {"k": "v"}; var output = "${m["k"]}";`, "v"},
+		{`var output = "\${escaped}";`, "${escaped}"},
+		// Lists and hashmaps that hold themselves
+		{`var l = [1]; l[0] = l; var output = "${l}";`, "[[...]]"},
+		{`var m = {"a": 1}; m["a"] = m; var output = "${m}";`, "[\"a\": [...]\n]"},
+		{`var output = ` + "`raw ${1}`" + `;`, "raw ${1}"},
+		{
+			`
+			function greet(name) {
+				return "hello ${name}";
+			}
+			var output = greet("as");
+			`,
+			"hello as",
+		},
+	}
+
+	outputVariable := "output"
+	lexer := lexer.New()
+
+	for i, test := range tests {
+		tokens := lexer.Scan(test.input)
+		parser := parser.New(tokens)
+		statements := parser.Parse()
+
+		for _, engine := range engines {
+			interpreter := engine.new(statements)
+			if err := interpreter.Start(); err != nil {
+				t.Fatalf(engine.name+" : Test: [%d] - Unexpected error : %s", i, err)
+			}
+
+			obj := interpreter.Environment.Get(outputVariable)
+			if obj == nil || obj.String() != test.expectedOutput {
+				t.Fatalf(engine.name+" : Test: [%d] - Incorrect value, expected=%s, got=%v",
+					i, test.expectedOutput, obj)
+			}
+		}
+	}
+}
+
+func TestTryStatements(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedOutput string
+	}{
+		{
+			`
+			var output = "";
+			try {
+				output += "a";
+				throw "oops";
+				output += "b";
+			} catch (e) {
+				output += e.message;
+			}
+			`,
+			"aoops",
+		},
+		{ // Runtime errors are caught with their kind and position
+			`
+			var output = "";
+			try {
+				var a = 1 / 0;
+			} catch (e) {
+				output = "${e.kind} ${e.line}:${e.column} ${e.message} ${e.value}";
+			}
+			`,
+			"RuntimeError 4:15 Division by zero null",
+		},
+		{ // Any value can be thrown
+			`
+			var output = 0;
+			try {
+				throw [1, 2];
+			} catch (e) {
+				output = e.value[1];
+			}
+			`,
+			"2",
+		},
+		{ // Errors are caught from within the functions that are called
+			`
+			function parse(a) {
+				if (a == "") {
+					throw "empty";
+				}
+				return int(a);
+			}
+			function tryParse(a) {
+				try {
+					return parse(a);
+				} catch {
+					return -1;
+				}
+			}
+			var output = [tryParse("5"), tryParse("")];
+			`,
+			"[5, -1]",
+		},
+		{ // finally runs however the try is left
+			`
+			var output = "";
+			function f(a) {
+				try {
+					if (a) {
+						throw "x";
+					}
+					return "returned";
+				} catch {
+					return "caught";
+				} finally {
+					output += "finally ";
+				}
+			}
+			var a = f(true);
+			var b = f(false);
+			output += a + " " + b;
+			`,
+			"finally finally caught returned",
+		},
+		{ // Errors that are not caught are raised again after finally
+			`
+			var output = "";
+			try {
+				try {
+					throw "inner";
+				} finally {
+					output += "cleanup ";
+				}
+			} catch (e) {
+				output += e.message;
+			}
+			`,
+			"cleanup inner",
+		},
+		{
+			`
+			var output = "";
+			try {
+				try {
+					throw 1;
+				} catch (e) {
+					throw e.value + 1;
+				} finally {
+					output += "finally ";
+				}
+			} catch (e) {
+				output += e.message;
+			}
+			`,
+			"finally 2",
+		},
+		{ // Throwing what was caught raises the same error again
+			`
+			var output = "";
+			try {
+				try {
+					[1][2];
+				} catch (e) {
+					throw e;
+				}
+			} catch (e) {
+				output = "${e.kind} ${e.line}";
+			}
+			`,
+			"RuntimeError 5",
+		},
+		{ // break and continue run finally on their way out of the loop
+			`
+			var output = 0;
+			for (var i = 0; i < 5; i++) {
+				try {
+					if (i == 1) {
+						continue;
+					}
+					if (i == 3) {
+						break;
+					}
+					output += 1;
+				} finally {
+					output += 10;
+				}
+			}
+			`,
+			"42",
+		},
+		{ // What finally returns takes the place of what try returned
+			`
+			function f() {
+				try {
+					return 1;
+				} finally {
+					return 2;
+				}
+			}
+			var output = f();
+			`,
+			"2",
+		},
+		{ // Errors are caught from functions that builtins call
+			`
+			var output = 0;
+			try {
+				map([1, 2], function(x) {
+					throw x * 10;
+				});
+			} catch (e) {
+				output = e.value;
+			}
+			`,
+			"10",
+		},
+		{ // The error can be held on to after catch
+			`
+			var errors = [];
+			for (x in [1, 2]) {
+				try {
+					throw x;
+				} catch (e) {
+					errors = append(errors, function() { return e.value; });
+				}
+			}
+			var output = errors[0]() + errors[1]();
+			`,
+			"3",
+		},
+	}
+
+	outputVariable := "output"
+	lexer := lexer.New()
+
+	for i, test := range tests {
+		tokens := lexer.Scan(test.input)
+		parser := parser.New(tokens)
+		statements := parser.Parse()
+
+		for _, engine := range engines {
+			interpreter := engine.new(statements)
+			if err := interpreter.Start(); err != nil {
+				t.Fatalf(engine.name+" : Test: [%d] - Unexpected error : %s", i, err)
+			}
 
-		// Directly hook into the environment to check for outpit variable
-		if interpreter.Environment.Exists(outputVariable) {
 			obj := interpreter.Environment.Get(outputVariable)
-			if obj.String() != test.expectedOutput {
-				t.Fatalf("Test: [%d] - Incorrect value, expected=%s, got=%s",
-					i, test.expectedOutput, obj.String())
+			if obj == nil || obj.String() != test.expectedOutput {
+				t.Fatalf(engine.name+" : Test: [%d] - Incorrect value, expected=%s, got=%v",
+					i, test.expectedOutput, obj)
 			}
 		}
 	}
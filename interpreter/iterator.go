@@ -0,0 +1,88 @@
+package interpreter
+
+import (
+	"github.com/lczm/as/object"
+	"github.com/lczm/as/token"
+)
+
+// Gets an iterator to loop over an object, tok is where the object
+// is being looped over from.
+// Structs can be looped over by either:
+// - Being an iterator themselves, with hasNext() and next() methods
+// - Having an iterator() method that gives back something to loop over
+func (i *Interpreter) iterator(obj object.Object, tok token.Token) (object.Iterator, object.Object) {
+	switch obj := obj.(type) {
+	case object.Iterable:
+		return obj.Iterator(), nil
+	case *object.Struct:
+		if isStructIterator(obj) {
+			return &structIterator{interpreter: i, instance: obj, tok: tok}, nil
+		}
+
+		method, ok := obj.FindMethod("iterator")
+		if !ok {
+			return nil, newError(tok, "%s cannot be iterated over, it needs an iterator() "+
+				"method or hasNext() and next() methods", obj.String())
+		}
+
+		result := i.callFunction(i.bind(method.(*object.Function), obj), nil, tok)
+		if isError(result) {
+			return nil, result
+		}
+
+		// Only go one level deep, so that iterator() returning the
+		// struct itself does not go on forever
+		switch result := result.(type) {
+		case object.Iterable:
+			return result.Iterator(), nil
+		case *object.Struct:
+			if isStructIterator(result) {
+				return &structIterator{interpreter: i, instance: result, tok: tok}, nil
+			}
+		}
+		return nil, newError(tok, "iterator() of %s has to give back something "+
+			"that can be iterated over, not %s", obj.String(), result.RawType())
+	default:
+		return nil, newError(tok, "Object of %s cannot be iterated over", obj.RawType())
+	}
+}
+
+func isStructIterator(instance *object.Struct) bool {
+	_, hasNext := instance.FindMethod("hasNext")
+	_, next := instance.FindMethod("next")
+	return hasNext && next
+}
+
+// Adapts a struct with hasNext() and next() methods into an iterator,
+// the keys are the number of elements given back so far.
+type structIterator struct {
+	interpreter *Interpreter
+	instance    *object.Struct
+	tok         token.Token
+	index       int64
+}
+
+func (si *structIterator) Next() (object.Object, object.Object, bool) {
+	hasNext := si.call("hasNext")
+	if isError(hasNext) {
+		return nil, hasNext, true
+	}
+	if !si.interpreter.IsTruthy(hasNext) {
+		return nil, nil, false
+	}
+
+	value := si.call("next")
+	if isError(value) {
+		return nil, value, true
+	}
+
+	key := &object.Integer{Value: si.index}
+	si.index++
+	return key, value, true
+}
+
+func (si *structIterator) call(name string) object.Object {
+	method, _ := si.instance.FindMethod(name)
+	bound := si.interpreter.bind(method.(*object.Function), si.instance)
+	return si.interpreter.callFunction(bound, nil, si.tok)
+}
@@ -0,0 +1,161 @@
+package interpreter
+
+import (
+	"fmt"
+
+	"github.com/lczm/as/builtin"
+	"github.com/lczm/as/errors"
+	"github.com/lczm/as/object"
+	"github.com/lczm/as/token"
+)
+
+// Calls that are nested deeper than this are stopped when no other
+// limit is given, before they run out of Go stack
+const DEFAULT_CALL_DEPTH = 10000
+
+// How many steps are taken between checks of whether the
+// context has been cancelled
+const CANCEL_INTERVAL = 256
+
+// Limits on what a program can use up, a limit that is 0 is not checked.
+type Limits struct {
+	// The number of statements and expressions that can be evaluated
+	Steps int
+	// How deeply function calls can be nested, DEFAULT_CALL_DEPTH if 0
+	CallDepth int
+	// Roughly how many bytes of strings, lists, hashmaps and instances
+	// can be allocated over the whole run
+	Memory int
+}
+
+// What a program has used up so far, this is shared with the
+// interpreters of the modules that it imports
+type usage struct {
+	steps     int
+	depth     int
+	allocated int
+}
+
+// Sets the limits of the interpreter, and starts counting from nothing
+func (i *Interpreter) SetLimits(limits Limits) {
+	i.limits = limits
+	*i.usage = usage{}
+}
+
+// Removes the builtins that reach outside of the program, i.e. print(),
+// and stops files from being imported
+func (i *Interpreter) DisableIO() {
+	for _, name := range builtin.IO_BUILTINS {
+		delete(i.Environment.Values, name)
+	}
+	i.noIO = true
+}
+
+// Called on every evaluation, returns an error once the program has
+// run out of steps or has been cancelled
+func (i *Interpreter) step() *object.Error {
+	i.usage.steps++
+	if i.limits.Steps > 0 && i.usage.steps > i.limits.Steps {
+		return newLimitExceeded(token.Token{}, "Exceeded the limit of %d steps", i.limits.Steps)
+	}
+
+	if i.Context != nil && i.usage.steps%CANCEL_INTERVAL == 0 {
+		select {
+		case <-i.Context.Done():
+			return newLimitExceeded(token.Token{}, "Cancelled : %s", i.Context.Err())
+		default:
+		}
+	}
+	return nil
+}
+
+// Called when a function is entered, tok is where it is called from.
+// leave() has to be called once the function returns.
+func (i *Interpreter) enter(tok token.Token) *object.Error {
+	limit := i.limits.CallDepth
+	if limit == 0 {
+		limit = DEFAULT_CALL_DEPTH
+	}
+	if i.usage.depth >= limit {
+		return newLimitExceeded(tok, "Exceeded the limit of %d nested calls", limit)
+	}
+	i.usage.depth++
+	return nil
+}
+
+func (i *Interpreter) leave() {
+	i.usage.depth--
+}
+
+// Counts an object that has just been created towards the memory limit
+func (i *Interpreter) allocate(obj object.Object, tok token.Token) *object.Error {
+	if i.limits.Memory == 0 {
+		return nil
+	}
+	return i.charge(sizeOf(obj), tok)
+}
+
+// Counts what a builtin gives back towards the memory limit. Lists that
+// are a grown copy of the first argument, i.e. from append(), are only
+// counted for what was added to them, otherwise building up a list one
+// element at a time would count the whole of the list every time.
+func (i *Interpreter) allocateResult(obj object.Object, arguments []object.Object,
+	tok token.Token) *object.Error {
+
+	if i.limits.Memory == 0 {
+		return nil
+	}
+
+	size := sizeOf(obj)
+	if _, ok := obj.(*object.List); ok && len(arguments) > 0 {
+		if argument, ok := arguments[0].(*object.List); ok {
+			size -= sizeOf(argument)
+			if size < 0 {
+				size = 0
+			}
+		}
+	}
+	return i.charge(size, tok)
+}
+
+// Counts what a builtin is going to build towards the memory limit before
+// it is called, for the builtins that can say how much that is. What they
+// give back is not counted again.
+func (i *Interpreter) allocateBuiltin(builtin *object.BuiltinFunction, arguments []object.Object,
+	tok token.Token) *object.Error {
+
+	if i.limits.Memory == 0 || builtin.Size == nil {
+		return nil
+	}
+	return i.charge(builtin.Size(arguments...), tok)
+}
+
+func (i *Interpreter) charge(size int, tok token.Token) *object.Error {
+	i.usage.allocated += size
+	if i.usage.allocated > i.limits.Memory {
+		return newLimitExceeded(tok, "Exceeded the limit of %d bytes of memory", i.limits.Memory)
+	}
+	return nil
+}
+
+// A rough size of an object, without the objects that it holds as those
+// are counted when they are created. Numbers, bools and null are not counted.
+func sizeOf(obj object.Object) int {
+	switch obj := obj.(type) {
+	case *object.String:
+		return 16 + len(obj.Value)
+	case *object.List:
+		return 24 + 16*len(obj.Value)
+	case *object.HashMap:
+		return 48 + 48*len(obj.Value)
+	case *object.Struct:
+		return 64 + 32*len(obj.Attributes)
+	}
+	return 0
+}
+
+func newLimitExceeded(tok token.Token, format string, a ...interface{}) *object.Error {
+	return &object.Error{
+		Err: errors.NewLimitExceeded(tok, fmt.Sprintf(format, a...)),
+	}
+}
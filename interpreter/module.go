@@ -0,0 +1,59 @@
+package interpreter
+
+import (
+	"github.com/lczm/as/ast"
+	"github.com/lczm/as/environment"
+	"github.com/lczm/as/object"
+)
+
+// import "path"; defines the module under the name of the file,
+// import name from "path"; defines it under the given name.
+func (i *Interpreter) evalImportStatement(stmt *ast.ImportStatement) object.Object {
+	name, ok := stmt.ModuleName()
+	if !ok {
+		return newError(stmt.Path, "Cannot name a module after \"%s\", use 'import name from \"%s\";'",
+			stmt.Path.Literal, stmt.Path.Literal)
+	}
+
+	if i.noIO {
+		return newError(stmt.Keyword, "Imports are disabled")
+	}
+
+	module := i.importModule(stmt)
+	if isError(module) {
+		return module
+	}
+	i.Environment.Define(name, module)
+	return nil
+}
+
+// Modules are run by an interpreter of their own
+func (i *Interpreter) importModule(stmt *ast.ImportStatement) object.Object {
+	return i.modules.Import(i.File, stmt.Path.Literal, stmt.Path,
+		func(path string, statements []ast.Statement) (map[string]object.Object, error) {
+			moduleInterpreter := i.newModuleInterpreter(path, statements)
+			if err := moduleInterpreter.Start(); err != nil {
+				return nil, err
+			}
+			return moduleInterpreter.Environment.Values, nil
+		})
+}
+
+// Modules are run in an environment of their own, the builtins are kept
+// in the parent environment so that only what the module declares is
+// part of the module.
+func (i *Interpreter) newModuleInterpreter(path string, statements []ast.Statement) *Interpreter {
+	moduleInterpreter := New(statements)
+	moduleInterpreter.Resolve(statements)
+	if i.output != nil {
+		moduleInterpreter.SetOutput(i.output)
+	}
+	moduleInterpreter.Environment = environment.NewChildEnvironment(moduleInterpreter.Environment)
+	moduleInterpreter.File = path
+	moduleInterpreter.modules = i.modules
+	// Modules count towards the limits of the program importing them
+	moduleInterpreter.Context = i.Context
+	moduleInterpreter.limits = i.limits
+	moduleInterpreter.usage = i.usage
+	return moduleInterpreter
+}
@@ -2,22 +2,44 @@ package lexer
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
 
+	"github.com/lczm/as/errors"
 	"github.com/lczm/as/token"
 )
 
+// Raw strings can span multiple lines, this is used to tell that
+// the rest of the string has not been typed in yet.
+const UNTERMINATED_RAW_STRING = "Unterminated raw string"
+
 type Lexer struct {
 	Keywords map[string]token.TokenType
+	// Syntax errors found by the last call to Scan
+	Errors []errors.Error
 }
 
 func (l *Lexer) Scan(source string) []token.Token {
+	l.Errors = nil
+	// Default to line 1
+	return l.scan(source, 0, len(source), 1, 0)
+}
+
+// Scans the part of the source from index up to limit. Positions are
+// kept relative to the whole source, so that parts of strings that are
+// interpolated can be scanned on their own.
+// lineStart is the byte offset of where the current line starts, used to
+// work out the column of every token.
+func (l *Lexer) scan(source string, index int, limit int,
+	currentLine int, lineStart int) []token.Token {
 	var tokens []token.Token
 
-	// Default to line 1
-	currentLine := 1
+	currentIndex := index
+	for currentIndex < limit {
+		// Keep track of where the token starts
+		start := currentIndex
 
-	currentIndex := 0
-	for currentIndex < len(source) {
 		// Get the current character
 		ch := source[currentIndex]
 		// Increment index, as used by previous character
@@ -28,23 +50,28 @@ func (l *Lexer) Scan(source string) []token.Token {
 		case '\t': // Tabs
 		case '\n': // New line
 			currentLine++
+			lineStart = currentIndex
 		case '\r': // Carriage Return (CR)
 			break
 		// Operators
 		case '+':
 			// Handle the case of '++'
-			if currentIndex < len(source) && source[currentIndex] == '+' {
+			if currentIndex < limit && source[currentIndex] == '+' {
 				tokens = append(tokens, token.Token{
 					Type:    token.INCREMENT,
 					Literal: "++",
 					Line:    currentLine,
+					Column:  start - lineStart + 1,
+					Offset:  start,
 				})
 				currentIndex++
-			} else if currentIndex < len(source) && source[currentIndex] == '=' {
+			} else if currentIndex < limit && source[currentIndex] == '=' {
 				tokens = append(tokens, token.Token{
 					Type:    token.AUG_PLUS,
 					Literal: "+=",
 					Line:    currentLine,
+					Column:  start - lineStart + 1,
+					Offset:  start,
 				})
 				currentIndex++
 			} else {
@@ -52,21 +79,27 @@ func (l *Lexer) Scan(source string) []token.Token {
 					Type:    token.PLUS,
 					Literal: "+",
 					Line:    currentLine,
+					Column:  start - lineStart + 1,
+					Offset:  start,
 				})
 			}
 		case '-':
-			if currentIndex < len(source) && source[currentIndex] == '-' {
+			if currentIndex < limit && source[currentIndex] == '-' {
 				tokens = append(tokens, token.Token{
 					Type:    token.DECREMENT,
 					Literal: "--",
 					Line:    currentLine,
+					Column:  start - lineStart + 1,
+					Offset:  start,
 				})
 				currentIndex++
-			} else if currentIndex < len(source) && source[currentIndex] == '=' {
+			} else if currentIndex < limit && source[currentIndex] == '=' {
 				tokens = append(tokens, token.Token{
 					Type:    token.AUG_MINUS,
 					Literal: "-=",
 					Line:    currentLine,
+					Column:  start - lineStart + 1,
+					Offset:  start,
 				})
 				currentIndex++
 			} else {
@@ -74,15 +107,19 @@ func (l *Lexer) Scan(source string) []token.Token {
 					Type:    token.MINUS,
 					Literal: "-",
 					Line:    currentLine,
+					Column:  start - lineStart + 1,
+					Offset:  start,
 				})
 			}
 		case '!':
 			// Handle the case of '!='
-			if currentIndex < len(source) && source[currentIndex] == '=' {
+			if currentIndex < limit && source[currentIndex] == '=' {
 				tokens = append(tokens, token.Token{
 					Type:    token.NOT_EQ,
 					Literal: "!=",
 					Line:    currentLine,
+					Column:  start - lineStart + 1,
+					Offset:  start,
 				})
 				currentIndex++
 			} else { // Handle the case of '!'
@@ -90,14 +127,18 @@ func (l *Lexer) Scan(source string) []token.Token {
 					Type:    token.BANG,
 					Literal: "!",
 					Line:    currentLine,
+					Column:  start - lineStart + 1,
+					Offset:  start,
 				})
 			}
 		case '*':
-			if currentIndex < len(source) && source[currentIndex] == '=' {
+			if currentIndex < limit && source[currentIndex] == '=' {
 				tokens = append(tokens, token.Token{
 					Type:    token.AUG_ASTERISK,
 					Literal: "*=",
 					Line:    currentLine,
+					Column:  start - lineStart + 1,
+					Offset:  start,
 				})
 				currentIndex++
 			} else {
@@ -105,21 +146,27 @@ func (l *Lexer) Scan(source string) []token.Token {
 					Type:    token.ASTERISK,
 					Literal: "*",
 					Line:    currentLine,
+					Column:  start - lineStart + 1,
+					Offset:  start,
 				})
 			}
 		case '/':
-			if currentIndex < len(source) && source[currentIndex] == '=' {
+			if currentIndex < limit && source[currentIndex] == '=' {
 				tokens = append(tokens, token.Token{
 					Type:    token.AUG_SLASH,
 					Literal: "/=",
 					Line:    currentLine,
+					Column:  start - lineStart + 1,
+					Offset:  start,
 				})
 				currentIndex++
-			} else if currentIndex < len(source) && source[currentIndex] == '/' {
+			} else if currentIndex < limit && source[currentIndex] == '/' {
 				tokens = append(tokens, token.Token{
 					Type:    token.COMMENT,
 					Literal: "//",
 					Line:    currentLine,
+					Column:  start - lineStart + 1,
+					Offset:  start,
 				})
 				currentIndex++
 			} else {
@@ -127,14 +174,18 @@ func (l *Lexer) Scan(source string) []token.Token {
 					Type:    token.SLASH,
 					Literal: "/",
 					Line:    currentLine,
+					Column:  start - lineStart + 1,
+					Offset:  start,
 				})
 			}
 		case '%':
-			if currentIndex < len(source) && source[currentIndex] == '=' {
+			if currentIndex < limit && source[currentIndex] == '=' {
 				tokens = append(tokens, token.Token{
 					Type:    token.AUG_MODULUS,
 					Literal: "%=",
 					Line:    currentLine,
+					Column:  start - lineStart + 1,
+					Offset:  start,
 				})
 				currentIndex++
 			} else {
@@ -142,15 +193,19 @@ func (l *Lexer) Scan(source string) []token.Token {
 					Type:    token.MODULUS,
 					Literal: "%",
 					Line:    currentLine,
+					Column:  start - lineStart + 1,
+					Offset:  start,
 				})
 			}
 		// Comparison Operators
 		case '<':
-			if currentIndex < len(source) && source[currentIndex] == '=' {
+			if currentIndex < limit && source[currentIndex] == '=' {
 				tokens = append(tokens, token.Token{
 					Type:    token.LT_EQ,
 					Literal: "<=",
 					Line:    currentLine,
+					Column:  start - lineStart + 1,
+					Offset:  start,
 				})
 				currentIndex++
 			} else {
@@ -158,14 +213,18 @@ func (l *Lexer) Scan(source string) []token.Token {
 					Type:    token.LT,
 					Literal: "<",
 					Line:    currentLine,
+					Column:  start - lineStart + 1,
+					Offset:  start,
 				})
 			}
 		case '>':
-			if currentIndex < len(source) && source[currentIndex] == '=' {
+			if currentIndex < limit && source[currentIndex] == '=' {
 				tokens = append(tokens, token.Token{
 					Type:    token.GT_EQ,
 					Literal: ">=",
 					Line:    currentLine,
+					Column:  start - lineStart + 1,
+					Offset:  start,
 				})
 				currentIndex++
 			} else {
@@ -173,15 +232,28 @@ func (l *Lexer) Scan(source string) []token.Token {
 					Type:    token.GT,
 					Literal: ">",
 					Line:    currentLine,
+					Column:  start - lineStart + 1,
+					Offset:  start,
 				})
 			}
 		case '=':
 			// Handle the case of '=='
-			if currentIndex < len(source) && source[currentIndex] == '=' {
+			if currentIndex < limit && source[currentIndex] == '=' {
 				tokens = append(tokens, token.Token{
 					Type:    token.EQ,
 					Literal: "==",
 					Line:    currentLine,
+					Column:  start - lineStart + 1,
+					Offset:  start,
+				})
+				currentIndex++
+			} else if currentIndex < limit && source[currentIndex] == '>' {
+				tokens = append(tokens, token.Token{
+					Type:    token.ARROW,
+					Literal: "=>",
+					Line:    currentLine,
+					Column:  start - lineStart + 1,
+					Offset:  start,
 				})
 				currentIndex++
 			} else { // Handle the case of '='
@@ -189,30 +261,38 @@ func (l *Lexer) Scan(source string) []token.Token {
 					Type:    token.ASSIGN,
 					Literal: "=",
 					Line:    currentLine,
+					Column:  start - lineStart + 1,
+					Offset:  start,
 				})
 			}
 		// Logical Comparisons
 		case '&':
-			if source[currentIndex] == '&' {
+			if currentIndex < limit && source[currentIndex] == '&' {
 				tokens = append(tokens, token.Token{
 					Type:    token.AND,
 					Literal: "&&",
 					Line:    currentLine,
+					Column:  start - lineStart + 1,
+					Offset:  start,
 				})
 				currentIndex++
 			} else {
-				panic("Single '&' character cannot be lexed")
+				l.illegal(source, start, currentLine, lineStart,
+					"Single '&' character cannot be lexed, did you mean '&&'?")
 			}
 		case '|':
-			if source[currentIndex] == '|' {
+			if currentIndex < limit && source[currentIndex] == '|' {
 				tokens = append(tokens, token.Token{
 					Type:    token.OR,
 					Literal: "||",
 					Line:    currentLine,
+					Column:  start - lineStart + 1,
+					Offset:  start,
 				})
 				currentIndex++
 			} else {
-				panic("Single '|' character cannot be lexed")
+				l.illegal(source, start, currentLine, lineStart,
+					"Single '|' character cannot be lexed, did you mean '||'?")
 			}
 		// Delimiters
 		case '.':
@@ -220,86 +300,159 @@ func (l *Lexer) Scan(source string) []token.Token {
 				Type:    token.DOT,
 				Literal: ".",
 				Line:    currentLine,
+				Column:  start - lineStart + 1,
+				Offset:  start,
 			})
 		case ',':
 			tokens = append(tokens, token.Token{
 				Type:    token.COMMA,
 				Literal: ",",
 				Line:    currentLine,
+				Column:  start - lineStart + 1,
+				Offset:  start,
 			})
 		case ':':
 			tokens = append(tokens, token.Token{
 				Type:    token.COLON,
 				Literal: ":",
 				Line:    currentLine,
+				Column:  start - lineStart + 1,
+				Offset:  start,
 			})
 		case ';':
 			tokens = append(tokens, token.Token{
 				Type:    token.SEMICOLON,
 				Literal: ";",
 				Line:    currentLine,
+				Column:  start - lineStart + 1,
+				Offset:  start,
 			})
 		case '(':
 			tokens = append(tokens, token.Token{
 				Type:    token.LPAREN,
 				Literal: "(",
 				Line:    currentLine,
+				Column:  start - lineStart + 1,
+				Offset:  start,
 			})
 		case ')':
 			tokens = append(tokens, token.Token{
 				Type:    token.RPAREN,
 				Literal: ")",
 				Line:    currentLine,
+				Column:  start - lineStart + 1,
+				Offset:  start,
 			})
 		case '{':
 			tokens = append(tokens, token.Token{
 				Type:    token.LBRACE,
 				Literal: "{",
 				Line:    currentLine,
+				Column:  start - lineStart + 1,
+				Offset:  start,
 			})
 		case '}':
 			tokens = append(tokens, token.Token{
 				Type:    token.RBRACE,
 				Literal: "}",
 				Line:    currentLine,
+				Column:  start - lineStart + 1,
+				Offset:  start,
 			})
 		case '[':
 			tokens = append(tokens, token.Token{
 				Type:    token.LBRACKET,
 				Literal: "[",
 				Line:    currentLine,
+				Column:  start - lineStart + 1,
+				Offset:  start,
 			})
 		case ']':
 			tokens = append(tokens, token.Token{
 				Type:    token.RBRACKET,
 				Literal: "]",
 				Line:    currentLine,
+				Column:  start - lineStart + 1,
+				Offset:  start,
 			})
-		case '"':
+		case '"', '\'':
+			// Strings in either quotes are the same, and have to end on the
+			// same line that they started on
+			stringTokens, end := l.scanString(source, start, currentLine, lineStart)
+			tokens = append(tokens, stringTokens...)
+			currentIndex = end
+		case '`':
+			// Raw strings, everything up until the closing '`' is taken as
+			// it is, including new lines
 			extendedIndex := currentIndex
-			for extendedIndex < len(source) && source[extendedIndex] != '"' {
+			for extendedIndex < limit && source[extendedIndex] != '`' {
 				extendedIndex++
 			}
+			if extendedIndex >= limit {
+				l.illegal(source, start, currentLine, lineStart, UNTERMINATED_RAW_STRING)
+			}
 
-			stringValue := source[currentIndex:extendedIndex]
-			currentIndex = extendedIndex + 1
-
+			stringValue := strings.ReplaceAll(source[currentIndex:extendedIndex], "\r\n", "\n")
 			tokens = append(tokens, token.Token{
 				Type:    token.STRING,
 				Literal: stringValue,
 				Line:    currentLine,
+				Column:  start - lineStart + 1,
+				Offset:  start,
 			})
+
+			// The tokens after the string are on the line that the string
+			// ended on
+			for index := currentIndex; index < extendedIndex; index++ {
+				if source[index] == '\n' {
+					currentLine++
+					lineStart = index + 1
+				}
+			}
+			currentIndex = extendedIndex + 1
 		default:
 			if l.isDigit(ch) { // Handle numeric case
 				extendedIndex := currentIndex
-				for extendedIndex < len(source) && l.isDigit(source[extendedIndex]) {
+				for extendedIndex < limit && l.isDigit(source[extendedIndex]) {
 					extendedIndex++
 				}
 
+				// A fractional part, i.e. 3.14
+				// The '.' has to be followed by a digit, so that it is not
+				// confused with anything else that uses a '.'
+				tokenType := token.TokenType(token.NUMBER)
+				if extendedIndex+1 < limit && source[extendedIndex] == '.' &&
+					l.isDigit(source[extendedIndex+1]) {
+					tokenType = token.FLOAT
+					extendedIndex++
+					for extendedIndex < limit && l.isDigit(source[extendedIndex]) {
+						extendedIndex++
+					}
+				}
+
+				// An exponent, i.e. 1e-9, 2.5E+3
+				if extendedIndex < limit &&
+					(source[extendedIndex] == 'e' || source[extendedIndex] == 'E') {
+					exponentIndex := extendedIndex + 1
+					if exponentIndex < limit &&
+						(source[exponentIndex] == '+' || source[exponentIndex] == '-') {
+						exponentIndex++
+					}
+					if exponentIndex < limit && l.isDigit(source[exponentIndex]) {
+						tokenType = token.FLOAT
+						extendedIndex = exponentIndex
+						for extendedIndex < limit && l.isDigit(source[extendedIndex]) {
+							extendedIndex++
+						}
+					}
+				}
+
 				tokens = append(tokens, token.Token{
-					Type:    token.NUMBER,
+					Type:    tokenType,
 					Literal: source[currentIndex-1 : extendedIndex],
 					Line:    currentLine,
+					Column:  start - lineStart + 1,
+					Offset:  start,
 				})
 				currentIndex = extendedIndex
 			} else if l.isAlphaNumeric(ch) { // Handle alpha-numeric case
@@ -307,7 +460,7 @@ func (l *Lexer) Scan(source string) []token.Token {
 				// alphaNumeric, i.e. 'abc', 'bcd'
 				// This can then get classified as an identifier
 				extendedIndex := currentIndex
-				for extendedIndex < len(source) &&
+				for extendedIndex < limit &&
 					l.isAlphaNumeric(source[extendedIndex]) {
 					extendedIndex++
 				}
@@ -320,18 +473,27 @@ func (l *Lexer) Scan(source string) []token.Token {
 						Type:    l.Keywords[identifier],
 						Literal: identifier,
 						Line:    currentLine,
+						Column:  start - lineStart + 1,
+						Offset:  start,
 					})
 				} else {
 					tokens = append(tokens, token.Token{
 						Type:    token.IDENTIFIER,
 						Literal: identifier,
 						Line:    currentLine,
+						Column:  start - lineStart + 1,
+						Offset:  start,
 					})
 				}
 				currentIndex = extendedIndex
 			} else {
-				// TODO : Do some form of error handling here
-				fmt.Println("The lexer cannot handle this character : ", string(ch))
+				// Skip over the whole character, in the case that it is
+				// more than a single byte
+				_, size := utf8.DecodeRuneInString(source[start:])
+				currentIndex = start + size
+				l.illegal(source, start, currentLine, lineStart,
+					fmt.Sprintf("The lexer cannot handle this character : '%s'",
+						source[start:currentIndex]))
 			}
 		}
 	}
@@ -339,6 +501,181 @@ func (l *Lexer) Scan(source string) []token.Token {
 	return tokens
 }
 
+// Records an error for a character that cannot be lexed, the lexer then
+// carries on from the next character so that all errors can be reported.
+// Scans a quoted string that starts at the quote at start, giving back
+// the tokens of the string with its escape sequences replaced, and the
+// index right after the closing quote.
+// Unterminated strings are reported and end at the end of the line.
+//
+// Strings with ${expression} in them are split up into parts, every part
+// of the string is followed by the tokens of an expression, except for the
+// last part. The first part is an INTERPOLATION token, the ones after are
+// INTERPOLATION_MIDDLE and the last part is an INTERPOLATION_END token.
+// i.e. "a ${b} c" is INTERPOLATION("a "), IDENTIFIER(b), INTERPOLATION_END(" c")
+func (l *Lexer) scanString(source string, start int, line int,
+	lineStart int) ([]token.Token, int) {
+	quote := source[start]
+
+	var tokens []token.Token
+	var value strings.Builder
+	// Where the current part of the string starts
+	partStart := start
+	part := func(last bool) token.Token {
+		tokenType := token.TokenType(token.STRING)
+		switch {
+		case len(tokens) == 0 && !last:
+			tokenType = token.INTERPOLATION
+		case len(tokens) > 0 && !last:
+			tokenType = token.INTERPOLATION_MIDDLE
+		case len(tokens) > 0 && last:
+			tokenType = token.INTERPOLATION_END
+		}
+		return token.Token{
+			Type:    tokenType,
+			Literal: value.String(),
+			Line:    line,
+			Column:  partStart - lineStart + 1,
+			Offset:  partStart,
+		}
+	}
+
+	index := start + 1
+	for {
+		if index >= len(source) || source[index] == '\n' {
+			l.illegal(source, start, line, lineStart, "Unterminated string")
+			return append(tokens, part(true)), index
+		}
+
+		ch := source[index]
+		if ch == quote {
+			return append(tokens, part(true)), index + 1
+		}
+		if ch == '$' && index+1 < len(source) && source[index+1] == '{' {
+			closing := l.interpolationEnd(source, index+2)
+			if closing == -1 {
+				l.illegal(source, index, line, lineStart, "Unterminated string interpolation")
+				end := strings.IndexByte(source[index:], '\n')
+				if end == -1 {
+					return append(tokens, part(true)), len(source)
+				}
+				return append(tokens, part(true)), index + end
+			}
+
+			tokens = append(tokens, part(false))
+			tokens = append(tokens, l.scan(source, index+2, closing, line, lineStart)...)
+
+			value.Reset()
+			partStart = closing
+			index = closing + 1
+			continue
+		}
+		if ch != '\\' {
+			value.WriteByte(ch)
+			index++
+			continue
+		}
+
+		// Escape sequences, a '\' at the end of a line leaves the
+		// string unterminated
+		if index+1 >= len(source) || source[index+1] == '\n' {
+			index++
+			continue
+		}
+		switch source[index+1] {
+		case 'n':
+			value.WriteByte('\n')
+		case 't':
+			value.WriteByte('\t')
+		case 'r':
+			value.WriteByte('\r')
+		case '0':
+			value.WriteByte(0)
+		case '\\', '"', '\'', '`', '$':
+			value.WriteByte(source[index+1])
+		case 'u':
+			// \u{1F600}, any number of hex digits within the braces
+			end := index + 3
+			for end < len(source) && l.isHexDigit(source[end]) {
+				end++
+			}
+			if index+2 >= len(source) || source[index+2] != '{' ||
+				end >= len(source) || source[end] != '}' {
+				l.illegal(source, index, line, lineStart,
+					"Expect '{' and '}' around the hex digits of a unicode escape sequence")
+				index += 2
+				continue
+			}
+
+			codePoint, err := strconv.ParseUint(source[index+3:end], 16, 32)
+			if err != nil || !utf8.ValidRune(rune(codePoint)) {
+				l.illegal(source, index, line, lineStart,
+					fmt.Sprintf("Invalid unicode escape sequence '%s'", source[index:end+1]))
+			} else {
+				value.WriteRune(rune(codePoint))
+			}
+			index = end + 1
+			continue
+		default:
+			escaped, size := utf8.DecodeRuneInString(source[index+1:])
+			l.illegal(source, index, line, lineStart,
+				fmt.Sprintf("Invalid escape sequence '\\%c'", escaped))
+			index += 1 + size
+			continue
+		}
+		index += 2
+	}
+}
+
+// Finds the '}' that closes off an interpolated expression that starts
+// at index, skipping over any braces and strings within the expression.
+// Gives back -1 if the expression is not closed on the same line.
+func (l *Lexer) interpolationEnd(source string, index int) int {
+	depth := 1
+	for index < len(source) && source[index] != '\n' {
+		switch source[index] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return index
+			}
+		case '"', '\'', '`':
+			quote := source[index]
+			index++
+			for index < len(source) && source[index] != quote && source[index] != '\n' {
+				if source[index] == '\\' && quote != '`' &&
+					index+1 < len(source) && source[index+1] != '\n' {
+					index++
+				}
+				index++
+			}
+			if index >= len(source) || source[index] != quote {
+				return -1
+			}
+		}
+		index++
+	}
+	return -1
+}
+
+func (l *Lexer) illegal(source string, start int, line int, lineStart int, message string) {
+	_, size := utf8.DecodeRuneInString(source[start:])
+	tok := token.Token{
+		Type:    token.ILLEGAL,
+		Literal: source[start : start+size],
+		Line:    line,
+		Column:  start - lineStart + 1,
+		Offset:  start,
+	}
+	l.Errors = append(l.Errors, errors.NewSyntaxError(tok, message))
+}
+
+func (l *Lexer) isHexDigit(b byte) bool {
+	return (b >= '0' && b <= '9') || (b >= 'a' && b <= 'f') || (b >= 'A' && b <= 'F')
+}
+
 func (l *Lexer) isDigit(b byte) bool {
 	if b >= '0' && b <= '9' {
 		return true
@@ -371,12 +708,23 @@ func New() *Lexer {
 	keywords["else"] = token.ELSE
 	keywords["while"] = token.WHILE
 	keywords["for"] = token.FOR
+	keywords["in"] = token.IN
 	keywords["function"] = token.FUNCTION
 	keywords["return"] = token.RETURN
+	keywords["break"] = token.BREAK
+	keywords["continue"] = token.CONTINUE
 	keywords["true"] = token.TRUE
 	keywords["false"] = token.FALSE
 	keywords["struct"] = token.STRUCT
 	keywords["this"] = token.THIS
+	keywords["super"] = token.SUPER
+	keywords["null"] = token.NULL
+	keywords["nil"] = token.NULL
+	keywords["import"] = token.IMPORT
+	keywords["try"] = token.TRY
+	keywords["catch"] = token.CATCH
+	keywords["finally"] = token.FINALLY
+	keywords["throw"] = token.THROW
 
 	l := &Lexer{
 		Keywords: keywords,
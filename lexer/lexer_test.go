@@ -55,6 +55,10 @@ func TestIndividualTokenScan(t *testing.T) {
 		{"1", token.NUMBER, "1"},
 		{"12", token.NUMBER, "12"},
 		{"091283", token.NUMBER, "091283"},
+		{"3.14", token.FLOAT, "3.14"},
+		{"1e-9", token.FLOAT, "1e-9"},
+		{"2.5E+3", token.FLOAT, "2.5E+3"},
+		{"10e5", token.FLOAT, "10e5"},
 
 		// Identifiers
 		{"abc", token.IDENTIFIER, "abc"},
@@ -71,9 +75,17 @@ func TestIndividualTokenScan(t *testing.T) {
 		{"else", token.ELSE, "else"},
 		{"while", token.WHILE, "while"},
 		{"for", token.FOR, "for"},
+		{"in", token.IN, "in"},
 		{"function", token.FUNCTION, "function"},
+		{"break", token.BREAK, "break"},
+		{"continue", token.CONTINUE, "continue"},
 		{"struct", token.STRUCT, "struct"},
 		{"this", token.THIS, "this"},
+		{"super", token.SUPER, "super"},
+		{"null", token.NULL, "null"},
+		{"nil", token.NULL, "nil"},
+		{"import", token.IMPORT, "import"},
+		{"from", token.IDENTIFIER, "from"},
 	}
 
 	lexer := New()
@@ -118,6 +130,12 @@ func TestMultipleTokenScan(t *testing.T) {
 			[]token.TokenType{token.NUMBER, token.PLUS, token.NUMBER},
 			[]string{"123", "+", "45"},
 		},
+		{ // A '.' that is not followed by a digit is not part of the number
+			`1.5 + 2.a 3e`,
+			[]token.TokenType{token.FLOAT, token.PLUS, token.NUMBER, token.DOT,
+				token.IDENTIFIER, token.NUMBER, token.IDENTIFIER},
+			[]string{"1.5", "+", "2", ".", "a", "3", "e"},
+		},
 		{
 			// Numbers + Comparison Operators + Operators + Delimiters
 			`(123 >= 45) + (45 * 2)`,
@@ -210,6 +228,24 @@ func TestMultipleTokenScan(t *testing.T) {
 				token.SEMICOLON, token.RBRACE},
 			[]string{"struct", "Test", "{", "var", "a", ";", "}"},
 		},
+		{ // Interpolated strings are split up around their expressions
+			`"a ${b + 1} c ${d} e"`,
+			[]token.TokenType{token.INTERPOLATION, token.IDENTIFIER, token.PLUS, token.NUMBER,
+				token.INTERPOLATION_MIDDLE, token.IDENTIFIER, token.INTERPOLATION_END},
+			[]string{"a ", "b", "+", "1", " c ", "d", " e"},
+		},
+		{ // Braces and strings within an interpolated expression
+			`'${ {"}": 1}["}"] }'`,
+			[]token.TokenType{token.INTERPOLATION, token.LBRACE, token.STRING, token.COLON,
+				token.NUMBER, token.RBRACE, token.LBRACKET, token.STRING, token.RBRACKET,
+				token.INTERPOLATION_END},
+			[]string{"", "{", "}", ":", "1", "}", "[", "}", "]", ""},
+		},
+		{ // Escaped interpolation
+			`"\${a}"`,
+			[]token.TokenType{token.STRING},
+			[]string{"${a}"},
+		},
 	}
 
 	lexer := New()
@@ -239,3 +275,139 @@ func TestMultipleTokenScan(t *testing.T) {
 		}
 	}
 }
+
+func TestTokenPositions(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedLines   []int
+		expectedColumns []int
+		expectedOffsets []int
+	}{
+		{
+			`var a = 10;`,
+			[]int{1, 1, 1, 1, 1},
+			[]int{1, 5, 7, 9, 11},
+			[]int{0, 4, 6, 8, 10},
+		},
+		{
+			"a;\n  b += 2;",
+			[]int{1, 1, 2, 2, 2, 2},
+			[]int{1, 2, 3, 5, 8, 9},
+			[]int{0, 1, 5, 7, 10, 11},
+		},
+		{
+			"\"hi\" == x",
+			[]int{1, 1, 1},
+			[]int{1, 6, 9},
+			[]int{0, 5, 8},
+		},
+		{ // Tokens within interpolated strings keep their positions
+			"x = \"a ${b} c\";",
+			[]int{1, 1, 1, 1, 1, 1},
+			[]int{1, 3, 5, 10, 11, 15},
+			[]int{0, 2, 4, 9, 10, 14},
+		},
+		{ // Tokens after a multi-line raw string are on the line it ends on
+			"`a\nbc` x\ny",
+			[]int{1, 2, 3},
+			[]int{1, 5, 1},
+			[]int{0, 7, 9},
+		},
+	}
+
+	lexer := New()
+	for i, test := range tests {
+		tokens := lexer.Scan(test.input)
+
+		if len(tokens) != len(test.expectedColumns) {
+			t.Fatalf("Test : [%d] - Mismatch amount of scanned tokens, expected=%d, got=%d",
+				i, len(test.expectedColumns), len(tokens))
+		}
+
+		for j, token := range tokens {
+			if token.Line != test.expectedLines[j] {
+				t.Fatalf("Test : [%d - %d] - Wrong Line, expected=%d, got=%d",
+					i, j, test.expectedLines[j], token.Line)
+			}
+			if token.Column != test.expectedColumns[j] {
+				t.Fatalf("Test : [%d - %d] - Wrong Column, expected=%d, got=%d",
+					i, j, test.expectedColumns[j], token.Column)
+			}
+			if token.Offset != test.expectedOffsets[j] {
+				t.Fatalf("Test : [%d - %d] - Wrong Offset, expected=%d, got=%d",
+					i, j, test.expectedOffsets[j], token.Offset)
+			}
+		}
+	}
+}
+
+func TestStringScan(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedValue string
+	}{
+		{`"hello"`, "hello"},
+		{`'hello'`, "hello"},
+		{`"a\nb\tc\rd"`, "a\nb\tc\rd"},
+		{`"say \"hi\""`, `say "hi"`},
+		{`'it\'s'`, "it's"},
+		{`'"' + "'"`, `"`},
+		{`"back\\slash"`, `back\slash`},
+		{`"\u{e9}\u{1F600}"`, "é😀"},
+		{"`raw \\n \"string\"`", `raw \n "string"`},
+		{"`multi\nline`", "multi\nline"},
+		{`"héllo"`, "héllo"},
+	}
+
+	lexer := New()
+	for i, test := range tests {
+		tokens := lexer.Scan(test.input)
+
+		if len(lexer.Errors) > 0 {
+			t.Fatalf("Test : [%d] - Unexpected error : %s", i, lexer.Errors[0].Error())
+		}
+		if tokens[0].Type != token.STRING {
+			t.Fatalf("Test : [%d] - Wrong TokenType, expected=%q, got=%q",
+				i, token.STRING, tokens[0].Type)
+		}
+		if tokens[0].Literal != test.expectedValue {
+			t.Fatalf("Test : [%d] - Wrong value, expected=%q, got=%q",
+				i, test.expectedValue, tokens[0].Literal)
+		}
+	}
+}
+
+func TestStringErrors(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+		expectedLine    int
+		expectedColumn  int
+	}{
+		{"var a = \"abc;\nvar b = 1;", "Unterminated string", 1, 9},
+		{"'abc", "Unterminated string", 1, 1},
+		{"a\n  `abc", UNTERMINATED_RAW_STRING, 2, 3},
+		{`"\q"`, "Invalid escape sequence '\\q'", 1, 2},
+		{`"ab\u{110000}"`, "Invalid unicode escape sequence '\\u{110000}'", 1, 4},
+		{`"${a"`, "Unterminated string interpolation", 1, 2},
+		{`"\u00e9"`, "Expect '{' and '}' around the hex digits of a unicode escape sequence", 1, 2},
+	}
+
+	lexer := New()
+	for i, test := range tests {
+		lexer.Scan(test.input)
+
+		if len(lexer.Errors) != 1 {
+			t.Fatalf("Test : [%d] - Expected one error, got=%d", i, len(lexer.Errors))
+		}
+		err := lexer.Errors[0]
+		if err.Message() != test.expectedMessage {
+			t.Fatalf("Test : [%d] - Wrong message, expected=%q, got=%q",
+				i, test.expectedMessage, err.Message())
+		}
+		if err.Span().Line != test.expectedLine || err.Span().Column != test.expectedColumn {
+			t.Fatalf("Test : [%d] - Wrong position, expected=%d:%d, got=%d:%d",
+				i, test.expectedLine, test.expectedColumn, err.Span().Line, err.Span().Column)
+		}
+	}
+}
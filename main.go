@@ -1,41 +1,81 @@
 package main
 
 import (
+	"encoding/json"
+	"flag"
 	"fmt"
 	"io/ioutil"
 	"os"
-	"runtime"
 
 	"github.com/lczm/as/analysis"
-	"github.com/lczm/as/globals"
+	"github.com/lczm/as/ast"
+	"github.com/lczm/as/dap"
+	"github.com/lczm/as/debugger"
+	"github.com/lczm/as/errors"
 	"github.com/lczm/as/interpreter"
 	"github.com/lczm/as/lexer"
+	"github.com/lczm/as/object"
 	"github.com/lczm/as/parser"
+	"github.com/lczm/as/repl"
+	"github.com/lczm/as/testrunner"
+	"github.com/lczm/as/vm"
 )
 
 func main() {
-	// If there are no arguments passed into the binary, don't prompt a help
-	// message and exit from the program, don't continue to do anything
-	if len(os.Args) == 1 {
-		userOs := runtime.GOOS
-		fmt.Println("No files found.")
-		switch userOs {
-		case "windows": // Windows
-			fmt.Println("Usage: as {file}")
-		default: // Mac, Linux
-			fmt.Println("Usage: ./as {file}")
+	errorFormat := flag.String("error-format", "text",
+		"How errors are reported, either 'text' or 'json'")
+	useVM := flag.Bool("vm", false, "Run the file on the bytecode vm instead of the tree-walking interpreter")
+	flag.Parse()
+
+	// If there are no arguments passed into the binary, start up an
+	// interactive session instead of running a file
+	if flag.NArg() == 0 {
+		fmt.Println("as - type 'exit' to quit")
+		repl.Start(os.Stdin, os.Stdout)
+		os.Exit(0)
+	}
+
+	// 'as test [paths]' runs the tests under the paths
+	if flag.Arg(0) == "test" {
+		runner := testrunner.New(os.Stdout)
+		if err := runner.Run(flag.Args()[1:]); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if runner.Failed > 0 {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	// 'as dap' is a debug adapter for editors, over stdin and stdout
+	if flag.Arg(0) == "dap" {
+		server := dap.New(os.Stdin, os.Stdout)
+		if err := server.Run(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
 		}
 		os.Exit(0)
 	}
 
+	// 'as debug file' runs the file under the debugger
+	debug := flag.Arg(0) == "debug"
+
 	// Grab all the arguments
-	arguments := os.Args[1:]
-	if len(arguments) > 1 {
+	arguments := flag.Args()
+	if debug {
+		arguments = arguments[1:]
+	}
+	if len(arguments) != 1 {
 		os.Exit(1)
 	}
 
 	name := arguments[0]
-	data, _ := ioutil.ReadFile(name)
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
 
 	input := string(data)
 
@@ -51,27 +91,91 @@ func main() {
 	semanticAnalyzer := analysis.New(statements)
 	semanticAnalyzer.Analyze()
 
+	errorList := append(lexer.Errors, parser.Errors...)
+	errorList = append(errorList, semanticAnalyzer.Errors...)
+
 	// TODO : if it is more than 0, and there is some form of strict flag
 	// this should not continue running
-	if len(globals.ErrorList) > 0 {
+	if len(errorList) > 0 {
 		// If there are any errors that are detected
-		for _, error := range globals.ErrorList {
-			error.Describe()
-		}
+		report(input, *errorFormat, errorList)
 		// TODO : Find the correct error code to exit from an error
 		os.Exit(1)
 	}
 
 	// TODO : Some form of flag to determine whether this should be continued or not
-	if len(globals.WarningList) > 0 {
-		for _, warning := range globals.WarningList {
-			warning.Describe()
-		}
+	if len(semanticAnalyzer.Warnings) > 0 {
+		report(input, *errorFormat, semanticAnalyzer.Warnings)
 		// If there is a flag to determine that this should not be continued;
 		// then this should exited
 		// os.Exit(1)
 	}
 
+	var trace []object.Frame
+	if debug {
+		trace, err = runDebugger(statements, name, input)
+	} else {
+		trace, err = run(statements, name, *useVM)
+	}
+	// Quitting the debugger stops the program without it having failed
+	if _, stopped := err.(errors.Stopped); stopped {
+		return
+	}
+	if err != nil {
+		runtimeError, ok := err.(errors.Error)
+		if !ok {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if *errorFormat == "text" {
+			fmt.Print(object.Traceback(trace))
+		}
+		report(input, *errorFormat, []errors.Error{runtimeError})
+		os.Exit(1)
+	}
+}
+
+// Runs the statements of a file on either the vm or the tree-walking
+// interpreter, the repl always uses the interpreter. The calls that
+// a runtime error was raised within are given back with it.
+func run(statements []ast.Statement, name string, useVM bool) ([]object.Frame, error) {
+	if useVM {
+		machine := vm.New(statements)
+		machine.File = name
+		err := machine.Start()
+		return machine.Trace, err
+	}
+
 	interpreter := interpreter.New(statements)
-	interpreter.Start()
+	interpreter.File = name
+	err := interpreter.Start()
+	return interpreter.Trace, err
+}
+
+// Debugging is always done on the tree-walking interpreter
+func runDebugger(statements []ast.Statement, name string, input string) ([]object.Frame, error) {
+	interpreter := interpreter.New(statements)
+	interpreter.File = name
+	interpreter.Debugger = debugger.New(os.Stdin, os.Stdout, name, input)
+	err := interpreter.Start()
+	return interpreter.Trace, err
+}
+
+// Reports errors either as text with the source line underlined,
+// or as JSON (to stderr) for tooling to consume.
+func report(source string, format string, errorList []errors.Error) {
+	if format == "json" {
+		reports := make([]errors.Report, 0, len(errorList))
+		for _, error := range errorList {
+			reports = append(reports, errors.NewReport(error))
+		}
+		encoded, _ := json.Marshal(reports)
+		fmt.Fprintln(os.Stderr, string(encoded))
+		return
+	}
+
+	for _, error := range errorList {
+		error.Describe()
+		fmt.Print(errors.Excerpt(source, error.Span()))
+	}
 }
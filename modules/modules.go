@@ -0,0 +1,144 @@
+// Package modules finds, parses and keeps track of the files that are
+// imported. Running them is left to the interpreter or the vm that is
+// importing them.
+package modules
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lczm/as/ast"
+	"github.com/lczm/as/errors"
+	"github.com/lczm/as/lexer"
+	"github.com/lczm/as/object"
+	"github.com/lczm/as/parser"
+	"github.com/lczm/as/token"
+)
+
+// Runs the statements of the module at path, giving back what the
+// module declares
+type Run func(path string, statements []ast.Statement) (map[string]object.Object, error)
+
+// Every file is only run once no matter how many times it is imported,
+// this is shared between what runs the program and what runs each of
+// the modules.
+type Modules struct {
+	// Modules that have finished running, by their absolute path
+	loaded map[string]*object.Module
+	// Absolute paths of the files that are still running, in the order that
+	// they were imported. Importing one of these again is an import cycle.
+	loading []string
+}
+
+func New() *Modules {
+	return &Modules{
+		loaded:  make(map[string]*object.Module),
+		loading: make([]string, 0),
+	}
+}
+
+// Imports the module at importPath for the file that is importing it,
+// tok is the path of the import.
+func (m *Modules) Import(file string, importPath string, tok token.Token, run Run) object.Object {
+	path, ok := Resolve(file, importPath)
+	if !ok {
+		return newError(tok, "Cannot find module \"%s\"", importPath)
+	}
+
+	if module, ok := m.loaded[path]; ok {
+		return module
+	}
+
+	// The file that the program started from is running as well
+	if len(m.loading) == 0 && file != "" {
+		if absolute, err := filepath.Abs(file); err == nil {
+			m.loading = append(m.loading, absolute)
+		}
+	}
+	for index, loading := range m.loading {
+		if loading == path {
+			cycle := make([]string, 0)
+			for _, file := range m.loading[index:] {
+				cycle = append(cycle, filepath.Base(file))
+			}
+			cycle = append(cycle, filepath.Base(path))
+			return newError(tok, "Import cycle : %s", strings.Join(cycle, " -> "))
+		}
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return newError(tok, "Cannot read module \"%s\"", importPath)
+	}
+
+	// Syntax errors in the module are reported through the import instead
+	// of alongside the errors of the file that is importing it
+	lexer := lexer.New()
+	tokens := lexer.Scan(string(data))
+	parser := parser.New(tokens)
+	statements := parser.Parse()
+	if syntaxErrors := append(lexer.Errors, parser.Errors...); len(syntaxErrors) > 0 {
+		return newError(tok, "Error in \"%s\" : %s", importPath, syntaxErrors[0].Error())
+	}
+
+	m.loading = append(m.loading, path)
+	values, err := run(path, statements)
+	m.loading = m.loading[:len(m.loading)-1]
+	if err != nil {
+		// Errors that stop the program, i.e. going over a limit, are
+		// given back as they are so that they cannot be caught
+		if runtimeError, ok := err.(errors.Error); ok {
+			if errorObj := (&object.Error{Err: runtimeError}); !errorObj.Catchable() {
+				return errorObj
+			}
+		}
+		return newError(tok, "Error in \"%s\" : %s", importPath, err.Error())
+	}
+
+	module := &object.Module{
+		Name:   filepath.Base(path),
+		Path:   path,
+		Values: values,
+	}
+	m.loaded[path] = module
+	return module
+}
+
+// Imports are looked for relative to the file that is importing them,
+// then in each of the directories listed in AS_PATH.
+// Gives back the absolute path of the file that was found.
+func Resolve(file string, path string) (string, bool) {
+	candidates := make([]string, 0)
+	if filepath.IsAbs(path) {
+		candidates = append(candidates, path)
+	} else {
+		candidates = append(candidates, filepath.Join(filepath.Dir(file), path))
+		for _, directory := range filepath.SplitList(os.Getenv("AS_PATH")) {
+			if directory != "" {
+				candidates = append(candidates, filepath.Join(directory, path))
+			}
+		}
+	}
+
+	for _, candidate := range candidates {
+		info, err := os.Stat(candidate)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		absolute, err := filepath.Abs(candidate)
+		if err != nil {
+			continue
+		}
+		return absolute, true
+	}
+	return "", false
+}
+
+func newError(tok token.Token, format string, a ...interface{}) *object.Error {
+	return &object.Error{
+		Err: errors.NewRuntimeError(tok, fmt.Sprintf(format, a...)),
+	}
+}
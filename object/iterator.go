@@ -0,0 +1,191 @@
+package object
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Iterators hand out the elements of a container one at a time,
+// this is what `for (x in container)` loops over.
+// Next gives back the key and value of the next element, and false
+// once there are no elements left. Iterators that run code to produce
+// their elements (i.e. structs) give back an *Error as the value if
+// that fails.
+type Iterator interface {
+	Next() (Object, Object, bool)
+}
+
+// All objects that can be looped over implement this interface
+type Iterable interface {
+	Iterator() Iterator
+}
+
+// Lists give back their index and element
+type listIterator struct {
+	list  *List
+	index int
+}
+
+func (li *listIterator) Next() (Object, Object, bool) {
+	if li.index >= len(li.list.Value) {
+		return nil, nil, false
+	}
+	key := &Integer{Value: int64(li.index)}
+	value := li.list.Value[li.index]
+	li.index++
+	return key, value, true
+}
+
+func (l *List) Iterator() Iterator {
+	return &listIterator{list: l}
+}
+
+// Strings give back their index and every character, characters
+// are utf-8 decoded and not split into bytes
+type stringIterator struct {
+	runes []rune
+	index int
+}
+
+func (si *stringIterator) Next() (Object, Object, bool) {
+	if si.index >= len(si.runes) {
+		return nil, nil, false
+	}
+	key := &Integer{Value: int64(si.index)}
+	value := &String{Value: string(si.runes[si.index])}
+	si.index++
+	return key, value, true
+}
+
+func (s *String) Iterator() Iterator {
+	return &stringIterator{runes: []rune(s.Value)}
+}
+
+// Hashmaps give back their keys and values, in the same order as Pairs()
+type hashMapIterator struct {
+	pairs []HashValue
+	index int
+}
+
+func (hi *hashMapIterator) Next() (Object, Object, bool) {
+	if hi.index >= len(hi.pairs) {
+		return nil, nil, false
+	}
+	pair := hi.pairs[hi.index]
+	hi.index++
+	return pair.Key, pair.Value, true
+}
+
+func (hm *HashMap) Iterator() Iterator {
+	return &hashMapIterator{pairs: hm.Pairs()}
+}
+
+// Gives back all the key and value pairs of a hashmap sorted by their
+// keys, so that iterating and printing a hashmap is always done in the
+// same order. Numbers come first, then strings, then everything else.
+func (hm *HashMap) Pairs() []HashValue {
+	pairs := make([]HashValue, 0, len(hm.Value))
+	for _, pair := range hm.Value {
+		pairs = append(pairs, pair)
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		return keyLess(pairs[i].Key, pairs[j].Key)
+	})
+	return pairs
+}
+
+func keyLess(a Object, b Object) bool {
+	aRank, bRank := keyRank(a), keyRank(b)
+	if aRank != bRank {
+		return aRank < bRank
+	}
+
+	switch a := a.(type) {
+	case *Integer:
+		if b, ok := b.(*Integer); ok {
+			return a.Value < b.Value
+		}
+		return float64(a.Value) < b.(*Float).Value
+	case *Float:
+		if b, ok := b.(*Integer); ok {
+			return a.Value < float64(b.Value)
+		}
+		return a.Value < b.(*Float).Value
+	case *String:
+		return a.Value < b.(*String).Value
+	}
+	return a.FormattedString() < b.FormattedString()
+}
+
+func keyRank(obj Object) int {
+	switch obj.(type) {
+	case *Integer, *Float:
+		return 0
+	case *String:
+		return 1
+	}
+	return 2
+}
+
+// A range of integers that is produced lazily, from Start up to but not
+// including End, i.e. range(0, 10, 2) gives back 0, 2, 4, 6, 8.
+// Step can be negative to count downwards.
+type Range struct {
+	Start int64
+	End   int64
+	Step  int64
+}
+
+func (r *Range) RawType() string {
+	return RANGE
+}
+
+func (r *Range) Type() string {
+	return fmt.Sprintf("<type: %s>", RANGE)
+}
+
+func (r *Range) String() string {
+	return fmt.Sprintf("range(%d, %d, %d)", r.Start, r.End, r.Step)
+}
+
+func (r *Range) FormattedString() string {
+	return r.String()
+}
+
+// The number of integers in the range, range() does not make
+// ranges that have more than fit in an int64
+func (r *Range) Len() int64 {
+	return int64(r.Size())
+}
+
+// The number of integers in the range, worked out in uint64 as the
+// distance between the start and end can be more than fits in an int64
+func (r *Range) Size() uint64 {
+	if r.Step > 0 && r.Start < r.End {
+		return (uint64(r.End)-uint64(r.Start)-1)/uint64(r.Step) + 1
+	}
+	if r.Step < 0 && r.Start > r.End {
+		return (uint64(r.Start)-uint64(r.End)-1)/(^uint64(r.Step)+1) + 1
+	}
+	return 0
+}
+
+// Ranges give back the position in the range and the integer
+type rangeIterator struct {
+	r     *Range
+	index int64
+}
+
+func (ri *rangeIterator) Next() (Object, Object, bool) {
+	if ri.index >= ri.r.Len() {
+		return nil, nil, false
+	}
+	key := &Integer{Value: ri.index}
+	value := &Integer{Value: ri.r.Start + ri.index*ri.r.Step}
+	ri.index++
+	return key, value, true
+}
+
+func (r *Range) Iterator() Iterator {
+	return &rangeIterator{r: r}
+}
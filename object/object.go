@@ -3,21 +3,35 @@ package object
 import (
 	"fmt"
 	"hash/fnv"
+	"math"
+	"strconv"
+	"strings"
 
 	"github.com/lczm/as/ast"
+	"github.com/lczm/as/errors"
+	"github.com/lczm/as/token"
 )
 
 // Types
 const (
 	BOOL     = "BOOL"
 	INTEGER  = "INTEGER"
+	FLOAT    = "FLOAT"
+	NULL     = "NULL"
 	FUNCTION = "FUNCTION"
 	STRUCT   = "STRUCT"
 	RETURN   = "RETURN"
+	BREAK    = "BREAK"
+	CONTINUE = "CONTINUE"
 	STRING   = "STRING"
 	BUILTIN  = "BULITIN" // builtin functions from the host language
 	LIST     = "LIST"
 	HASHMAP  = "HASHMAP"
+	RANGE    = "RANGE"
+	MODULE   = "MODULE"
+	ERROR    = "ERROR"
+	// Errors that have been caught
+	EXCEPTION = "EXCEPTION"
 )
 
 // All types implement this interface
@@ -30,6 +44,84 @@ type Object interface {
 	FormattedString() string
 }
 
+// This allows builtin functions to call functions that are passed
+// into them, i.e. sort(list, function(a, b) { return a < b; })
+// This is implemented by the interpreter.
+type Caller interface {
+	CallFunction(function Object, arguments []Object) Object
+	// The calls that are running, outermost first, for callstack()
+	CallStack() []Frame
+}
+
+// A call to a function that is running. Line and File are where the call
+// is, Line is 0 for functions that are not called from the script itself,
+// i.e. by map(), and File is empty when the code is not from a file.
+type Frame struct {
+	Function string
+	Line     int
+	File     string
+}
+
+// i.e. f() called from main.as, line 3
+func (f Frame) String() string {
+	if f.Line == 0 {
+		return fmt.Sprintf("%s()", f.Function)
+	}
+	if f.File == "" {
+		return fmt.Sprintf("%s() called from line %d", f.Function, f.Line)
+	}
+	return fmt.Sprintf("%s() called from %s, line %d", f.Function, f.File, f.Line)
+}
+
+// How many lines of a traceback are shown at either end of it, the
+// lines in between are left out
+const TRACEBACK_LIMIT = 10
+
+// The calls that an error was raised within, one to a line after a
+// heading, or nothing if there are none i.e. errors at the top level.
+// A call that is repeated one after another, i.e. in recursion, is only
+// shown once, and only the ends of long tracebacks are shown.
+func Traceback(trace []Frame) string {
+	if len(trace) == 0 {
+		return ""
+	}
+
+	// Every line and how many of the calls it stands for
+	lines := make([]string, 0)
+	calls := make([]int, 0)
+	for index := 0; index < len(trace); {
+		end := index + 1
+		for end < len(trace) && trace[end] == trace[index] {
+			end++
+		}
+		lines = append(lines, "  "+trace[index].String())
+		calls = append(calls, 1)
+		if repeated := end - index - 1; repeated > 0 {
+			lines = append(lines, fmt.Sprintf("  ... repeated %d more times", repeated))
+			calls = append(calls, repeated)
+		}
+		index = end
+	}
+
+	if len(lines) > 2*TRACEBACK_LIMIT {
+		hidden := 0
+		for _, count := range calls[TRACEBACK_LIMIT : len(lines)-TRACEBACK_LIMIT] {
+			hidden += count
+		}
+		shown := make([]string, 0, 2*TRACEBACK_LIMIT+1)
+		shown = append(shown, lines[:TRACEBACK_LIMIT]...)
+		shown = append(shown, fmt.Sprintf("  ... %d more calls", hidden))
+		lines = append(shown, lines[len(lines)-TRACEBACK_LIMIT:]...)
+	}
+
+	var out strings.Builder
+	out.WriteString("Traceback (most recent call last):\n")
+	for _, line := range lines {
+		out.WriteString(line + "\n")
+	}
+	return out.String()
+}
+
 // All the call-able objects will implement this interface
 // i.e. functions
 type Callable interface {
@@ -93,6 +185,29 @@ func (b *Bool) Hash() HashKey {
 	}
 }
 
+// Null type, the absence of a value.
+// There is only ever one null value, NullValue, so that it can be
+// compared by its type.
+type Null struct{}
+
+var NullValue = &Null{}
+
+func (n *Null) RawType() string {
+	return NULL
+}
+
+func (n *Null) Type() string {
+	return fmt.Sprintf("<type: %s>", NULL)
+}
+
+func (n *Null) String() string {
+	return "null"
+}
+
+func (n *Null) FormattedString() string {
+	return "null"
+}
+
 // Integer type
 type Integer struct {
 	Value int64
@@ -119,6 +234,46 @@ func (i *Integer) Hash() HashKey {
 		Value: int(i.Value)}
 }
 
+// Float type
+type Float struct {
+	Value float64
+}
+
+func (f *Float) RawType() string {
+	return FLOAT
+}
+
+func (f *Float) Type() string {
+	return fmt.Sprintf("<type: %s>", FLOAT)
+}
+
+// Floats are printed in their shortest form, but always with a
+// decimal point or exponent so that they can be told apart from
+// integers, i.e. 2.0, 0.1, 1e-09
+func (f *Float) String() string {
+	s := strconv.FormatFloat(f.Value, 'g', -1, 64)
+	if math.IsInf(f.Value, 0) || math.IsNaN(f.Value) ||
+		strings.ContainsAny(s, ".e") {
+		return s
+	}
+	return s + ".0"
+}
+
+func (f *Float) FormattedString() string {
+	return f.String()
+}
+
+// Floats that hold a whole number hash the same as the integer
+// of the same value, so that 1 and 1.0 are the same hashmap key.
+func (f *Float) Hash() HashKey {
+	if f.Value == math.Trunc(f.Value) && !math.IsInf(f.Value, 0) {
+		return HashKey{Type: INTEGER, Value: int(f.Value)}
+	}
+
+	return HashKey{Type: f.RawType(),
+		Value: int(math.Float64bits(f.Value))}
+}
+
 // String type
 type String struct {
 	Value string
@@ -136,8 +291,9 @@ func (s *String) String() string {
 	return s.Value
 }
 
+// Quoted, with any special characters escaped
 func (s *String) FormattedString() string {
-	return "\"" + s.Value + "\""
+	return strconv.Quote(s.Value)
 }
 
 func (s *String) Hash() HashKey {
@@ -148,9 +304,23 @@ func (s *String) Hash() HashKey {
 		Value: int(hash.Sum64())}
 }
 
+// The environment that a function is declared in, this is implemented by
+// environment.Environment, which cannot be referred to directly here as
+// the environment package depends on this one.
+type Scope interface {
+	Define(name string, value Object)
+	Get(name string) Object
+}
+
 // Function type, it is an Object as well as a Callable
+// Closure is the scope that the function was declared in, calls to the
+// function are evaluated in a child of it.
 type Function struct {
 	FunctionStatement ast.FunctionStatement
+	Closure           Scope
+	// The file that the function is declared in, calls within it are from
+	// this file even when it is called from another
+	File string
 }
 
 func (f *Function) RawType() string {
@@ -162,11 +332,19 @@ func (f *Function) Type() string {
 }
 
 func (f *Function) String() string {
-	return fmt.Sprintf("Function : <%s>", f.FunctionStatement.Name.Literal)
+	return fmt.Sprintf("Function : <%s>", f.Name())
 }
 
 func (f *Function) FormattedString() string {
-	return fmt.Sprintf("Function : <%s>", f.FunctionStatement.Name.Literal)
+	return fmt.Sprintf("Function : <%s>", f.Name())
+}
+
+// Anonymous functions do not have a name
+func (f *Function) Name() string {
+	if f.FunctionStatement.Name.Literal == "" {
+		return "anonymous"
+	}
+	return f.FunctionStatement.Name.Literal
 }
 
 // The call functions should return an object
@@ -174,9 +352,14 @@ func (f *Function) FormattedString() string {
 // var x = function(...)
 func (f *Function) Call() {}
 
+// Size is set on builtins that can build something large, it gives back
+// roughly how many bytes Fn will allocate for the arguments, so that the
+// memory limit can be checked before anything is built. Arguments that
+// Fn would reject are sized as 0.
 type BuiltinFunction struct {
 	Name string
 	Fn   func(args ...Object) Object
+	Size func(args ...Object) int
 }
 
 func (bf *BuiltinFunction) RawType() string {
@@ -195,11 +378,33 @@ func (bf *BuiltinFunction) FormattedString() string {
 	return fmt.Sprintf("BulitinFunction: <%s>", bf.Name)
 }
 
+// Both struct declarations and their instances are a Struct.
+// Attribute initializers are evaluated in the closure for every new
+// instance, so that instances do not share their default values.
+// The vm compiles the initializers into Initialize instead, a method
+// that sets the attributes of the instance it is called on.
+// Instance is set on instances, which cannot be called like their struct.
 type Struct struct {
-	Name       string
-	HasInit    bool
-	Attributes map[string]Object
-	Methods    map[string]Object
+	Name         string
+	Parent       *Struct
+	HasInit      bool
+	Attributes   map[string]Object
+	Methods      map[string]Object
+	Initializers map[string]ast.Expression
+	Closure      Scope
+	Initialize   Object
+	Instance     bool
+}
+
+// Looks up a method on the struct, and then on its parents
+func (s *Struct) FindMethod(name string) (Object, bool) {
+	for current := s; current != nil; current = current.Parent {
+		method, ok := current.Methods[name]
+		if ok {
+			return method, true
+		}
+	}
+	return nil, false
 }
 
 func (s *Struct) RawType() string {
@@ -218,6 +423,30 @@ func (s *Struct) FormattedString() string {
 	return fmt.Sprintf("Struct: <%s>", s.Name)
 }
 
+// Modules are the files brought in with `import`, Values are everything
+// that was declared at the top level of the file, accessed by module.name
+type Module struct {
+	Name   string
+	Path   string
+	Values map[string]Object
+}
+
+func (m *Module) RawType() string {
+	return MODULE
+}
+
+func (m *Module) Type() string {
+	return fmt.Sprintf("Module: <%s>", MODULE)
+}
+
+func (m *Module) String() string {
+	return fmt.Sprintf("Module: <%s>", m.Name)
+}
+
+func (m *Module) FormattedString() string {
+	return fmt.Sprintf("Module: <%s>", m.Name)
+}
+
 // Return type, this is only for the interpreter and is not for use normally.
 type Return struct {
 	Value Object
@@ -239,6 +468,138 @@ func (r *Return) FormattedString() string {
 	return r.Value.String()
 }
 
+// Break and Continue are only for the interpreter, they are passed back
+// up out of blocks the same way as Return, until they reach the loop.
+type Break struct{}
+
+func (b *Break) RawType() string {
+	return BREAK
+}
+
+func (b *Break) Type() string {
+	return fmt.Sprintf("Break: <%s>", BREAK)
+}
+
+func (b *Break) String() string {
+	return "break"
+}
+
+func (b *Break) FormattedString() string {
+	return "break"
+}
+
+type Continue struct{}
+
+func (c *Continue) RawType() string {
+	return CONTINUE
+}
+
+func (c *Continue) Type() string {
+	return fmt.Sprintf("Continue: <%s>", CONTINUE)
+}
+
+func (c *Continue) String() string {
+	return "continue"
+}
+
+func (c *Continue) FormattedString() string {
+	return "continue"
+}
+
+// Error type, this is only for the interpreter, runtime errors are wrapped
+// in this so that they can be passed back up the same way as Return.
+type Error struct {
+	Err errors.Error
+	// What was thrown with `throw`, nil for errors raised by the interpreter
+	Value Object
+	// The calls that the error was raised within, outermost first
+	Trace []Frame
+}
+
+// Going over a limit, or being stopped by the debugger, has to stop
+// the program, so these cannot be caught
+func (e *Error) Catchable() bool {
+	switch e.Err.(type) {
+	case errors.LimitExceeded, errors.Stopped:
+		return false
+	}
+	return true
+}
+
+func (e *Error) RawType() string {
+	return ERROR
+}
+
+func (e *Error) Type() string {
+	return fmt.Sprintf("<type: %s>", ERROR)
+}
+
+func (e *Error) String() string {
+	return e.Err.Error()
+}
+
+func (e *Error) FormattedString() string {
+	return e.Err.Error()
+}
+
+// An error that has been caught with try/catch, its attributes are
+// e.message, e.kind, e.line, e.column and e.value
+type Exception struct {
+	Err   errors.Error
+	Value Object
+}
+
+// Catching an error turns it into an exception, throwing the
+// exception again raises the same error
+func NewException(errorObj *Error) *Exception {
+	value := errorObj.Value
+	if value == nil {
+		value = NullValue
+	}
+	return &Exception{Err: errorObj.Err, Value: value}
+}
+
+// What `throw value;` raises, tok is the throw keyword. Values that
+// are not strings are thrown with their string as the message.
+func Throw(value Object, tok token.Token) *Error {
+	if exception, ok := value.(*Exception); ok {
+		return &Error{Err: exception.Err, Value: exception.Value}
+	}
+	return &Error{Err: errors.NewThrownError(tok, value.String()), Value: value}
+}
+
+func (e *Exception) Attribute(name string) (Object, bool) {
+	switch name {
+	case "message":
+		return &String{Value: e.Err.Message()}, true
+	case "kind":
+		return &String{Value: e.Err.Kind()}, true
+	case "line":
+		return &Integer{Value: int64(e.Err.Span().Line)}, true
+	case "column":
+		return &Integer{Value: int64(e.Err.Span().Column)}, true
+	case "value":
+		return e.Value, true
+	}
+	return nil, false
+}
+
+func (e *Exception) RawType() string {
+	return EXCEPTION
+}
+
+func (e *Exception) Type() string {
+	return fmt.Sprintf("<type: %s>", EXCEPTION)
+}
+
+func (e *Exception) String() string {
+	return e.Err.Error()
+}
+
+func (e *Exception) FormattedString() string {
+	return e.Err.Error()
+}
+
 // Container types - Lists/Hashmaps
 // List container type
 type List struct {
@@ -254,27 +615,26 @@ func (l *List) Type() string {
 }
 
 func (l *List) String() string {
-	var valueStrings []string
-	for i := 0; i < len(l.Value); i++ {
-		if i == len(l.Value)-1 {
-			valueStrings = append(valueStrings, fmt.Sprintf("%s", l.Value[i].String()))
-		} else {
-			valueStrings = append(valueStrings, fmt.Sprintf("%s,", l.Value[i].String()))
-		}
-	}
-	// Sprintf can automatically convert an array of strings into
-	// a string for the output.
-	return fmt.Sprintf("%s", valueStrings)
+	return l.toString(make(map[Object]bool))
 }
 
 func (l *List) FormattedString() string {
+	return l.toString(make(map[Object]bool))
+}
+
+func (l *List) toString(visiting map[Object]bool) string {
+	if visiting[l] {
+		return "[...]"
+	}
+	visiting[l] = true
+	defer delete(visiting, l)
+
 	var valueStrings []string
 	for i := 0; i < len(l.Value); i++ {
-		// valueStrings = append(valueStrings, l.Value[i].String())
 		if i == len(l.Value)-1 {
-			valueStrings = append(valueStrings, fmt.Sprintf("%s", l.Value[i].String()))
+			valueStrings = append(valueStrings, fmt.Sprintf("%s", stringOf(l.Value[i], visiting)))
 		} else {
-			valueStrings = append(valueStrings, fmt.Sprintf("%s,", l.Value[i].String()))
+			valueStrings = append(valueStrings, fmt.Sprintf("%s,", stringOf(l.Value[i], visiting)))
 		}
 	}
 	// Sprintf can automatically convert an array of strings into
@@ -297,40 +657,31 @@ func (hm *HashMap) Type() string {
 }
 
 func (hm *HashMap) String() string {
-	var valueStrings []string
-
-	// Don't need the key here
-	count := 0
-	length := len(hm.Value)
-	for _, value := range hm.Value {
-		if count == length-1 {
-			valueStrings = append(valueStrings,
-				fmt.Sprintf("%s: %s\n", value.Key.FormattedString(), value.Value.String()))
-		} else {
-			valueStrings = append(valueStrings,
-				fmt.Sprintf("%s: %s, \n", value.Key.FormattedString(), value.Value.String()))
-		}
-		count++
-	}
-
-	// Sprintf can automatically convert an array of strings into
-	// a string for the output.
-	return fmt.Sprintf("%s", valueStrings)
+	return hm.toString(make(map[Object]bool))
 }
 
 func (hm *HashMap) FormattedString() string {
+	return hm.toString(make(map[Object]bool))
+}
+
+func (hm *HashMap) toString(visiting map[Object]bool) string {
+	if visiting[hm] {
+		return "[...]"
+	}
+	visiting[hm] = true
+	defer delete(visiting, hm)
+
 	var valueStrings []string
 
-	// Don't need the key here
 	count := 0
 	length := len(hm.Value)
-	for _, value := range hm.Value {
+	for _, value := range hm.Pairs() {
 		if count == length-1 {
 			valueStrings = append(valueStrings,
-				fmt.Sprintf("%s: %s\n", value.Key.FormattedString(), value.Value.String()))
+				fmt.Sprintf("%s: %s\n", value.Key.FormattedString(), stringOf(value.Value, visiting)))
 		} else {
 			valueStrings = append(valueStrings,
-				fmt.Sprintf("%s: %s, \n", value.Key.FormattedString(), value.Value.String()))
+				fmt.Sprintf("%s: %s, \n", value.Key.FormattedString(), stringOf(value.Value, visiting)))
 		}
 		count++
 	}
@@ -339,3 +690,16 @@ func (hm *HashMap) FormattedString() string {
 	// a string for the output.
 	return fmt.Sprintf("%s", valueStrings)
 }
+
+// Lists and hashmaps can hold themselves, visiting holds the ones that are
+// being shown so that they are shown as [...] when they are reached again
+// from within themselves, instead of going on forever
+func stringOf(obj Object, visiting map[Object]bool) string {
+	switch obj := obj.(type) {
+	case *List:
+		return obj.toString(visiting)
+	case *HashMap:
+		return obj.toString(visiting)
+	}
+	return obj.String()
+}
@@ -0,0 +1,365 @@
+package object
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/lczm/as/token"
+)
+
+// The operators and what is truthy are shared by the interpreter and the
+// vm, so that both of them give back the same results.
+
+// Gives back the result of a binary operation, or an error message if
+// the operation cannot be done on the operands.
+func BinaryOperation(operator token.Token, left Object, right Object) (Object, string) {
+	switch operator.Type {
+	case token.PLUS: // Add
+		// Integers
+		if left.RawType() == INTEGER && right.RawType() == INTEGER {
+			leftValue := left.(*Integer).Value
+			rightValue := right.(*Integer).Value
+			return &Integer{Value: leftValue + rightValue}, ""
+		}
+		// Strings
+		if left.RawType() == STRING && right.RawType() == STRING {
+			leftValue := left.(*String).Value
+			rightValue := right.(*String).Value
+			return &String{Value: leftValue + rightValue}, ""
+		}
+		if leftValue, rightValue, ok := floatOperands(left, right); ok {
+			return &Float{Value: leftValue + rightValue}, ""
+		}
+	case token.MINUS: // Subtract
+		if left.RawType() == INTEGER && right.RawType() == INTEGER {
+			leftValue := left.(*Integer).Value
+			rightValue := right.(*Integer).Value
+			return &Integer{Value: leftValue - rightValue}, ""
+		}
+		if leftValue, rightValue, ok := floatOperands(left, right); ok {
+			return &Float{Value: leftValue - rightValue}, ""
+		}
+	case token.ASTERISK: // Multiply
+		if left.RawType() == INTEGER && right.RawType() == INTEGER {
+			leftValue := left.(*Integer).Value
+			rightValue := right.(*Integer).Value
+
+			return &Integer{Value: leftValue * rightValue}, ""
+		}
+		if leftValue, rightValue, ok := floatOperands(left, right); ok {
+			return &Float{Value: leftValue * rightValue}, ""
+		}
+	case token.SLASH: // Divide
+		if left.RawType() == INTEGER && right.RawType() == INTEGER {
+			leftValue := left.(*Integer).Value
+			rightValue := right.(*Integer).Value
+			if rightValue == 0 {
+				return nil, "Division by zero"
+			}
+
+			return &Integer{Value: leftValue / rightValue}, ""
+		}
+		if leftValue, rightValue, ok := floatOperands(left, right); ok {
+			if rightValue == 0 {
+				return nil, "Division by zero"
+			}
+			return &Float{Value: leftValue / rightValue}, ""
+		}
+	case token.MODULUS: // Modulus
+		if left.RawType() == INTEGER && right.RawType() == INTEGER {
+			leftValue := left.(*Integer).Value
+			rightValue := right.(*Integer).Value
+			if rightValue == 0 {
+				return nil, "Modulus by zero"
+			}
+
+			return &Integer{Value: leftValue % rightValue}, ""
+		}
+		if leftValue, rightValue, ok := floatOperands(left, right); ok {
+			if rightValue == 0 {
+				return nil, "Modulus by zero"
+			}
+			return &Float{Value: math.Mod(leftValue, rightValue)}, ""
+		}
+	case token.GT: // Greater than
+		if left.RawType() == INTEGER && right.RawType() == INTEGER {
+			leftValue := left.(*Integer).Value
+			rightValue := right.(*Integer).Value
+			return &Bool{Value: leftValue > rightValue}, ""
+		}
+		if leftValue, rightValue, ok := floatOperands(left, right); ok {
+			return &Bool{Value: leftValue > rightValue}, ""
+		}
+	case token.GT_EQ: // Greater equal than
+		if left.RawType() == INTEGER && right.RawType() == INTEGER {
+			leftValue := left.(*Integer).Value
+			rightValue := right.(*Integer).Value
+			return &Bool{Value: leftValue >= rightValue}, ""
+		}
+		if leftValue, rightValue, ok := floatOperands(left, right); ok {
+			return &Bool{Value: leftValue >= rightValue}, ""
+		}
+	case token.LT: // Lesser than
+		if left.RawType() == INTEGER && right.RawType() == INTEGER {
+			leftValue := left.(*Integer).Value
+			rightValue := right.(*Integer).Value
+			return &Bool{Value: leftValue < rightValue}, ""
+		}
+		if leftValue, rightValue, ok := floatOperands(left, right); ok {
+			return &Bool{Value: leftValue < rightValue}, ""
+		}
+	case token.LT_EQ: // Lesser equal than
+		if left.RawType() == INTEGER && right.RawType() == INTEGER {
+			leftValue := left.(*Integer).Value
+			rightValue := right.(*Integer).Value
+			return &Bool{Value: leftValue <= rightValue}, ""
+		}
+		if leftValue, rightValue, ok := floatOperands(left, right); ok {
+			return &Bool{Value: leftValue <= rightValue}, ""
+		}
+	case token.EQ: // Equals '=='
+		// Null is only ever equal to itself
+		if left.RawType() == NULL || right.RawType() == NULL {
+			return &Bool{Value: left.RawType() == right.RawType()}, ""
+		}
+		// Integers
+		if left.RawType() == INTEGER && right.RawType() == INTEGER {
+			leftValue := left.(*Integer).Value
+			rightValue := right.(*Integer).Value
+			return &Bool{Value: leftValue == rightValue}, ""
+		}
+		// Strings
+		if left.RawType() == STRING && right.RawType() == STRING {
+			leftValue := left.(*String).Value
+			rightValue := right.(*String).Value
+			return &Bool{Value: leftValue == rightValue}, ""
+		}
+		// Bools
+		if left.RawType() == BOOL && right.RawType() == BOOL {
+			leftValue := left.(*Bool).Value
+			rightValue := right.(*Bool).Value
+			return &Bool{Value: leftValue == rightValue}, ""
+		}
+		// Floats, or floats and integers
+		if leftValue, rightValue, ok := floatOperands(left, right); ok {
+			return &Bool{Value: leftValue == rightValue}, ""
+		}
+	case token.NOT_EQ: // Not equals '!='
+		if left.RawType() == NULL || right.RawType() == NULL {
+			return &Bool{Value: left.RawType() != right.RawType()}, ""
+		}
+		// Integers
+		if left.RawType() == INTEGER && right.RawType() == INTEGER {
+			leftValue := left.(*Integer).Value
+			rightValue := right.(*Integer).Value
+			return &Bool{Value: leftValue != rightValue}, ""
+		}
+		// Strings
+		if left.RawType() == STRING && right.RawType() == STRING {
+			leftValue := left.(*String).Value
+			rightValue := right.(*String).Value
+			return &Bool{Value: leftValue != rightValue}, ""
+		}
+		// Floats, or floats and integers
+		if leftValue, rightValue, ok := floatOperands(left, right); ok {
+			return &Bool{Value: leftValue != rightValue}, ""
+		}
+	}
+
+	return nil, fmt.Sprintf("Unsupported operand types for '%s' : %s and %s",
+		operator.Literal, left.RawType(), right.RawType())
+}
+
+// Gives back the result of a unary operation, or an error message if
+// the operation cannot be done on the operand.
+func UnaryOperation(operator token.Token, right Object) (Object, string) {
+	switch operator.Type {
+	case token.MINUS:
+		// Inverse the value
+		if right.RawType() == INTEGER {
+			rightValue := right.(*Integer).Value
+			return &Integer{Value: -rightValue}, ""
+		}
+		if right.RawType() == FLOAT {
+			rightValue := right.(*Float).Value
+			return &Float{Value: -rightValue}, ""
+		}
+	case token.BANG:
+		// If evaluated condition is true, inverse the result
+		if IsTruthy(right) {
+			return &Bool{Value: false}, ""
+		}
+		// If it is not true, inverse the result here
+		return &Bool{Value: true}, ""
+	}
+	return nil, fmt.Sprintf("Unsupported operand type for '%s' : %s",
+		operator.Literal, right.RawType())
+}
+
+// This is where it is important to define what is truthy and what is not.
+// Anything that is not listed here, i.e. null, is not truthy.
+func IsTruthy(obj Object) bool {
+	// Check for booleans
+	if obj.RawType() == BOOL {
+		return obj.(*Bool).Value
+	}
+
+	if obj.RawType() == INTEGER {
+		// If the value of the object is 0, it is falsey
+		if obj.(*Integer).Value == 0 {
+			return false
+		}
+		// If the value of the object is not 0, it is truthy
+		return true
+	}
+
+	if obj.RawType() == FLOAT {
+		return obj.(*Float).Value != 0
+	}
+
+	return false
+}
+
+// If either operand is a float and the other is a number, both are
+// promoted to floats for arithmetic and comparisons.
+func floatOperands(left Object, right Object) (float64, float64, bool) {
+	leftValue, leftOk := toFloat(left)
+	rightValue, rightOk := toFloat(right)
+	isFloat := left.RawType() == FLOAT || right.RawType() == FLOAT
+	return leftValue, rightValue, leftOk && rightOk && isFloat
+}
+
+func toFloat(obj Object) (float64, bool) {
+	switch obj := obj.(type) {
+	case *Integer:
+		return float64(obj.Value), true
+	case *Float:
+		return obj.Value, true
+	}
+	return 0, false
+}
+
+// Gives back the element of a list, hashmap or string at an index, or an
+// error message if it cannot be indexed. Missing hashmap keys give back null.
+func IndexOperation(container Object, index Object) (Object, string) {
+	switch container := container.(type) {
+	case *List:
+		listIndex, ok := index.(*Integer)
+		if !ok {
+			return nil, "Indexed operation on a list expression is not an integer"
+		}
+		if listIndex.Value < 0 || listIndex.Value >= int64(len(container.Value)) {
+			return nil, fmt.Sprintf("List index %d is out of range", listIndex.Value)
+		}
+		return container.Value[listIndex.Value], ""
+	case *HashMap:
+		hashable, ok := index.(Hashable)
+		if !ok {
+			return nil, fmt.Sprintf("Object of %s cannot be used as a hashmap key", index.RawType())
+		}
+		pair, found := container.Value[hashable.Hash()]
+		if found {
+			return pair.Value, ""
+		}
+		return NullValue, ""
+	case *String:
+		stringIndex, ok := index.(*Integer)
+		if !ok {
+			return nil, "Indexed operation on a string expression is not an integer"
+		}
+		// Strings are indexed by character, and not by byte
+		characters := []rune(container.Value)
+		if stringIndex.Value < 0 || stringIndex.Value >= int64(len(characters)) {
+			return nil, fmt.Sprintf("String index %d is out of range", stringIndex.Value)
+		}
+		return &String{Value: string(characters[stringIndex.Value])}, ""
+	}
+	return nil, fmt.Sprintf("Object of %s cannot be indexed", container.RawType())
+}
+
+// Sets the element of a list or hashmap at an index, giving back an error
+// message if it cannot be set.
+func SetIndexOperation(container Object, index Object, value Object) string {
+	switch container := container.(type) {
+	case *List:
+		listIndex, ok := index.(*Integer)
+		if !ok {
+			return "Indexed operation on a list expression is not an integer"
+		}
+		if listIndex.Value < 0 || listIndex.Value >= int64(len(container.Value)) {
+			return fmt.Sprintf("List index %d is out of range", listIndex.Value)
+		}
+		container.Value[listIndex.Value] = value
+	case *HashMap:
+		hashable, ok := index.(Hashable)
+		if !ok {
+			return fmt.Sprintf("Object of %s cannot be used as a hashmap key", index.Type())
+		}
+		container.Value[hashable.Hash()] = HashValue{
+			Key:   index,
+			Value: value,
+		}
+	default:
+		return fmt.Sprintf("Cannot assign to an index of %s", container.RawType())
+	}
+	return ""
+}
+
+// Whether two objects hold the same values, lists, hashmaps and instances
+// of structs are compared by what they hold rather than by whether they
+// are the same object. Integers and floats are equal if their values are.
+func Equal(left Object, right Object) bool {
+	if leftValue, rightValue, ok := floatOperands(left, right); ok {
+		return leftValue == rightValue
+	}
+
+	switch left := left.(type) {
+	case *Integer:
+		right, ok := right.(*Integer)
+		return ok && left.Value == right.Value
+	case *String:
+		right, ok := right.(*String)
+		return ok && left.Value == right.Value
+	case *Bool:
+		right, ok := right.(*Bool)
+		return ok && left.Value == right.Value
+	case *Null:
+		return right.RawType() == NULL
+	case *List:
+		right, ok := right.(*List)
+		if !ok || len(left.Value) != len(right.Value) {
+			return false
+		}
+		for i := range left.Value {
+			if !Equal(left.Value[i], right.Value[i]) {
+				return false
+			}
+		}
+		return true
+	case *HashMap:
+		right, ok := right.(*HashMap)
+		if !ok || len(left.Value) != len(right.Value) {
+			return false
+		}
+		for key, pair := range left.Value {
+			other, ok := right.Value[key]
+			if !ok || !Equal(pair.Value, other.Value) {
+				return false
+			}
+		}
+		return true
+	case *Struct:
+		right, ok := right.(*Struct)
+		if !ok || left.Name != right.Name || len(left.Attributes) != len(right.Attributes) {
+			return false
+		}
+		for name, value := range left.Attributes {
+			other, ok := right.Attributes[name]
+			if !ok || !Equal(value, other) {
+				return false
+			}
+		}
+		return true
+	}
+	return left == right
+}
@@ -5,32 +5,79 @@ import (
 
 	"github.com/lczm/as/ast"
 	"github.com/lczm/as/errors"
-	"github.com/lczm/as/globals"
 	"github.com/lczm/as/token"
 )
 
 type Parser struct {
 	current int
 	tokens  []token.Token
+	// How many loops the parser is currently in, 'break' and
+	// 'continue' can only be used within loops
+	loopDepth int
+	// How many blocks the parser is currently in, imports can only
+	// be at the top level of a file
+	blockDepth int
+	// Syntax errors found while parsing
+	Errors []errors.Error
 }
 
+// This is used to unwind the parser back up to the closest declaration
+// when a syntax error is found, the error itself is recorded
+// in Errors before this happens.
+type parseError struct{}
+
 func (p *Parser) Parse() []ast.Statement {
 	// var expressions []ast.Expression
 	// expressions = append(expressions, p.expression())
 
 	var statements []ast.Statement
-	for p.current != len(p.tokens) {
-		statements = append(statements, p.declaration())
+	for !p.isAtEnd() {
+		// Declarations that have syntax errors are dropped
+		if stmt := p.declaration(); stmt != nil {
+			statements = append(statements, stmt)
+		}
 	}
 
 	return statements
 }
 
-func (p *Parser) declaration() ast.Statement {
+// Allows the last expression to be typed in without the trailing ';'
+// i.e. '>> 1 + 2' in the repl, a ';' is added after it if it is missing
+func TerminateExpression(tokens []token.Token) []token.Token {
+	if len(tokens) == 0 {
+		return tokens
+	}
+	last := tokens[len(tokens)-1]
+	if last.Type == token.SEMICOLON || last.Type == token.RBRACE {
+		return tokens
+	}
+	return append(tokens, token.Token{
+		Type:    token.SEMICOLON,
+		Literal: ";",
+		Line:    last.Line,
+		Column:  last.Column + len(last.Literal),
+		Offset:  last.Offset + len(last.Literal),
+	})
+}
+
+func (p *Parser) declaration() (stmt ast.Statement) {
+	// If there is a syntax error anywhere within this declaration, skip
+	// ahead to the next one so that the rest of the errors can be reported
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(parseError); !ok {
+				panic(r)
+			}
+			p.synchronize()
+			stmt = nil
+		}
+	}()
+
 	if p.match(token.VAR) {
 		return p.varDeclaration()
-	} else if p.match(token.THIS) {
-		return p.thisDeclaration()
+	}
+	if p.blockDepth == 0 && p.match(token.IMPORT) {
+		return p.importStatement()
 	}
 	return p.statement()
 }
@@ -57,19 +104,12 @@ func (p *Parser) varDeclaration() ast.Statement {
 	return variableStatement
 }
 
-func (p *Parser) thisDeclaration() ast.Statement {
-	// TODO : This should not return a varDeclartion
-	// there should an ast that defines this.{attribute}
-
-	p.eat(token.DOT, "Expect '.' after 'this'")
-	// p.eat(token.IDENTIFIER, "Expect attribute name")
-	// name := p.previous()
-	return p.varDeclaration()
-}
-
 func (p *Parser) statement() ast.Statement {
 	// This is a function declaration, it can be re-used to be parsed for methods as well.
-	if p.match(token.FUNCTION) {
+	// If there is no name after 'function', it is an anonymous function expression
+	// which will be handled by p.expressionStatement().
+	if p.peek().Type == token.FUNCTION && p.peekN(1).Type == token.IDENTIFIER {
+		p.advance()
 		return p.functionStatement("function")
 	}
 	if p.match(token.STRUCT) {
@@ -81,6 +121,18 @@ func (p *Parser) statement() ast.Statement {
 	if p.match(token.RETURN) {
 		return p.returnStatement()
 	}
+	if p.match(token.BREAK, token.CONTINUE) {
+		return p.loopControlStatement()
+	}
+	if p.match(token.IMPORT) {
+		p.error(p.previous(), "Imports can only be at the top level of a file")
+	}
+	if p.match(token.TRY) {
+		return p.tryStatement()
+	}
+	if p.match(token.THROW) {
+		return p.throwStatement()
+	}
 	if p.match(token.FOR) {
 		return p.forStatement()
 	}
@@ -95,12 +147,29 @@ func (p *Parser) statement() ast.Statement {
 }
 
 func (p *Parser) functionStatement(functionType string) ast.Statement {
-	name := p.peek()
-	if name.Type != token.IDENTIFIER {
-		panic("Function name is not an identifier")
+	p.eat(token.IDENTIFIER, "Expect "+functionType+" name")
+	name := p.previous()
+
+	p.eat(token.LPAREN, "Expect '(' to start off function declaration")
+	parameters := p.parameters(functionType)
+
+	// Get the body of the function block statement
+	// function(a, b, c) { }
+	p.eat(token.LBRACE, "Expect '{' to start off the body of a function declaration")
+
+	body := p.functionBody()
+
+	functionStatement := &ast.FunctionStatement{
+		Name:   name,
+		Params: parameters,
+		Body:   *body,
 	}
-	p.advance()
+	return functionStatement
+}
 
+// Parses the parameters of a function, this expects the '(' to have
+// been eaten already, and will eat the closing ')'.
+func (p *Parser) parameters(functionType string) []token.Token {
 	// This will store the tokens in a function.
 	// This is a token and not an expr array because
 	// this does not evaluate.
@@ -112,14 +181,9 @@ func (p *Parser) functionStatement(functionType string) ast.Statement {
 	// make sure to change that together
 	emptyParameter := true
 
-	p.eat(token.LPAREN, "Expect '(' to start off function declaration")
 	for !p.match(token.RPAREN) {
-		parameter := p.peek()
-		if parameter.Type != token.IDENTIFIER {
-			panic("Expect identifiers within a function argument")
-		}
-		parameters = append(parameters, parameter)
-		p.advance()
+		p.eat(token.IDENTIFIER, "Expect identifiers within a "+functionType+" argument")
+		parameters = append(parameters, p.previous())
 		if !p.match(token.COMMA) {
 			emptyParameter = false
 			break
@@ -131,28 +195,85 @@ func (p *Parser) functionStatement(functionType string) ast.Statement {
 	}
 
 	p.eat(token.RPAREN, "Expect ')' to end off function declaration")
+	return parameters
+}
 
-	// Get the body of the function block statement
-	// function(a, b, c) { }
-	p.eat(token.LBRACE, "Expect '{' to start off the body of a function declaration")
+// function(a, b) { return a + b; }
+// This expects the 'function' keyword to have been eaten already
+func (p *Parser) functionExpression() ast.Expression {
+	keyword := p.previous()
 
-	// Cast ast.Statement into a ast.BlockStatement
-	body := p.blockStatement().(*ast.BlockStatement)
+	p.eat(token.LPAREN, "Expect '(' after 'function'")
+	parameters := p.parameters("function")
 
-	functionStatement := &ast.FunctionStatement{
-		Name:   name,
-		Params: parameters,
-		Body:   *body,
+	p.eat(token.LBRACE, "Expect '{' to start off the body of a function")
+	body := p.functionBody()
+
+	return &ast.FunctionExpression{
+		Keyword: keyword,
+		Params:  parameters,
+		Body:    *body,
 	}
-	return functionStatement
+}
+
+// (a, b) => a + b
+// (a, b) => { return a + b; }
+// a => a + 1
+// This expects the parameters to have been parsed already, and
+// the '=>' is the next token.
+func (p *Parser) arrowFunction(parameters []token.Token) ast.Expression {
+	p.eat(token.ARROW, "Expect '=>' after arrow function parameters")
+	arrow := p.previous()
+
+	// Either a block, or a single expression that will be returned
+	var body *ast.BlockStatement
+	if p.match(token.LBRACE) {
+		body = p.functionBody()
+	} else {
+		value := p.expression()
+		body = &ast.BlockStatement{
+			Statements: []ast.Statement{
+				&ast.ReturnStatement{
+					Keyword: arrow,
+					Value:   value,
+				},
+			},
+		}
+	}
+
+	return &ast.FunctionExpression{
+		Keyword: arrow,
+		Params:  parameters,
+		Body:    *body,
+	}
+}
+
+// Looks ahead from a '(' to check if it is the start of an arrow
+// function, (a, b, c) =>
+func (p *Parser) isArrowFunction() bool {
+	if p.peek().Type != token.LPAREN {
+		return false
+	}
+
+	n := 1
+	for p.peekN(n).Type == token.IDENTIFIER || p.peekN(n).Type == token.COMMA {
+		n++
+	}
+	return p.peekN(n).Type == token.RPAREN && p.peekN(n+1).Type == token.ARROW
 }
 
 func (p *Parser) structStatement() ast.Statement {
-	name := p.peek()
-	if name.Type != token.IDENTIFIER {
-		panic("Struct name is not an identifier")
+	p.eat(token.IDENTIFIER, "Expect struct name")
+	name := p.previous()
+
+	// struct Child : Parent {}
+	var parent *ast.VariableExpression
+	if p.match(token.COLON) {
+		p.eat(token.IDENTIFIER, "Expect parent struct name")
+		parent = &ast.VariableExpression{
+			Name: p.previous(),
+		}
 	}
-	p.advance()
 
 	attributes := make(map[token.Token]ast.Statement)
 	methods := make(map[token.Token]ast.Statement)
@@ -161,13 +282,15 @@ func (p *Parser) structStatement() ast.Statement {
 
 	for !p.match(token.RBRACE) {
 		value := p.peek()
-		// fmt.Println(value.Type, value.Literal)
 		if value.Type == token.VAR {
-			variable := p.declaration().(*ast.VariableStatement)
+			p.advance()
+			variable := p.varDeclaration().(*ast.VariableStatement)
 			attributes[variable.Name] = variable
 		} else if value.Type == token.IDENTIFIER {
 			function := p.functionStatement("method").(*ast.FunctionStatement)
 			methods[function.Name] = function
+		} else {
+			p.error(value, "Expect attribute or method declaration within struct")
 		}
 	}
 
@@ -177,6 +300,7 @@ func (p *Parser) structStatement() ast.Statement {
 
 	structStatement := &ast.StructStatement{
 		Name:       name,
+		Parent:     parent,
 		Attributes: attributes,
 		Methods:    methods,
 	}
@@ -186,6 +310,8 @@ func (p *Parser) structStatement() ast.Statement {
 // this function in the future should also support else if statements.
 // this can be done by nesting if else {if else {if else}}
 func (p *Parser) ifStatement() ast.Statement {
+	keyword := p.previous()
+
 	// Condition
 	p.eat(token.LPAREN, "Expect '(' to start off if condition")
 	condition := p.expression()
@@ -202,6 +328,7 @@ func (p *Parser) ifStatement() ast.Statement {
 	}
 
 	ifStatement := &ast.IfStatement{
+		Keyword:   keyword,
 		Condition: condition,
 		Then:      thenStatement,
 		Else:      elseStatement,
@@ -210,6 +337,108 @@ func (p *Parser) ifStatement() ast.Statement {
 	return ifStatement
 }
 
+// Parses the body of a function, this expects the '{' to have been
+// eaten already. Loops outside of the function cannot be broken out
+// of from within the function.
+func (p *Parser) functionBody() *ast.BlockStatement {
+	loopDepth := p.loopDepth
+	p.loopDepth = 0
+	defer func() {
+		p.loopDepth = loopDepth
+	}()
+
+	// Cast ast.Statement into a ast.BlockStatement
+	return p.blockStatement().(*ast.BlockStatement)
+}
+
+// Parses the body of a loop, the depth is restored even if the
+// body has a syntax error in it.
+func (p *Parser) loopBody() ast.Statement {
+	p.loopDepth++
+	defer func() {
+		p.loopDepth--
+	}()
+
+	return p.statement()
+}
+
+// break; and continue;
+func (p *Parser) loopControlStatement() ast.Statement {
+	keyword := p.previous()
+	if p.loopDepth == 0 {
+		p.error(keyword, "Cannot use '"+keyword.Literal+"' outside of a loop")
+	}
+	p.eat(token.SEMICOLON, "Expect ';' after '"+keyword.Literal+"'")
+
+	if keyword.Type == token.BREAK {
+		return &ast.BreakStatement{Keyword: keyword}
+	}
+	return &ast.ContinueStatement{Keyword: keyword}
+}
+
+// import "path"; and import name from "path";
+// 'from' is not a keyword so that it can still be used as a name.
+func (p *Parser) importStatement() ast.Statement {
+	importStatement := &ast.ImportStatement{Keyword: p.previous()}
+
+	if p.match(token.IDENTIFIER) {
+		name := p.previous()
+		importStatement.Name = &name
+		if !p.match(token.IDENTIFIER) || p.previous().Literal != "from" {
+			p.error(p.previous(), "Expect 'from' after the name of an import")
+		}
+	}
+
+	p.eat(token.STRING, "Expect a string of the path to import")
+	importStatement.Path = p.previous()
+	p.eat(token.SEMICOLON, "Expect ';' after import")
+
+	return importStatement
+}
+
+// try { } catch (e) { } finally { }
+// The name of the error can be left out, i.e. catch { }, and either one
+// of catch or finally can be left out, but not both.
+func (p *Parser) tryStatement() ast.Statement {
+	tryStatement := &ast.TryStatement{Keyword: p.previous()}
+
+	p.eat(token.LBRACE, "Expect '{' after try")
+	tryStatement.Body = p.blockStatement().(*ast.BlockStatement)
+
+	if p.match(token.CATCH) {
+		if p.match(token.LPAREN) {
+			p.eat(token.IDENTIFIER, "Expect the name of the error after '('")
+			name := p.previous()
+			tryStatement.Name = &name
+			p.eat(token.RPAREN, "Expect ')' after the name of the error")
+		}
+		p.eat(token.LBRACE, "Expect '{' after catch")
+		tryStatement.Catch = p.blockStatement().(*ast.BlockStatement)
+	}
+
+	if p.match(token.FINALLY) {
+		p.eat(token.LBRACE, "Expect '{' after finally")
+		tryStatement.Finally = p.blockStatement().(*ast.BlockStatement)
+	}
+
+	if tryStatement.Catch == nil && tryStatement.Finally == nil {
+		p.error(p.peek(), "Expect 'catch' or 'finally' after try")
+	}
+	return tryStatement
+}
+
+// throw expression;
+func (p *Parser) throwStatement() ast.Statement {
+	keyword := p.previous()
+	value := p.expression()
+	p.eat(token.SEMICOLON, "Expect ';' after throw statement")
+
+	return &ast.ThrowStatement{
+		Keyword: keyword,
+		Value:   value,
+	}
+}
+
 func (p *Parser) returnStatement() ast.Statement {
 	keyword := p.previous()
 
@@ -229,8 +458,15 @@ func (p *Parser) returnStatement() ast.Statement {
 }
 
 func (p *Parser) forStatement() ast.Statement {
+	keyword := p.previous()
 	p.eat(token.LPAREN, "Expect '(' after for.")
 
+	// for (x in ...) and for (k, v in ...)
+	if p.peek().Type == token.IDENTIFIER &&
+		(p.peekN(1).Type == token.IN || p.peekN(1).Type == token.COMMA) {
+		return p.forInStatement(keyword)
+	}
+
 	// Variable section of for loops
 	var variable ast.Statement = nil
 	// for (var {x};
@@ -238,7 +474,8 @@ func (p *Parser) forStatement() ast.Statement {
 		// This will be a variableStatement
 		variable = p.varDeclaration()
 		if variable.(*ast.VariableStatement).Initializer == nil {
-			panic("Cannot have uninitialized variable in a 'for' statement")
+			p.error(variable.(*ast.VariableStatement).Name,
+				"Cannot have uninitialized variable in a 'for' statement")
 		}
 	} else { // Existing variable, for({x};)
 		variable = p.expressionStatement()
@@ -257,9 +494,10 @@ func (p *Parser) forStatement() ast.Statement {
 	p.eat(token.RPAREN, "Expect ')' after 'for' statement.")
 
 	// Block statement
-	body := p.statement()
+	body := p.loopBody()
 
 	forStatement := &ast.ForStatement{
+		Keyword:   keyword,
 		Variable:  variable,
 		Condition: condition,
 		Effect:    effect,
@@ -268,16 +506,49 @@ func (p *Parser) forStatement() ast.Statement {
 	return forStatement
 }
 
+// This expects the '(' after 'for' to have been eaten already
+func (p *Parser) forInStatement(keyword token.Token) ast.Statement {
+	p.eat(token.IDENTIFIER, "Expect variable name in 'for' statement")
+	value := p.previous()
+
+	var key *token.Token
+	if p.match(token.COMMA) {
+		first := value
+		key = &first
+		p.eat(token.IDENTIFIER, "Expect variable name after ',' in 'for' statement")
+		value = p.previous()
+	}
+
+	p.eat(token.IN, "Expect 'in' after variable names in 'for' statement")
+	in := p.previous()
+	iterable := p.expression()
+	p.eat(token.RPAREN, "Expect ')' after 'for' statement.")
+
+	body := p.loopBody()
+
+	forInStatement := &ast.ForInStatement{
+		Keyword:  keyword,
+		Key:      key,
+		Value:    value,
+		In:       in,
+		Iterable: iterable,
+		Body:     body,
+	}
+	return forInStatement
+}
+
 func (p *Parser) whileStatement() ast.Statement {
+	keyword := p.previous()
 	p.eat(token.LPAREN, "Expect '(' after while.")
 
 	condition := p.expression()
 
 	p.eat(token.RPAREN, "Expect, ')' after condition of while.")
 	// This should most likely be a block statement
-	body := p.statement()
+	body := p.loopBody()
 
 	whileStatement := &ast.WhileStatement{
+		Keyword:   keyword,
 		Condition: condition,
 		Body:      body,
 	}
@@ -285,11 +556,18 @@ func (p *Parser) whileStatement() ast.Statement {
 }
 
 func (p *Parser) blockStatement() ast.Statement {
+	p.blockDepth++
+	defer func() {
+		p.blockDepth--
+	}()
+
 	var statements []ast.Statement
 
 	// Keep going until it hits the right brace - '}'.
-	for p.peek().Type != token.RBRACE {
-		statements = append(statements, p.declaration())
+	for p.peek().Type != token.RBRACE && !p.isAtEnd() {
+		if stmt := p.declaration(); stmt != nil {
+			statements = append(statements, stmt)
+		}
 	}
 
 	// Once the right brace is hit, move the parser past the
@@ -321,138 +599,109 @@ func (p *Parser) assignment() ast.Expression {
 	expr := p.and()
 
 	// Match for assignment
-	for p.match(token.ASSIGN) {
+	if p.match(token.ASSIGN) {
 		assignment := p.previous()
 		value := p.assignment()
 
-		if varExpr, ok := expr.(*ast.VariableExpression); ok {
-			return &ast.AssignmentExpression{
-				Name:  varExpr.Name,
-				Value: value,
-			}
-		} else if callExpr, ok := expr.(*ast.CallExpression); ok {
-			varExpr := callExpr.Callee.(*ast.VariableExpression)
-			return &ast.AssignmentIndexExpression{
-				Name:  varExpr.Name,
-				Value: value,
-				Index: callExpr.Arguments[0],
-			}
-		} else if getExpr, ok := expr.(*ast.GetExpression); ok {
-			varExpr := getExpr.Callee.(*ast.VariableExpression)
-			return &ast.AssignmentStruct{
-				Name:      varExpr.Name,
-				Attribute: getExpr.Caller,
-				Value:     value,
-			}
-		}
-
-		// Error out here.
-		panic(assignment)
+		return p.assignmentTarget(expr, value, assignment)
 	}
 
 	// i++; i--;
 	// Match for increment / decrement
 	for p.match(token.INCREMENT, token.DECREMENT) {
-		var binaryExpr *ast.BinaryExpression
+		// Re-use the position of the '++' / '--' for the operator, so that
+		// errors can still point to it
+		increment := p.previous()
+		operator := increment
 		// Check that when it is incrementing, binaryExpr should be plus
 		// Make sure to use previous to check as match increments it
-		if p.previous().Type == token.INCREMENT {
-			binaryExpr = &ast.BinaryExpression{
-				Left:  expr,
-				Right: &ast.NumberExpression{Value: 1},
-				Operator: token.Token{
-					Type:    token.PLUS,
-					Literal: "+",
-				},
-			}
-		} else if p.previous().Type == token.DECREMENT {
-			binaryExpr = &ast.BinaryExpression{
-				Left:  expr,
-				Right: &ast.NumberExpression{Value: 1},
-				Operator: token.Token{
-					Type:    token.MINUS,
-					Literal: "-",
-				},
-			}
+		if operator.Type == token.INCREMENT {
+			operator.Type = token.PLUS
+			operator.Literal = "+"
+		} else if operator.Type == token.DECREMENT {
+			operator.Type = token.MINUS
+			operator.Literal = "-"
 		}
 
-		// Can check if it casted properly
-		variableExpr := expr.(*ast.VariableExpression)
+		binaryExpr := &ast.BinaryExpression{
+			Left:     expr,
+			Right:    &ast.NumberExpression{Value: 1},
+			Operator: operator,
+		}
 
 		// Return an assignment expression
 		// syntax sugar converting i++ into i = i + 1
 		// likewise for i-- into i = i - 1
-		expr = &ast.AssignmentExpression{
-			Name:  variableExpr.Name,
-			Value: binaryExpr,
-		}
+		expr = p.assignmentTarget(expr, binaryExpr, increment)
 	}
 
 	// +=; -=; *=; /=; %=;
 	// Match for augmented assignments
 	for p.match(token.AUG_PLUS, token.AUG_MINUS, token.AUG_ASTERISK,
 		token.AUG_SLASH, token.AUG_MODULUS) {
-		var binaryExpr *ast.BinaryExpression
-		if p.previous().Type == token.AUG_PLUS {
-			// a += b; <- 'b' here can be an expression
-			binaryExpr = &ast.BinaryExpression{
-				Left:  expr,
-				Right: p.expression(), // Recurse down an expression
-				Operator: token.Token{
-					Type:    token.PLUS,
-					Literal: "+",
-				},
-			}
-		} else if p.previous().Type == token.AUG_MINUS {
-			binaryExpr = &ast.BinaryExpression{
-				Left:  expr,
-				Right: p.expression(), // Recurse down an expression
-				Operator: token.Token{
-					Type:    token.MINUS,
-					Literal: "-",
-				},
-			}
-		} else if p.previous().Type == token.AUG_ASTERISK {
-			binaryExpr = &ast.BinaryExpression{
-				Left:  expr,
-				Right: p.expression(), // Recurse down an expression
-				Operator: token.Token{
-					Type:    token.ASTERISK,
-					Literal: "*",
-				},
-			}
-		} else if p.previous().Type == token.AUG_SLASH {
-			binaryExpr = &ast.BinaryExpression{
-				Left:  expr,
-				Right: p.expression(), // Recurse down an expression
-				Operator: token.Token{
-					Type:    token.SLASH,
-					Literal: "/",
-				},
-			}
-		} else if p.previous().Type == token.AUG_MODULUS {
-			binaryExpr = &ast.BinaryExpression{
-				Left:  expr,
-				Right: p.expression(), // Recurse down an expression
-				Operator: token.Token{
-					Type:    token.MODULUS,
-					Literal: "%",
-				},
-			}
+		augmented := p.previous()
+		operator := augmented
+		if operator.Type == token.AUG_PLUS {
+			operator.Type = token.PLUS
+		} else if operator.Type == token.AUG_MINUS {
+			operator.Type = token.MINUS
+		} else if operator.Type == token.AUG_ASTERISK {
+			operator.Type = token.ASTERISK
+		} else if operator.Type == token.AUG_SLASH {
+			operator.Type = token.SLASH
+		} else if operator.Type == token.AUG_MODULUS {
+			operator.Type = token.MODULUS
 		}
+		// Strip off the '=', i.e. '+=' into '+'
+		operator.Literal = string(operator.Type)
 
-		// Check if it can be casted properly to a variableExpression
-		variableExpr := expr.(*ast.VariableExpression)
-
-		expr = &ast.AssignmentExpression{
-			Name:  variableExpr.Name,
-			Value: binaryExpr,
+		// a += b; <- 'b' here can be an expression
+		binaryExpr := &ast.BinaryExpression{
+			Left:     expr,
+			Right:    p.expression(), // Recurse down an expression
+			Operator: operator,
 		}
+
+		expr = p.assignmentTarget(expr, binaryExpr, augmented)
 	}
 
 	return expr
 }
 
+// Converts the left hand side of an assignment into the matching
+// assignment expression.
+// a = value; a[index] = value; a.attribute = value;
+func (p *Parser) assignmentTarget(target ast.Expression, value ast.Expression,
+	operator token.Token) ast.Expression {
+
+	switch target := target.(type) {
+	case *ast.VariableExpression:
+		return &ast.AssignmentExpression{
+			Name:  target.Name,
+			Value: value,
+		}
+	case *ast.CallExpression:
+		// Only indexing can be assigned to, and not function calls
+		if target.Token.Type == token.LBRACKET {
+			return &ast.AssignmentIndexExpression{
+				Object: target.Callee,
+				Value:  value,
+				Index:  target.Arguments[0],
+				Token:  target.Token,
+			}
+		}
+	case *ast.GetExpression:
+		return &ast.AssignmentStruct{
+			Object:    target.Callee,
+			Attribute: target.Caller,
+			Value:     value,
+		}
+	}
+
+	p.error(operator, "Invalid assignment target")
+	return nil
+}
+
 func (p *Parser) and() ast.Expression {
 	expr := p.or()
 
@@ -567,6 +816,7 @@ func (p *Parser) call() ast.Expression {
 	for {
 		// If it is a left paren : '(<argument>, <argument>)
 		if p.match(token.LPAREN) {
+			paren := p.previous()
 			var arguments []ast.Expression
 			emptyParameter := true
 
@@ -594,8 +844,10 @@ func (p *Parser) call() ast.Expression {
 			expr = &ast.CallExpression{
 				Callee:    expr,
 				Arguments: arguments,
+				Token:     paren,
 			}
 		} else if p.match(token.LBRACKET) { // If it is a '[' : used for indexing
+			bracket := p.previous()
 			// The value inside the '['(value)']' can be a function or anything
 			// so this should be parsed with an expression
 			index := p.expression()
@@ -609,24 +861,17 @@ func (p *Parser) call() ast.Expression {
 			expr = &ast.CallExpression{
 				Callee:    expr,
 				Arguments: arguments,
+				Token:     bracket,
 			}
 		} else if p.match(token.DOT) {
 			// attribute := p.primary()
 			arguments := make([]ast.Expression, 0)
 
-			current := p.peek()
-			currentNext := p.peekN(1)
-			isMethod := false
-			var caller ast.Expression
-
-			if current.Type == token.IDENTIFIER && currentNext.Type == token.LPAREN {
-				isMethod = true
-				caller = p.primary()
-			} else {
-				caller = p.primary()
+			p.eat(token.IDENTIFIER, "Expect attribute name after '.'")
+			caller := &ast.VariableExpression{
+				Name: p.previous(),
 			}
-
-			// fmt.Println("caller : ", caller)
+			isMethod := p.peek().Type == token.LPAREN
 
 			expr = &ast.GetExpression{
 				Callee:    expr,
@@ -645,13 +890,23 @@ func (p *Parser) primary() ast.Expression {
 	if p.match(token.NUMBER) {
 		i, err := strconv.Atoi(p.previous().Literal)
 		if err != nil {
-			panic(err)
+			p.error(p.previous(), "Number is too large to be represented")
 		}
 		return &ast.NumberExpression{
 			Value: i,
 		}
 	}
 
+	if p.match(token.FLOAT) {
+		f, err := strconv.ParseFloat(p.previous().Literal, 64)
+		if err != nil {
+			p.error(p.previous(), "Number is too large to be represented")
+		}
+		return &ast.FloatExpression{
+			Value: f,
+		}
+	}
+
 	if p.match(token.STRING) {
 		value := p.previous().Literal
 		return &ast.StringExpression{
@@ -659,12 +914,52 @@ func (p *Parser) primary() ast.Expression {
 		}
 	}
 
+	if p.match(token.INTERPOLATION) {
+		return p.interpolation()
+	}
+
 	if p.match(token.IDENTIFIER) {
+		// Single parameter arrow functions, a => a + 1
+		if p.peek().Type == token.ARROW {
+			return p.arrowFunction([]token.Token{p.previous()})
+		}
+
 		return &ast.VariableExpression{
 			Name: p.previous(),
 		}
 	}
 
+	// Anonymous functions
+	if p.match(token.FUNCTION) {
+		return p.functionExpression()
+	}
+
+	if p.isArrowFunction() {
+		p.advance()
+		parameters := p.parameters("arrow function")
+		return p.arrowFunction(parameters)
+	}
+
+	if p.match(token.THIS) {
+		return &ast.ThisExpression{
+			Keyword: p.previous(),
+		}
+	}
+
+	if p.match(token.SUPER) {
+		keyword := p.previous()
+		p.eat(token.DOT, "Expect '.' after 'super'")
+		p.eat(token.IDENTIFIER, "Expect parent method name")
+		return &ast.SuperExpression{
+			Keyword: keyword,
+			Method:  p.previous(),
+		}
+	}
+
+	if p.match(token.NULL) {
+		return &ast.NullExpression{}
+	}
+
 	// True booleans
 	if p.match(token.TRUE) {
 		return &ast.BoolExpression{
@@ -716,6 +1011,7 @@ func (p *Parser) primary() ast.Expression {
 
 	// Hashmap declaration
 	if p.match(token.LBRACE) {
+		brace := p.previous()
 		// var hashMap map[ast.Expression]ast.Expression
 		hashMap := make(map[ast.Expression]ast.Expression, 0)
 		emptyHashMap := true
@@ -726,9 +1022,7 @@ func (p *Parser) primary() ast.Expression {
 		// }
 		for !p.match(token.RBRACE) {
 			key := p.expression()
-			if !p.match(token.COLON) {
-				panic("Syntax error while matching key/value in HashMap")
-			}
+			p.eat(token.COLON, "Expect ':' between a key and value in a hashmap")
 			value := p.expression()
 			hashMap[key] = value
 			p.match(token.COMMA)
@@ -743,9 +1037,11 @@ func (p *Parser) primary() ast.Expression {
 		p.eat(token.RBRACE, "Expect '}' after '{' (Start of hashmap)")
 		return &ast.HashMapExpression{
 			Values: hashMap,
+			Token:  brace,
 		}
 	}
 
+	p.error(p.peek(), "Expect expression")
 	return nil
 }
 
@@ -763,12 +1059,63 @@ func (p *Parser) match(tokens ...token.TokenType) bool {
 }
 
 func (p *Parser) peek() token.Token {
-	return p.tokens[p.current]
+	return p.peekN(0)
 }
 
 // Same as peek but allows for further peeks, other than just current
+// Peeking past the last token will give back an EOF token.
 func (p *Parser) peekN(n int) token.Token {
-	return p.tokens[p.current+n]
+	if p.current+n < len(p.tokens) {
+		return p.tokens[p.current+n]
+	}
+
+	// Place the EOF token right after the last token, so that
+	// errors at the end of the input point to the right place
+	eof := token.Token{Type: token.EOF, Line: 1, Column: 1}
+	if len(p.tokens) > 0 {
+		last := p.tokens[len(p.tokens)-1]
+		eof.Line = last.Line
+		eof.Column = last.Column + len(last.Literal)
+		eof.Offset = last.Offset + len(last.Literal)
+	}
+	return eof
+}
+
+// "a ${b} c ${d} e", this expects the first INTERPOLATION token to have
+// been eaten already. Every part of the string is followed by an expression,
+// until the INTERPOLATION_END token.
+func (p *Parser) interpolation() ast.Expression {
+	interpolation := &ast.InterpolationExpression{
+		Token: p.previous(),
+	}
+
+	for {
+		// Empty parts of the string can be left out
+		if p.previous().Literal != "" {
+			interpolation.Parts = append(interpolation.Parts, &ast.StringExpression{
+				Value: p.previous().Literal,
+			})
+		}
+
+		interpolation.Parts = append(interpolation.Parts, p.expression())
+
+		if p.match(token.INTERPOLATION_MIDDLE) {
+			continue
+		}
+		p.eat(token.INTERPOLATION_END, "Expect '}' after interpolated expression")
+		break
+	}
+
+	if p.previous().Literal != "" {
+		interpolation.Parts = append(interpolation.Parts, &ast.StringExpression{
+			Value: p.previous().Literal,
+		})
+	}
+	return interpolation
+}
+
+func (p *Parser) isAtEnd() bool {
+	return p.current >= len(p.tokens)
 }
 
 func (p *Parser) advance() {
@@ -780,19 +1127,14 @@ func (p *Parser) previous() token.Token {
 	current := p.current - 1
 
 	// Check == len(p.tokens) as it is length, so it starts from 0
-	if current < 0 || current == len(p.tokens) {
-		panic("Parsing an out of range index")
+	if current < 0 || current >= len(p.tokens) {
+		return p.peek()
 	}
 
 	return p.tokens[current]
 }
 
 func (p *Parser) eat(tokenType token.TokenType, message string) {
-	if p.current < 0 || p.current == len(p.tokens) {
-		globals.ErrorList = append(globals.ErrorList, errors.NewSyntaxError(p.peek(), message))
-		return
-	}
-
 	if p.peek().Type == tokenType {
 		p.current++
 		return
@@ -803,12 +1145,40 @@ func (p *Parser) eat(tokenType token.TokenType, message string) {
 	// 5: print(a);
 	// even though the syntax error is on line 4, it will show line 5,
 	// as it is trying to eat the next token that exists on line 5
-	if p.peek().Line != p.previous().Line {
-		globals.ErrorList = append(globals.ErrorList, errors.NewSyntaxError(p.previous(), message))
+	if p.current > 0 && p.peek().Line != p.previous().Line {
+		p.error(p.previous(), message)
 	} else {
-		globals.ErrorList = append(globals.ErrorList, errors.NewSyntaxError(p.peek(), message))
+		p.error(p.peek(), message)
+	}
+}
+
+// Records a syntax error and unwinds back up to the closest declaration.
+func (p *Parser) error(tok token.Token, message string) {
+	p.Errors = append(p.Errors, errors.NewSyntaxError(tok, message))
+	panic(parseError{})
+}
+
+// Skips tokens until it reaches what is most likely the start of
+// the next statement.
+func (p *Parser) synchronize() {
+	if !p.isAtEnd() {
+		p.advance()
 	}
 
+	for !p.isAtEnd() {
+		if p.previous().Type == token.SEMICOLON {
+			return
+		}
+
+		switch p.peek().Type {
+		case token.VAR, token.FUNCTION, token.STRUCT, token.IF,
+			token.FOR, token.WHILE, token.RETURN, token.BREAK, token.CONTINUE, token.IMPORT,
+			token.TRY, token.THROW:
+			return
+		}
+
+		p.advance()
+	}
 }
 
 func New(tokens []token.Token) *Parser {
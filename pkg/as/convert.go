@@ -0,0 +1,171 @@
+package as
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/lczm/as/errors"
+	"github.com/lczm/as/object"
+)
+
+// Converts a Go value into an object.
+//   - nil is null
+//   - bools, integers, floats and strings are their 'as' counterparts
+//   - slices and arrays are lists, maps are hashmaps
+//   - Functions are builtin functions
+//   - objects are given back as they are
+func ToObject(value interface{}) (object.Object, error) {
+	return toObject("function", value)
+}
+
+// name is what functions are called in the scripts
+func toObject(name string, value interface{}) (object.Object, error) {
+	switch value := value.(type) {
+	case nil:
+		return object.NullValue, nil
+	case object.Object:
+		return value, nil
+	case Function:
+		return builtinFunction(name, value), nil
+	case func(args ...object.Object) (object.Object, error):
+		return builtinFunction(name, value), nil
+	}
+
+	reflected := reflect.ValueOf(value)
+	switch reflected.Kind() {
+	case reflect.Bool:
+		return &object.Bool{Value: reflected.Bool()}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &object.Integer{Value: reflected.Int()}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &object.Integer{Value: int64(reflected.Uint())}, nil
+	case reflect.Float32, reflect.Float64:
+		return &object.Float{Value: reflected.Float()}, nil
+	case reflect.String:
+		return &object.String{Value: reflected.String()}, nil
+	case reflect.Slice, reflect.Array:
+		values := make([]object.Object, 0, reflected.Len())
+		for i := 0; i < reflected.Len(); i++ {
+			element, err := ToObject(reflected.Index(i).Interface())
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, element)
+		}
+		return &object.List{Value: values}, nil
+	case reflect.Map:
+		hashMap := make(map[object.HashKey]object.HashValue)
+		iterator := reflected.MapRange()
+		for iterator.Next() {
+			key, err := ToObject(iterator.Key().Interface())
+			if err != nil {
+				return nil, err
+			}
+			hashable, ok := key.(object.Hashable)
+			if !ok {
+				return nil, fmt.Errorf("Object of %s cannot be used as a hashmap key", key.RawType())
+			}
+			element, err := ToObject(iterator.Value().Interface())
+			if err != nil {
+				return nil, err
+			}
+			hashMap[hashable.Hash()] = object.HashValue{Key: key, Value: element}
+		}
+		return &object.HashMap{Value: hashMap}, nil
+	}
+	return nil, fmt.Errorf("Cannot convert a value of %T into an object", value)
+}
+
+// Converts an object into a Go value.
+//   - null is nil
+//   - integers are int64, floats are float64
+//   - bools and strings are their Go counterparts
+//   - lists are []interface{}
+//   - hashmaps are map[string]interface{} when all of their keys are
+//     strings, and map[interface{}]interface{} otherwise
+//   - instances of structs are map[string]interface{} of their attributes
+//
+// Functions and modules cannot be converted.
+func FromObject(obj object.Object) (interface{}, error) {
+	switch obj := obj.(type) {
+	case *object.Null:
+		return nil, nil
+	case *object.Bool:
+		return obj.Value, nil
+	case *object.Integer:
+		return obj.Value, nil
+	case *object.Float:
+		return obj.Value, nil
+	case *object.String:
+		return obj.Value, nil
+	case *object.List:
+		values := make([]interface{}, 0, len(obj.Value))
+		for _, element := range obj.Value {
+			value, err := FromObject(element)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return values, nil
+	case *object.HashMap:
+		return fromHashMap(obj)
+	case *object.Struct:
+		attributes := make(map[string]interface{}, len(obj.Attributes))
+		for name, attribute := range obj.Attributes {
+			value, err := FromObject(attribute)
+			if err != nil {
+				return nil, err
+			}
+			attributes[name] = value
+		}
+		return attributes, nil
+	}
+	return nil, fmt.Errorf("Cannot convert an object of %s into a Go value", obj.RawType())
+}
+
+func fromHashMap(hashMap *object.HashMap) (interface{}, error) {
+	values := make(map[interface{}]interface{}, len(hashMap.Value))
+	stringKeys := true
+	for _, pair := range hashMap.Value {
+		key, err := FromObject(pair.Key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := FromObject(pair.Value)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := key.(string); !ok {
+			stringKeys = false
+		}
+		values[key] = value
+	}
+
+	if !stringKeys {
+		return values, nil
+	}
+	stringValues := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		stringValues[key.(string)] = value
+	}
+	return stringValues, nil
+}
+
+// Errors from Go functions are raised at the call to them,
+// the same as the errors of builtin functions are
+func builtinFunction(name string, function Function) *object.BuiltinFunction {
+	return &object.BuiltinFunction{
+		Name: name,
+		Fn: func(args ...object.Object) object.Object {
+			result, err := function(args...)
+			if err != nil {
+				return &object.Error{Err: errors.NewDefaultError(err.Error())}
+			}
+			if result == nil {
+				return object.NullValue
+			}
+			return result
+		},
+	}
+}
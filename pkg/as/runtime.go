@@ -0,0 +1,238 @@
+// Package as runs 'as' scripts from within a Go program.
+//
+//	runtime := as.NewRuntime()
+//	runtime.Define("limit", 10)
+//	value, err := runtime.RunString("limit * 2")
+//
+// Every Runtime has its own environment, so any number of them can be
+// used side by side. A single Runtime is not safe for concurrent use.
+//
+// Scripts that cannot be trusted can be run with limits, and without
+// the builtins that reach outside of the script
+//
+//	runtime.SetLimits(as.Limits{Steps: 100000, Memory: 1 << 20})
+//	runtime.DisableIO()
+//	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+//	defer cancel()
+//	value, err := runtime.RunContext(ctx, program)
+//
+// Going over a limit gives back an errors.LimitExceeded.
+package as
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/lczm/as/analysis"
+	"github.com/lczm/as/ast"
+	"github.com/lczm/as/environment"
+	"github.com/lczm/as/errors"
+	"github.com/lczm/as/interpreter"
+	"github.com/lczm/as/lexer"
+	"github.com/lczm/as/object"
+	"github.com/lczm/as/parser"
+)
+
+type Runtime struct {
+	interpreter *interpreter.Interpreter
+	// The environment that the top level of every program is run in,
+	// it holds the builtins, what the host has defined and whatever
+	// the programs that have been run so far have declared
+	globals *environment.Environment
+	limits  Limits
+}
+
+// Limits on what a single run of a program, or a single call,
+// can use up. A limit that is 0 is not checked, other than
+// CallDepth which has a default.
+type Limits = interpreter.Limits
+
+// A program that has been compiled and can be run any number of times
+type Program struct {
+	statements []ast.Statement
+	// The file that the program comes from, imports are looked for
+	// relative to it. Empty when the program is not from a file.
+	file string
+}
+
+// All the errors that are found while compiling a program
+type Errors []errors.Error
+
+func (e Errors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "\n")
+}
+
+// Go functions that can be called from scripts. Errors that are given back
+// are raised as runtime errors at the call, a nil object is the same as null.
+type Function func(args ...object.Object) (object.Object, error)
+
+// Compiles source code, problems with the source are given back as Errors.
+// Names are resolved against what the runtime has defined so far, so values
+// that the program uses have to be defined before it is compiled.
+func (r *Runtime) Compile(source string) (*Program, error) {
+	return r.compile(source, "")
+}
+
+func (r *Runtime) compile(source string, file string) (*Program, error) {
+	lexer := lexer.New()
+	tokens := lexer.Scan(source)
+
+	// The same as the repl, the last expression does not need a
+	// trailing ';' i.e. 'limit * 2'
+	tokens = parser.TerminateExpression(tokens)
+
+	parser := parser.New(tokens)
+	statements := parser.Parse()
+
+	errorList := append(lexer.Errors, parser.Errors...)
+	if len(errorList) == 0 {
+		resolver := analysis.NewResolver(r.globals)
+		resolver.Resolve(statements)
+		errorList = resolver.Errors
+	}
+	if len(errorList) > 0 {
+		return nil, Errors(errorList)
+	}
+
+	return &Program{
+		statements: statements,
+		file:       file,
+	}, nil
+}
+
+// Runs a program, stopping at the first runtime error. The value of
+// the last statement is given back if it is an expression, or null if
+// it is not. Runtime errors are given back as an errors.Error.
+func (r *Runtime) Run(program *Program) (object.Object, error) {
+	return r.RunContext(context.Background(), program)
+}
+
+// The same as Run, but the program is stopped once ctx is cancelled
+func (r *Runtime) RunContext(ctx context.Context, program *Program) (value object.Object, err error) {
+	defer r.recoverError(&err)
+	r.start(ctx)
+	r.interpreter.File = program.file
+
+	value, errorObj := r.interpreter.Run(program.statements)
+	if errorObj != nil {
+		return nil, errorObj.Err
+	}
+	return value, nil
+}
+
+// Compiles and runs source code
+func (r *Runtime) RunString(source string) (object.Object, error) {
+	program, err := r.Compile(source)
+	if err != nil {
+		return nil, err
+	}
+	return r.Run(program)
+}
+
+// Compiles and runs a file, imports within it are looked for
+// relative to the file
+func (r *Runtime) RunFile(path string) (object.Object, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	program, err := r.compile(string(data), path)
+	if err != nil {
+		return nil, err
+	}
+	return r.Run(program)
+}
+
+// Defines a global that scripts can use, the value is converted
+// with ToObject. Functions are defined as builtins under the name.
+func (r *Runtime) Define(name string, value interface{}) error {
+	obj, err := toObject(name, value)
+	if err != nil {
+		return err
+	}
+	r.globals.Define(name, obj)
+	return nil
+}
+
+// Gives back a global, or nil if it has not been defined
+func (r *Runtime) Get(name string) object.Object {
+	return r.globals.Get(name)
+}
+
+// Calls a function that has been defined in the runtime,
+// the arguments are converted with ToObject.
+func (r *Runtime) Call(name string, args ...interface{}) (object.Object, error) {
+	return r.CallContext(context.Background(), name, args...)
+}
+
+// The same as Call, but the function is stopped once ctx is cancelled
+func (r *Runtime) CallContext(ctx context.Context, name string,
+	args ...interface{}) (value object.Object, err error) {
+
+	defer r.recoverError(&err)
+	r.start(ctx)
+	function := r.globals.Get(name)
+	if function == nil {
+		return nil, fmt.Errorf("Undefined function '%s'", name)
+	}
+
+	arguments := make([]object.Object, 0, len(args))
+	for _, arg := range args {
+		argument, err := ToObject(arg)
+		if err != nil {
+			return nil, err
+		}
+		arguments = append(arguments, argument)
+	}
+
+	obj := r.interpreter.CallFunction(function, arguments)
+	switch obj := obj.(type) {
+	case *object.Error:
+		return nil, obj.Err
+	case *object.Return:
+		return obj.Value, nil
+	}
+	return obj, nil
+}
+
+// Sets the limits that every run and call is held to from now on
+func (r *Runtime) SetLimits(limits Limits) {
+	r.limits = limits
+}
+
+// Removes the builtins that reach outside of the script, i.e. print(),
+// and stops files from being imported. Programs have to be compiled
+// after this for their use of these builtins to be reported.
+func (r *Runtime) DisableIO() {
+	r.interpreter.DisableIO()
+}
+
+// A script should not be able to bring down the program that runs it,
+// anything that panics while it runs is given back as an error instead
+func (r *Runtime) recoverError(err *error) {
+	if recovered := recover(); recovered != nil {
+		r.interpreter.Reset(r.globals)
+		*err = fmt.Errorf("Internal error : %v", recovered)
+	}
+}
+
+// Every run and call gets the whole of the limits to itself
+func (r *Runtime) start(ctx context.Context) {
+	r.interpreter.Context = ctx
+	r.interpreter.SetLimits(r.limits)
+}
+
+func NewRuntime() *Runtime {
+	interpreter := interpreter.New(nil)
+	return &Runtime{
+		interpreter: interpreter,
+		globals:     interpreter.Environment,
+	}
+}
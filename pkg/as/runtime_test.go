@@ -0,0 +1,357 @@
+package as
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lczm/as/errors"
+	"github.com/lczm/as/object"
+)
+
+func TestRunString(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedOutput string
+	}{
+		{"1 + 2", "3"},
+		{"var a = 10; a * 2;", "20"},
+		{`function f(a) { return a + "!"; } f("hi")`, "hi!"},
+		// Only the value of an expression is given back
+		{"var a = 1;", "null"},
+		{"len([1, 2, 3])", "3"},
+	}
+
+	for i, test := range tests {
+		runtime := NewRuntime()
+		obj, err := runtime.RunString(test.input)
+		if err != nil {
+			t.Fatalf("Test: [%d] - Unexpected error : %s", i, err)
+		}
+		if obj.String() != test.expectedOutput {
+			t.Fatalf("Test: [%d] - Incorrect value, expected=%s, got=%s",
+				i, test.expectedOutput, obj.String())
+		}
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	runtime := NewRuntime()
+
+	// Syntax errors and names that cannot be resolved are found
+	// before anything is run
+	_, err := runtime.RunString("var a = ;\nprint(b);")
+	errorList, ok := err.(Errors)
+	if !ok || len(errorList) != 1 || errorList[0].Kind() != errors.SYNTAX_ERROR {
+		t.Fatalf("Expected a syntax error, got=%v", err)
+	}
+	_, err = runtime.RunString("print(b);")
+	errorList, ok = err.(Errors)
+	if !ok || len(errorList) != 1 || errorList[0].Message() != "Undefined variable 'b'" {
+		t.Fatalf("Expected an undefined variable, got=%v", err)
+	}
+
+	_, err = runtime.RunString("var a = 1;\nvar b = a / 0;")
+	runtimeError, ok := err.(errors.Error)
+	if !ok || runtimeError.Kind() != errors.RUNTIME_ERROR || runtimeError.Span().Line != 2 {
+		t.Fatalf("Expected a runtime error on line 2, got=%v", err)
+	}
+
+	// The runtime can still be used after an error
+	obj, err := runtime.RunString("a + 1")
+	if err != nil || obj.String() != "2" {
+		t.Fatalf("Expected the runtime to carry on, got=%v, %v", obj, err)
+	}
+}
+
+func TestDefine(t *testing.T) {
+	runtime := NewRuntime()
+	values := map[string]interface{}{
+		"limit":   10,
+		"ratio":   0.5,
+		"name":    "as",
+		"enabled": true,
+		"tags":    []string{"a", "b"},
+		"config":  map[string]interface{}{"depth": 2},
+		"nothing": nil,
+	}
+	for name, value := range values {
+		if err := runtime.Define(name, value); err != nil {
+			t.Fatalf("Unexpected error defining %s : %s", name, err)
+		}
+	}
+
+	obj, err := runtime.RunString(`[limit * ratio, name, enabled, tags[1], config["depth"], nothing]`)
+	if err != nil {
+		t.Fatalf("Unexpected error : %s", err)
+	}
+	if obj.String() != "[5.0, as, true, b, 2, null]" {
+		t.Fatalf("Incorrect value, got=%s", obj.String())
+	}
+
+	if err := runtime.Define("channel", make(chan int)); err == nil {
+		t.Fatalf("Expected an error defining a channel")
+	}
+}
+
+func TestFunctions(t *testing.T) {
+	runtime := NewRuntime()
+	var logged []string
+	runtime.Define("log", Function(func(args ...object.Object) (object.Object, error) {
+		for _, arg := range args {
+			logged = append(logged, arg.String())
+		}
+		return nil, nil
+	}))
+	runtime.Define("fail", Function(func(args ...object.Object) (object.Object, error) {
+		return nil, fmt.Errorf("fail() was called")
+	}))
+
+	_, err := runtime.RunString(`log("a", 1); var x = log();`)
+	if err != nil {
+		t.Fatalf("Unexpected error : %s", err)
+	}
+	if !reflect.DeepEqual(logged, []string{"a", "1"}) {
+		t.Fatalf("Incorrect arguments, got=%v", logged)
+	}
+	if runtime.Get("x") != object.NullValue {
+		t.Fatalf("Expected nil to be given back as null, got=%v", runtime.Get("x"))
+	}
+
+	// Errors from Go functions point to where they are called
+	_, err = runtime.RunString("\n  fail();")
+	runtimeError, ok := err.(errors.Error)
+	if !ok || runtimeError.Message() != "fail() was called" || runtimeError.Span().Line != 2 {
+		t.Fatalf("Expected the error to be raised at the call, got=%v", err)
+	}
+
+	// Functions declared in scripts can be called from Go
+	_, err = runtime.RunString("function allow(user) { return user[\"age\"] >= 18; }")
+	if err != nil {
+		t.Fatalf("Unexpected error : %s", err)
+	}
+	obj, err := runtime.Call("allow", map[string]int{"age": 20})
+	if err != nil || obj.String() != "true" {
+		t.Fatalf("Incorrect result of the call, got=%v, %v", obj, err)
+	}
+	if _, err := runtime.Call("allow"); err == nil {
+		t.Fatalf("Expected an error for the wrong number of arguments")
+	}
+	if _, err := runtime.Call("missing"); err == nil {
+		t.Fatalf("Expected an error for an undefined function")
+	}
+}
+
+func TestPanics(t *testing.T) {
+	runtime := NewRuntime()
+	runtime.Define("explode", Function(func(args ...object.Object) (object.Object, error) {
+		panic("boom")
+	}))
+
+	// Panics are given back as errors, from within calls as well
+	_, err := runtime.RunString("function f() { explode(); } f();")
+	if err == nil || err.Error() != "Internal error : boom" {
+		t.Fatalf("Expected the panic to be given back as an error, got=%v", err)
+	}
+	if _, err := runtime.Call("explode"); err == nil {
+		t.Fatalf("Expected the panic in the call to be given back as an error")
+	}
+
+	// The runtime can still be used afterwards, from the top level
+	value, err := runtime.RunString("var after = 1; after")
+	if err != nil || value.String() != "1" || runtime.Get("after") == nil {
+		t.Fatalf("Expected the runtime to run at the top level, got=%v, err=%v", value, err)
+	}
+}
+
+func TestFromObject(t *testing.T) {
+	runtime := NewRuntime()
+	tests := []struct {
+		input         string
+		expectedValue interface{}
+	}{
+		{"1", int64(1)},
+		{"1.5", 1.5},
+		{`"a"`, "a"},
+		{"false", false},
+		{"null", nil},
+		{`[1, "a", [true]]`, []interface{}{int64(1), "a", []interface{}{true}}},
+		// A '{' at the start of a statement is a block
+		{
+			`var m = {"a": 1, "b": [2]}; m`,
+			map[string]interface{}{"a": int64(1), "b": []interface{}{int64(2)}},
+		},
+		{`var n = {1: "a"}; n`, map[interface{}]interface{}{int64(1): "a"}},
+		{"struct P { var x = 1; } P()", map[string]interface{}{"x": int64(1)}},
+	}
+
+	for i, test := range tests {
+		obj, err := runtime.RunString(test.input)
+		if err != nil {
+			t.Fatalf("Test: [%d] - Unexpected error : %s", i, err)
+		}
+		value, err := FromObject(obj)
+		if err != nil {
+			t.Fatalf("Test: [%d] - Unexpected error : %s", i, err)
+		}
+		if !reflect.DeepEqual(value, test.expectedValue) {
+			t.Fatalf("Test: [%d] - Incorrect value, expected=%#v, got=%#v",
+				i, test.expectedValue, value)
+		}
+	}
+
+	if _, err := FromObject(runtime.Get("len")); err == nil {
+		t.Fatalf("Expected an error converting a function")
+	}
+}
+
+func TestIndependentRuntimes(t *testing.T) {
+	first := NewRuntime()
+	second := NewRuntime()
+
+	if _, err := first.RunString("var a = 1;"); err != nil {
+		t.Fatalf("Unexpected error : %s", err)
+	}
+	if _, err := second.RunString("var a = 2;"); err != nil {
+		t.Fatalf("Unexpected error : %s", err)
+	}
+	if first.Get("a").String() != "1" || second.Get("a").String() != "2" {
+		t.Fatalf("Runtimes share their globals, got=%s and %s",
+			first.Get("a").String(), second.Get("a").String())
+	}
+
+	// Errors in one runtime do not leak into another
+	if _, err := first.RunString("var b = ;"); err == nil {
+		t.Fatalf("Expected a syntax error")
+	}
+	if _, err := second.RunString("a"); err != nil {
+		t.Fatalf("Unexpected error : %s", err)
+	}
+}
+
+func TestLimits(t *testing.T) {
+	tests := []struct {
+		limits          Limits
+		input           string
+		expectedMessage string
+	}{
+		{Limits{Steps: 1000}, "while (true) {}", "Exceeded the limit of 1000 steps"},
+		{
+			Limits{CallDepth: 50},
+			"function f(n) { return f(n + 1); } f(0);",
+			"Exceeded the limit of 50 nested calls",
+		},
+		{
+			Limits{Memory: 1024},
+			`var a = ""; while (true) { a = a + "abcdefgh"; }`,
+			"Exceeded the limit of 1024 bytes of memory",
+		},
+		{
+			Limits{Memory: 1024},
+			"var a = []; while (true) { a = append(a, 1); }",
+			"Exceeded the limit of 1024 bytes of memory",
+		},
+		// Builtins are stopped before they build something too large
+		{
+			Limits{Memory: 1 << 20},
+			`repeat("abcdefgh", 1000000000);`,
+			"Exceeded the limit of 1048576 bytes of memory",
+		},
+		{
+			Limits{Memory: 1 << 20},
+			`var a = []; for (i in range(100)) { a = append(a, "a"); } join(a, repeat(",", 100000));`,
+			"Exceeded the limit of 1048576 bytes of memory",
+		},
+		{
+			Limits{Memory: 1 << 20},
+			`format("%1000000d%1000000d", 1, 2);`,
+			"Exceeded the limit of 1048576 bytes of memory",
+		},
+		// Limits are reached from within functions passed to builtins
+		{
+			Limits{Steps: 1000},
+			"map([1], function(x) { while (true) {} });",
+			"Exceeded the limit of 1000 steps",
+		},
+	}
+
+	for i, test := range tests {
+		runtime := NewRuntime()
+		runtime.SetLimits(test.limits)
+		_, err := runtime.RunString(test.input)
+
+		limitError, ok := err.(errors.LimitExceeded)
+		if !ok {
+			t.Fatalf("Test: [%d] - Expected the limit to be exceeded, got=%v", i, err)
+		}
+		if limitError.Message() != test.expectedMessage {
+			t.Fatalf("Test: [%d] - Wrong message, expected=%q, got=%q",
+				i, test.expectedMessage, limitError.Message())
+		}
+	}
+
+	// Appending only counts the element that is added, not the whole list
+	runtime := NewRuntime()
+	runtime.SetLimits(Limits{Memory: 1 << 20})
+	value, err := runtime.RunString("var a = []; for (i in range(5000)) { a = append(a, i); } len(a)")
+	if err != nil || value.String() != "5000" {
+		t.Fatalf("Expected a list of 5000 integers, got=%v, err=%v", value, err)
+	}
+
+	// Every run gets the whole of the limits
+	runtime = NewRuntime()
+	runtime.SetLimits(Limits{Steps: 100})
+	for i := 0; i < 10; i++ {
+		if _, err := runtime.RunString("var a = 1 + 2;"); err != nil {
+			t.Fatalf("Unexpected error : %s", err)
+		}
+	}
+}
+
+func TestCancel(t *testing.T) {
+	runtime := NewRuntime()
+	program, err := runtime.Compile("while (true) {}")
+	if err != nil {
+		t.Fatalf("Unexpected error : %s", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = runtime.RunContext(ctx, program)
+	if _, ok := err.(errors.LimitExceeded); !ok {
+		t.Fatalf("Expected the program to be cancelled, got=%v", err)
+	}
+
+	_, err = runtime.RunString("function spin() { while (true) {} }")
+	if err != nil {
+		t.Fatalf("Unexpected error : %s", err)
+	}
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if _, err := runtime.CallContext(ctx, "spin"); err == nil {
+		t.Fatalf("Expected the call to be cancelled")
+	}
+}
+
+func TestDisableIO(t *testing.T) {
+	runtime := NewRuntime()
+	runtime.DisableIO()
+
+	_, err := runtime.RunString(`print("hi");`)
+	errorList, ok := err.(Errors)
+	if !ok || errorList[0].Message() != "Undefined variable 'print'" {
+		t.Fatalf("Expected print() to be undefined, got=%v", err)
+	}
+
+	_, err = runtime.RunString(`import "geometry.as";`)
+	if err == nil || err.(errors.Error).Message() != "Imports are disabled" {
+		t.Fatalf("Expected imports to be disabled, got=%v", err)
+	}
+
+	// Everything else is still there
+	if _, err := runtime.RunString("len([1, 2])"); err != nil {
+		t.Fatalf("Unexpected error : %s", err)
+	}
+}
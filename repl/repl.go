@@ -0,0 +1,154 @@
+package repl
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/lczm/as/ast"
+	"github.com/lczm/as/errors"
+	"github.com/lczm/as/interpreter"
+	"github.com/lczm/as/lexer"
+	"github.com/lczm/as/object"
+	"github.com/lczm/as/parser"
+	"github.com/lczm/as/token"
+)
+
+const (
+	PROMPT       = ">> "
+	CONTINUATION = ".. "
+)
+
+// Start runs a read-eval-print loop until the input is exhausted or the
+// user types 'exit'. A single interpreter (and its environment) is kept
+// around for the whole session so that definitions carry over between inputs.
+func Start(in io.Reader, out io.Writer) {
+	scanner := bufio.NewScanner(in)
+	lexer := lexer.New()
+	interpreter := interpreter.New(nil)
+
+	// Keep a reference to the global environment, if an input fails halfway
+	// through a block, the interpreter has to be reset back to this.
+	globalEnvironment := interpreter.Environment
+
+	var buffer strings.Builder
+	for {
+		if buffer.Len() == 0 {
+			fmt.Fprint(out, PROMPT)
+		} else {
+			fmt.Fprint(out, CONTINUATION)
+		}
+
+		if !scanner.Scan() {
+			fmt.Fprintln(out)
+			return
+		}
+
+		line := scanner.Text()
+		if buffer.Len() == 0 && strings.TrimSpace(line) == "exit" {
+			return
+		}
+
+		buffer.WriteString(line)
+		buffer.WriteString("\n")
+
+		source := buffer.String()
+		tokens := lexer.Scan(source)
+		// Unbalanced '{', '(' or '[', or a raw string that has not been
+		// closed, keep reading until they are closed.
+		open := depth(tokens)
+		if open > 0 || unterminatedRawString(lexer.Errors) {
+			continue
+		}
+		buffer.Reset()
+
+		if open < 0 {
+			fmt.Fprintln(out, "Error : Unexpected closing bracket")
+			continue
+		}
+
+		if reportErrors(out, source, lexer.Errors) {
+			continue
+		}
+
+		if len(tokens) == 0 {
+			continue
+		}
+
+		tokens = parser.TerminateExpression(tokens)
+		evaluate(out, interpreter, tokens, source)
+		interpreter.Environment = globalEnvironment
+	}
+}
+
+// Parses and evaluates a single input, printing the values of expression
+// statements. Errors are reported and the session carries on.
+func evaluate(out io.Writer, interpreter *interpreter.Interpreter,
+	tokens []token.Token, source string) {
+	// Nothing should panic at this point, but a bug in the interpreter
+	// should not take down the whole session either
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Fprintf(out, "Error : %v\n", r)
+		}
+	}()
+
+	parser := parser.New(tokens)
+	statements := parser.Parse()
+
+	if reportErrors(out, source, parser.Errors) {
+		return
+	}
+
+	interpreter.Resolve(statements)
+	for _, stmt := range statements {
+		value, errorObj := interpreter.Run([]ast.Statement{stmt})
+		if errorObj != nil {
+			fmt.Fprint(out, object.Traceback(errorObj.Trace))
+			reportErrors(out, source, []errors.Error{errorObj.Err})
+			return
+		}
+
+		// Null is not printed, so that calls to functions that do not
+		// return anything, i.e. print(), do not clutter the session
+		if value != object.NullValue {
+			fmt.Fprintln(out, value.FormattedString())
+		}
+	}
+}
+
+// Prints out errors with the part of the input that caused them,
+// returns true if there were any.
+func reportErrors(out io.Writer, source string, errorList []errors.Error) bool {
+	for _, error := range errorList {
+		fmt.Fprintln(out, error.Error())
+		fmt.Fprint(out, errors.Excerpt(source, error.Span()))
+	}
+	return len(errorList) > 0
+}
+
+// Whether the input ends within a raw string, which carries on
+// over more than one line
+func unterminatedRawString(errorList []errors.Error) bool {
+	for _, error := range errorList {
+		if error.Message() == lexer.UNTERMINATED_RAW_STRING {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns how many '{', '(' and '[' have not been closed yet.
+func depth(tokens []token.Token) int {
+	depth := 0
+	for _, tok := range tokens {
+		switch tok.Type {
+		case token.LBRACE, token.LPAREN, token.LBRACKET:
+			depth++
+		case token.RBRACE, token.RPAREN, token.RBRACKET:
+			depth--
+		}
+	}
+	return depth
+}
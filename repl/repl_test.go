@@ -0,0 +1,64 @@
+package repl
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestREPL(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedOutput []string
+	}{
+		{ // Expressions are printed, with or without the trailing ';'
+			"1 + 2;\n5 * 2\n",
+			[]string{"3", "10"},
+		},
+		{ // Definitions carry over between inputs
+			"var a = 10;\na += 5;\na\n",
+			[]string{"15", "15"},
+		},
+		{ // Multi-line input with unbalanced braces
+			"function add(a, b) {\nreturn a + b;\n}\nadd(1, 2)\n",
+			[]string{"3"},
+		},
+		{ // Strings are printed with their quotes
+			"var s = [\n\"a\"\n];\ns[0]\n",
+			[]string{"\"a\""},
+		},
+		{ // Keeps going after an error
+			")\n1 + 1\n",
+			[]string{"Error : Unexpected closing bracket", "2"},
+		},
+		{ // Raw strings can span multiple lines, special characters are escaped when printed
+			"var s = `a\nb`;\ns\n'tab\\t'\n",
+			[]string{"\"a\\nb\"", "\"tab\\t\""},
+		},
+		{ // Null values are not printed
+			"var b;\nb\nfunction f() {}\nf()\nb == null\n",
+			[]string{"true"},
+		},
+	}
+
+	for i, test := range tests {
+		var out bytes.Buffer
+		Start(strings.NewReader(test.input), &out)
+
+		var output []string
+		for _, line := range strings.Split(out.String(), "\n") {
+			// Strip off all the prompts before the value
+			for strings.HasPrefix(line, PROMPT) || strings.HasPrefix(line, CONTINUATION) {
+				line = line[len(PROMPT):]
+			}
+			if line != "" {
+				output = append(output, line)
+			}
+		}
+
+		if strings.Join(output, "|") != strings.Join(test.expectedOutput, "|") {
+			t.Fatalf("Test : [%d] - Mismatch in output, expected=%q, got=%q",
+				i, test.expectedOutput, output)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+// Package testrunner runs the tests of 'as' programs, for 'as test'.
+//
+// Tests live in files named test_*.as, every top level function in
+// them that is named test_* is a test. Each test is run on a fresh
+// interpreter, so tests cannot see what other tests have done, and
+// fails if it raises an error, i.e. through assert() or assertEqual().
+package testrunner
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lczm/as/analysis"
+	"github.com/lczm/as/ast"
+	"github.com/lczm/as/errors"
+	"github.com/lczm/as/interpreter"
+	"github.com/lczm/as/lexer"
+	"github.com/lczm/as/object"
+	"github.com/lczm/as/parser"
+)
+
+const PREFIX = "test_"
+
+type Runner struct {
+	// Where the results of the tests are written to
+	Out    io.Writer
+	Passed int
+	Failed int
+}
+
+func New(out io.Writer) *Runner {
+	return &Runner{Out: out}
+}
+
+// Runs the tests in the given files, and in the test files under the
+// given directories. The current directory is used when there are none.
+// Errors are only given back when the paths cannot be read, failing
+// tests are counted in Failed.
+func (r *Runner) Run(paths []string) error {
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	files, err := Discover(paths)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if err := r.RunFile(file); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(r.Out, "%d passed, %d failed\n", r.Passed, r.Failed)
+	return nil
+}
+
+// Finds the test files under the paths, files that are named
+// directly are always included.
+func Discover(paths []string) ([]string, error) {
+	files := make([]string, 0)
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		err = filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() && isTestFile(info.Name()) {
+				files = append(files, file)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+func isTestFile(name string) bool {
+	return strings.HasPrefix(name, PREFIX) && strings.HasSuffix(name, ".as")
+}
+
+// Runs every test in a file. A file that does not compile, or that
+// raises an error at the top level, counts as a single failure.
+func (r *Runner) RunFile(file string) error {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	source := string(data)
+
+	lexer := lexer.New()
+	tokens := lexer.Scan(source)
+	parser := parser.New(tokens)
+	statements := parser.Parse()
+
+	errorList := append(lexer.Errors, parser.Errors...)
+	if len(errorList) == 0 {
+		semanticAnalyzer := analysis.New(statements)
+		semanticAnalyzer.Analyze()
+		errorList = semanticAnalyzer.Errors
+	}
+	if len(errorList) > 0 {
+		r.fail(file, source, errorList...)
+		return nil
+	}
+
+	for _, name := range tests(statements) {
+		r.runTest(file, source, statements, name)
+	}
+	return nil
+}
+
+// The names of the tests in a file, in the order they are declared
+func tests(statements []ast.Statement) []string {
+	names := make([]string, 0)
+	for _, stmt := range statements {
+		function, ok := stmt.(*ast.FunctionStatement)
+		if ok && strings.HasPrefix(function.Name.Literal, PREFIX) {
+			names = append(names, function.Name.Literal)
+		}
+	}
+	return names
+}
+
+// The top level of the file is run again for every test, so that every
+// test starts from the same globals
+func (r *Runner) runTest(file string, source string, statements []ast.Statement, name string) {
+	interpreter := interpreter.New(statements)
+	interpreter.File = file
+	if err := interpreter.Start(); err != nil {
+		r.fail(file+" : "+name, source, err.(errors.Error))
+		return
+	}
+
+	obj := interpreter.CallFunction(interpreter.Environment.Get(name), []object.Object{})
+	if errorObj, ok := obj.(*object.Error); ok {
+		r.fail(file+" : "+name, source, errorObj.Err)
+		return
+	}
+
+	r.Passed++
+	fmt.Fprintf(r.Out, "PASS %s : %s\n", file, name)
+}
+
+func (r *Runner) fail(name string, source string, errorList ...errors.Error) {
+	r.Failed++
+	fmt.Fprintf(r.Out, "FAIL %s\n", name)
+	for _, err := range errorList {
+		fmt.Fprintf(r.Out, "    %s\n", err.Error())
+		fmt.Fprint(r.Out, errors.Excerpt(source, err.Span()))
+	}
+}
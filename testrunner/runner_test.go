@@ -0,0 +1,67 @@
+package testrunner
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiscover(t *testing.T) {
+	files, err := Discover([]string{"testdata"})
+	if err != nil {
+		t.Fatalf("Unexpected error : %s", err)
+	}
+	expectedFiles := []string{
+		filepath.Join("testdata", "test_broken.as"),
+		filepath.Join("testdata", "test_math.as"),
+	}
+	if !reflect.DeepEqual(files, expectedFiles) {
+		t.Fatalf("Incorrect files, expected=%v, got=%v", expectedFiles, files)
+	}
+
+	// Files that are named are run even if they do not look like tests
+	file := filepath.Join("testdata", "helpers.as")
+	files, err = Discover([]string{file})
+	if err != nil || !reflect.DeepEqual(files, []string{file}) {
+		t.Fatalf("Expected the file to be used as it is, got=%v, %v", files, err)
+	}
+
+	if _, err := Discover([]string{"missing"}); err == nil {
+		t.Fatalf("Expected an error for a path that does not exist")
+	}
+}
+
+func TestRun(t *testing.T) {
+	var out bytes.Buffer
+	runner := New(&out)
+	if err := runner.Run([]string{"testdata"}); err != nil {
+		t.Fatalf("Unexpected error : %s", err)
+	}
+
+	if runner.Passed != 5 || runner.Failed != 2 {
+		t.Fatalf("Incorrect counts, expected=5 passed and 2 failed, got=%d and %d\n%s",
+			runner.Passed, runner.Failed, out.String())
+	}
+
+	output := out.String()
+	expectedLines := []string{
+		"FAIL " + filepath.Join("testdata", "test_broken.as"),
+		"PASS " + filepath.Join("testdata", "test_math.as") + " : test_add",
+		// Every test gets its own globals
+		"PASS " + filepath.Join("testdata", "test_math.as") + " : test_globals_are_fresh_again",
+		"FAIL " + filepath.Join("testdata", "test_math.as") + " : test_wrong",
+		"Assertion Error at line '26', column '16' : assertEqual() failed : expected [4], got 3",
+		"5 passed, 2 failed",
+	}
+	for _, line := range expectedLines {
+		if !strings.Contains(output, line) {
+			t.Fatalf("Expected the output to contain %q, got=\n%s", line, output)
+		}
+	}
+	// Only functions named test_* are tests
+	if strings.Contains(output, "helper") {
+		t.Fatalf("Expected helper() not to be run, got=\n%s", output)
+	}
+}
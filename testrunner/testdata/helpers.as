@@ -0,0 +1,3 @@
+function test_ignored() {
+    assert(false);
+}
@@ -0,0 +1,36 @@
+var total = 0;
+
+function add(a, b) {
+    return a + b;
+}
+
+function test_add() {
+    assertEqual(add(1, 2), 3);
+}
+
+function test_globals_are_fresh() {
+    total = total + 1;
+    assertEqual(total, 1);
+}
+
+function test_globals_are_fresh_again() {
+    total = total + 1;
+    assertEqual(total, 1);
+}
+
+function test_lists() {
+    assertEqual([add(1, 1), {"a": 1}], [2, {"a": 1}]);
+}
+
+function test_wrong() {
+    assertEqual(add(1, 2), [4]);
+}
+
+function test_throws() {
+    var exception = assertThrows(function() { throw "no"; }, "no");
+    assertEqual(exception.value, "no");
+}
+
+function helper() {
+    assert(false, "not a test");
+}
@@ -4,7 +4,6 @@ import (
 	"strconv"
 	"testing"
 
-	"github.com/lczm/as/interpreter"
 	"github.com/lczm/as/lexer"
 	"github.com/lczm/as/parser"
 )
@@ -59,23 +58,25 @@ func TestAugmentedAssignsFunc(t *testing.T) {
 		parser := parser.New(tokens)
 		statements := parser.Parse()
 
-		interpreter := interpreter.New(statements)
-		interpreter.Start()
+		for _, engine := range engines {
+			interpreter := engine.new(statements, "")
+			interpreter.Start()
 
-		// Directly hook into the environment to check for output variable.
-		if interpreter.Environment.Exists(outputVariable) {
-			obj := interpreter.Environment.Get(outputVariable)
-			// The object returns with strings, this should be fixed in the future, but
-			// for the time being this should do well enough for tests
+			// Directly hook into the environment to check for output variable.
+			if interpreter.Environment.Exists(outputVariable) {
+				obj := interpreter.Environment.Get(outputVariable)
+				// The object returns with strings, this should be fixed in the future, but
+				// for the time being this should do well enough for tests
 
-			value, err := strconv.Atoi(obj.String())
-			if err != nil {
-				panic(err)
-			}
+				value, err := strconv.Atoi(obj.String())
+				if err != nil {
+					panic(err)
+				}
 
-			if value != test.expectedOutput {
-				t.Fatalf("Test: [%d] - Incorrect value, expected=%d, got=%d",
-					i, test.expectedOutput, value)
+				if value != test.expectedOutput {
+					t.Fatalf(engine.name+" : Test: [%d] - Incorrect value, expected=%d, got=%d",
+						i, test.expectedOutput, value)
+				}
 			}
 		}
 	}
@@ -5,7 +5,6 @@ import (
 	"strings"
 	"testing"
 
-	"github.com/lczm/as/interpreter"
 	"github.com/lczm/as/lexer"
 	"github.com/lczm/as/parser"
 )
@@ -33,21 +32,23 @@ func TestLenFunc(t *testing.T) {
 		parser := parser.New(tokens)
 		statements := parser.Parse()
 
-		interpreter := interpreter.New(statements)
-		interpreter.Start()
+		for _, engine := range engines {
+			interpreter := engine.new(statements, "")
+			interpreter.Start()
 
-		// Directly hook into the environment to check for output variable.
-		if interpreter.Environment.Exists(outputVariable) {
-			obj := interpreter.Environment.Get(outputVariable)
+			// Directly hook into the environment to check for output variable.
+			if interpreter.Environment.Exists(outputVariable) {
+				obj := interpreter.Environment.Get(outputVariable)
 
-			value, err := strconv.Atoi(obj.String())
-			if err != nil {
-				panic(err)
-			}
+				value, err := strconv.Atoi(obj.String())
+				if err != nil {
+					panic(err)
+				}
 
-			if value != test.expectedOutput {
-				t.Fatalf("Test: [%d] - Incorrect value, expected=%d, got=%d",
-					i, test.expectedOutput, value)
+				if value != test.expectedOutput {
+					t.Fatalf(engine.name+" : Test: [%d] - Incorrect value, expected=%d, got=%d",
+						i, test.expectedOutput, value)
+				}
 			}
 		}
 	}
@@ -76,15 +77,17 @@ func TestTypeFunc(t *testing.T) {
 		parser := parser.New(tokens)
 		statements := parser.Parse()
 
-		interpreter := interpreter.New(statements)
-		interpreter.Start()
+		for _, engine := range engines {
+			interpreter := engine.new(statements, "")
+			interpreter.Start()
 
-		// Directly hook into the environment to check for output variable.
-		if interpreter.Environment.Exists(outputVariable) {
-			obj := interpreter.Environment.Get(outputVariable)
-			if obj.String() != test.expectedOutput {
-				t.Fatalf("Test: [%d] - Incorrect value, expected=%s, got=%s",
-					i, test.expectedOutput, obj.String())
+			// Directly hook into the environment to check for output variable.
+			if interpreter.Environment.Exists(outputVariable) {
+				obj := interpreter.Environment.Get(outputVariable)
+				if obj.String() != test.expectedOutput {
+					t.Fatalf(engine.name+" : Test: [%d] - Incorrect value, expected=%s, got=%s",
+						i, test.expectedOutput, obj.String())
+				}
 			}
 		}
 	}
@@ -126,17 +129,19 @@ func TestAppendFunc(t *testing.T) {
 		parser := parser.New(tokens)
 		statements := parser.Parse()
 
-		interpreter := interpreter.New(statements)
-		interpreter.Start()
+		for _, engine := range engines {
+			interpreter := engine.new(statements, "")
+			interpreter.Start()
 
-		// Directly hook into the environment to check for output variable.
-		if interpreter.Environment.Exists(outputVariable) {
-			obj := interpreter.Environment.Get(outputVariable)
-			// The object returns with strings, this should be fixed in the future, but
-			// for the time being this should do well enough for tests
-			if strings.TrimSuffix(obj.String(), "\n") != test.expectedOutput {
-				t.Fatalf("Test: [%d] - Incorrect value, expected=%s, got=%s",
-					i, test.expectedOutput, obj.String())
+			// Directly hook into the environment to check for output variable.
+			if interpreter.Environment.Exists(outputVariable) {
+				obj := interpreter.Environment.Get(outputVariable)
+				// The object returns with strings, this should be fixed in the future, but
+				// for the time being this should do well enough for tests
+				if strings.TrimSuffix(obj.String(), "\n") != test.expectedOutput {
+					t.Fatalf(engine.name+" : Test: [%d] - Incorrect value, expected=%s, got=%s",
+						i, test.expectedOutput, obj.String())
+				}
 			}
 		}
 	}
@@ -166,17 +171,418 @@ func TestRemoveAtFunc(t *testing.T) {
 		parser := parser.New(tokens)
 		statements := parser.Parse()
 
-		interpreter := interpreter.New(statements)
-		interpreter.Start()
+		for _, engine := range engines {
+			interpreter := engine.new(statements, "")
+			interpreter.Start()
+
+			// Directly hook into the environment to check for output variable.
+			if interpreter.Environment.Exists(outputVariable) {
+				obj := interpreter.Environment.Get(outputVariable)
+				// The object returns with strings, this should be fixed in the future, but
+				// for the time being this should do well enough for tests
+				if strings.TrimSuffix(obj.String(), "\n") != test.expectedOutput {
+					t.Fatalf(engine.name+" : Test: [%d] - Incorrect value, expected=%s, got=%s",
+						i, test.expectedOutput, obj.String())
+				}
+			}
+		}
+	}
+}
+
+func TestHigherOrderFuncs(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedOutput string
+	}{
+		{
+			`var output = map([1, 2, 3], function(x) { return x * 2; });`,
+			"[2, 4, 6]",
+		},
+		{
+			`var output = map([1, 2, 3], x => x + 1);`,
+			"[2, 3, 4]",
+		},
+		{
+			`var output = filter([1, 2, 3, 4], x => x % 2 == 0);`,
+			"[2, 4]",
+		},
+		{
+			`var output = sort([3, 1, 2]);`,
+			"[1, 2, 3]",
+		},
+		{
+			`var output = sort(["b", "c", "a"]);`,
+			"[a, b, c]",
+		},
+		{
+			`var output = sort([3, 1, 2], (a, b) => a > b);`,
+			"[3, 2, 1]",
+		},
+		{
+			`var output = sort([3, 1, 2], function(a, b) { return a - b; });`,
+			"[1, 2, 3]",
+		},
+		{ // Builtin functions can be passed in as well
+			`var output = map(["a", "bc"], len);`,
+			"[1, 2]",
+		},
+		{ // The original list is left untouched
+			`
+			var list = [2, 1];
+			var sorted = sort(list);
+			var output = list;
+			`,
+			"[2, 1]",
+		},
+	}
+
+	outputVariable := "output"
+	lexer := lexer.New()
+
+	for i, test := range tests {
+		tokens := lexer.Scan(test.input)
+		parser := parser.New(tokens)
+		statements := parser.Parse()
+
+		for _, engine := range engines {
+			interpreter := engine.new(statements, "")
+			if err := interpreter.Start(); err != nil {
+				t.Fatalf(engine.name+" : Test: [%d] - Unexpected error : %s", i, err)
+			}
+
+			obj := interpreter.Environment.Get(outputVariable)
+			if obj == nil || obj.String() != test.expectedOutput {
+				t.Fatalf(engine.name+" : Test: [%d] - Incorrect value, expected=%s, got=%v",
+					i, test.expectedOutput, obj)
+			}
+		}
+	}
+}
+
+func TestCallStackFunc(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedOutput string
+	}{
+		{`var output = callstack();`, "[]"},
+		{
+			`
+			function inner() {
+				return callstack();
+			}
+			function outer() {
+				return inner();
+			}
+			var calls = outer();
+			var output = map(calls, c => "${c["function"]}:${c["line"]}");
+			`,
+			"[outer:8, inner:6]",
+		},
+		{ // Functions that builtins call are not called from a line
+			`
+			var calls = map([1], x => callstack())[0];
+			var output = map(calls, c => "${c["function"]}:${c["line"]}");
+			`,
+			"[anonymous:0]",
+		},
+		{ // Methods are called by their name
+			`
+			struct Point {
+				where() {
+					return callstack()[0]["function"];
+				}
+			}
+			var output = Point().where();
+			`,
+			"where",
+		},
+	}
+
+	outputVariable := "output"
+	lexer := lexer.New()
+
+	for i, test := range tests {
+		tokens := lexer.Scan(test.input)
+		parser := parser.New(tokens)
+		statements := parser.Parse()
+
+		for _, engine := range engines {
+			interpreter := engine.new(statements, "")
+			if err := interpreter.Start(); err != nil {
+				t.Fatalf(engine.name+" : Test: [%d] - Unexpected error : %s", i, err)
+			}
+
+			obj := interpreter.Environment.Get(outputVariable)
+			if obj == nil || obj.String() != test.expectedOutput {
+				t.Fatalf(engine.name+" : Test: [%d] - Incorrect value, expected=%s, got=%v",
+					i, test.expectedOutput, obj)
+			}
+		}
+	}
+}
+
+func TestAssertFuncs(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedOutput string
+	}{
+		{`assert(1 < 2); var output = assert(true, "never");`, "null"},
+		{`assertEqual(1, 1.0); var output = "equal";`, "equal"},
+		// Lists, hashmaps and instances are compared by what they hold
+		{`assertEqual([1, {"a": [2]}], [1, {"a": [2]}]); var output = "equal";`, "equal"},
+		{
+			`
+			struct Point {
+				var x = 0;
+			}
+			var p = Point();
+			p.x = 1;
+			var q = Point();
+			q.x = 1;
+			assertEqual(p, q);
+			var output = "equal";
+			`,
+			"equal",
+		},
+		{`var output = assertThrows(function() { throw "no"; }).value;`, "no"},
+		{`var output = assertThrows(function() { return 1 / 0; }).kind;`, "RuntimeError"},
+		{`var output = assertThrows(function() { throw 1; }, "1").message;`, "1"},
+	}
+
+	outputVariable := "output"
+	lexer := lexer.New()
+
+	for i, test := range tests {
+		tokens := lexer.Scan(test.input)
+		parser := parser.New(tokens)
+		statements := parser.Parse()
+
+		for _, engine := range engines {
+			interpreter := engine.new(statements, "")
+			if err := interpreter.Start(); err != nil {
+				t.Fatalf(engine.name+" : Test: [%d] - Unexpected error : %s", i, err)
+			}
+
+			obj := interpreter.Environment.Get(outputVariable)
+			if obj == nil || obj.String() != test.expectedOutput {
+				t.Fatalf(engine.name+" : Test: [%d] - Incorrect value, expected=%s, got=%v",
+					i, test.expectedOutput, obj)
+			}
+		}
+	}
+}
+
+func TestConversionFuncs(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedOutput string
+	}{
+		{`var output = float(2);`, "2.0"},
+		{`var output = float("2.5");`, "2.5"},
+		{`var output = float(1.5);`, "1.5"},
+		{`var output = int(3.99);`, "3"},
+		{`var output = int(-3.99);`, "-3"},
+		{`var output = int("42");`, "42"},
+		{`var output = int(7);`, "7"},
+		{`var output = sort([3, 1.5, 2, 0.5]);`, "[0.5, 1.5, 2, 3]"},
+	}
+
+	outputVariable := "output"
+	lexer := lexer.New()
+
+	for i, test := range tests {
+		tokens := lexer.Scan(test.input)
+		parser := parser.New(tokens)
+		statements := parser.Parse()
+
+		for _, engine := range engines {
+			interpreter := engine.new(statements, "")
+			if err := interpreter.Start(); err != nil {
+				t.Fatalf(engine.name+" : Test: [%d] - Unexpected error : %s", i, err)
+			}
+
+			obj := interpreter.Environment.Get(outputVariable)
+			if obj == nil || obj.String() != test.expectedOutput {
+				t.Fatalf(engine.name+" : Test: [%d] - Incorrect value, expected=%s, got=%v",
+					i, test.expectedOutput, obj)
+			}
+		}
+	}
+}
+
+func TestRangeAndKeysFuncs(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedOutput string
+	}{
+		{`var output = range(5);`, "range(0, 5, 1)"},
+		{`var output = len(range(2, 5));`, "3"},
+		{`var output = len(range(0, 10, 3));`, "4"},
+		{`var output = len(range(10, 0, -3));`, "4"},
+		{`var output = len(range(5, 0));`, "0"},
+		{`var output = len(range(-9223372036854775807, 9223372036854775807, 2));`, "9223372036854775807"},
+		{`var output = len(range(9223372036854775807, -9223372036854775807, -3));`, "6148914691236517205"},
+		{`
+		var output = [];
+		for (i in range(9223372036854775805, 9223372036854775807)) {
+			output = append(output, i);
+		}
+		`, "[9223372036854775805, 9223372036854775806]"},
+		{`var output = keys({"b": 1, "a": 2, 1: 3});`, "[1, a, b]"},
+		{`var output = keys({});`, "[]"},
+	}
+
+	outputVariable := "output"
+	lexer := lexer.New()
+
+	for i, test := range tests {
+		tokens := lexer.Scan(test.input)
+		parser := parser.New(tokens)
+		statements := parser.Parse()
+
+		for _, engine := range engines {
+			interpreter := engine.new(statements, "")
+			if err := interpreter.Start(); err != nil {
+				t.Fatalf(engine.name+" : Test: [%d] - Unexpected error : %s", i, err)
+			}
+
+			obj := interpreter.Environment.Get(outputVariable)
+			if obj == nil || obj.String() != test.expectedOutput {
+				t.Fatalf(engine.name+" : Test: [%d] - Incorrect value, expected=%s, got=%v",
+					i, test.expectedOutput, obj)
+			}
+		}
+	}
+}
+
+func TestStringFuncs(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedOutput string
+	}{
+		{`var output = split("a,b,c", ",");`, "[a, b, c]"},
+		{`var output = split("héy", "");`, "[h, é, y]"},
+		{`var output = join(["a", 1, 2.5], "-");`, "a-1-2.5"},
+		{`var output = join([], ", ");`, ""},
+		{`var output = trim("  hi\t\n");`, "hi"},
+		{`var output = upper("abc");`, "ABC"},
+		{`var output = lower("ABC");`, "abc"},
+		{`var output = replace("a-b-c", "-", "+");`, "a+b+c"},
+		{`var output = contains("hello", "ell");`, "true"},
+		{`var output = contains("hello", "z");`, "false"},
+		{`var output = startsWith("hello", "he");`, "true"},
+		{`var output = endsWith("hello", "he");`, "false"},
+		{`var output = indexOf("héllo", "l");`, "2"},
+		{`var output = indexOf("hello", "z");`, "-1"},
+		{`var output = substring("héllo", 1);`, "éllo"},
+		{`var output = substring("hello", 1, 3);`, "el"},
+		{`var output = substring("hello", 5, 5);`, ""},
+		{`var output = repeat("ab", 3);`, "ababab"},
+		{`var output = repeat("ab", 0);`, ""},
+		{`var output = format("%s is %d", "a", 10);`, "a is 10"},
+		{`var output = format("%.2f|%5d|%-3s|", 3.14159, 42, "x");`, "3.14|   42|x  |"},
+		{`var output = format("%f", 2);`, "2.000000"},
+		{`var output = format("%q %s %x", "a", [1, "b"], 255);`, "\"a\" [1, b] ff"},
+		{`var output = format("100%%");`, "100%"},
+		{`var output = ord("a");`, "97"},
+		{`var output = ord("é");`, "233"},
+		{`var output = chr(97);`, "a"},
+		{`var output = chr(ord("a") + 1);`, "b"},
+	}
+
+	outputVariable := "output"
+	lexer := lexer.New()
+
+	for i, test := range tests {
+		tokens := lexer.Scan(test.input)
+		parser := parser.New(tokens)
+		statements := parser.Parse()
+
+		for _, engine := range engines {
+			interpreter := engine.new(statements, "")
+			if err := interpreter.Start(); err != nil {
+				t.Fatalf(engine.name+" : Test: [%d] - Unexpected error : %s", i, err)
+			}
+
+			obj := interpreter.Environment.Get(outputVariable)
+			if obj == nil || obj.String() != test.expectedOutput {
+				t.Fatalf(engine.name+" : Test: [%d] - Incorrect value, expected=%s, got=%v",
+					i, test.expectedOutput, obj)
+			}
+		}
+	}
+}
+
+func TestMathFuncs(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedOutput string
+	}{
+		{`var output = abs(-5);`, "5"},
+		{`var output = abs(-2.5);`, "2.5"},
+		{`var output = min(3, 1.5, 2);`, "1.5"},
+		{`var output = max(3, 1.5, 2);`, "3"},
+		{`var output = max([4, 9, 2]);`, "9"},
+		{`var output = min(7);`, "7"},
+		{`var output = pow(2, 10);`, "1024"},
+		{`var output = pow(-3, 3);`, "-27"},
+		{`var output = pow(2, 62);`, "4611686018427387904"},
+		{`var output = pow(-2, 63);`, "-9223372036854775808"},
+		{`var output = pow(0, 1000);`, "0"},
+		{`var output = pow(-1, 1001);`, "-1"},
+		{`var output = pow(2, -1);`, "0.5"},
+		{`var output = pow(4, 0.5);`, "2.0"},
+		{`var output = sqrt(16);`, "4.0"},
+		{`var output = floor(2.7);`, "2"},
+		{`var output = floor(-2.5);`, "-3"},
+		{`var output = ceil(2.1);`, "3"},
+		{`var output = round(2.5);`, "3"},
+		{`var output = round(-2.5);`, "-3"},
+		{`var output = round(4);`, "4"},
+		{`var output = sin(0);`, "0.0"},
+		{`var output = cos(0);`, "1.0"},
+		{`var output = tan(0);`, "0.0"},
+		{`var output = round(sin(PI / 2));`, "1"},
+		{`var output = log(E);`, "1.0"},
+		{`var output = exp(0);`, "1.0"},
+		{`var output = format("%.5f", PI);`, "3.14159"},
+		{`
+		seed(42);
+		var a = [random(), randomInt(100), randomInt(-5, 5)];
+		seed(42);
+		var b = [random(), randomInt(100), randomInt(-5, 5)];
+		var output = a[0] == b[0] && a[1] == b[1] && a[2] == b[2];
+		`, "true"},
+		{`
+		var output = true;
+		for (i in range(100)) {
+			var r = random();
+			var n = randomInt(3, 6);
+			if (r < 0 || r >= 1 || n < 3 || n >= 6) {
+				output = false;
+			}
+		}
+		`, "true"},
+	}
+
+	outputVariable := "output"
+	lexer := lexer.New()
+
+	for i, test := range tests {
+		tokens := lexer.Scan(test.input)
+		parser := parser.New(tokens)
+		statements := parser.Parse()
+
+		for _, engine := range engines {
+			interpreter := engine.new(statements, "")
+			if err := interpreter.Start(); err != nil {
+				t.Fatalf(engine.name+" : Test: [%d] - Unexpected error : %s", i, err)
+			}
 
-		// Directly hook into the environment to check for output variable.
-		if interpreter.Environment.Exists(outputVariable) {
 			obj := interpreter.Environment.Get(outputVariable)
-			// The object returns with strings, this should be fixed in the future, but
-			// for the time being this should do well enough for tests
-			if strings.TrimSuffix(obj.String(), "\n") != test.expectedOutput {
-				t.Fatalf("Test: [%d] - Incorrect value, expected=%s, got=%s",
-					i, test.expectedOutput, obj.String())
+			if obj == nil || obj.String() != test.expectedOutput {
+				t.Fatalf(engine.name+" : Test: [%d] - Incorrect value, expected=%s, got=%v",
+					i, test.expectedOutput, obj)
 			}
 		}
 	}
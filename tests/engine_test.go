@@ -0,0 +1,47 @@
+package tests
+
+import (
+	"github.com/lczm/as/ast"
+	"github.com/lczm/as/environment"
+	"github.com/lczm/as/interpreter"
+	"github.com/lczm/as/object"
+	"github.com/lczm/as/vm"
+)
+
+// Every test is run on both the tree-walker and the vm, as both of
+// them have to give back the same results. file is where imports are
+// looked for from, it can be left empty.
+type engine struct {
+	name string
+	new  func(statements []ast.Statement, file string) *program
+}
+
+// What the tests need from either of them, Trace gives back the calls
+// that the error that stopped the program was raised within
+type program struct {
+	Environment *environment.Environment
+	Start       func() error
+	Trace       func() []object.Frame
+}
+
+var engines = []engine{
+	{"interpreter", func(statements []ast.Statement, file string) *program {
+		interpreter := interpreter.New(statements)
+		interpreter.Resolve(statements)
+		interpreter.File = file
+		return &program{
+			Environment: interpreter.Environment,
+			Start:       interpreter.Start,
+			Trace:       func() []object.Frame { return interpreter.Trace },
+		}
+	}},
+	{"vm", func(statements []ast.Statement, file string) *program {
+		machine := vm.New(statements)
+		machine.File = file
+		return &program{
+			Environment: machine.Environment,
+			Start:       machine.Start,
+			Trace:       func() []object.Frame { return machine.Trace },
+		}
+	}},
+}
@@ -0,0 +1,729 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/lczm/as/analysis"
+	"github.com/lczm/as/errors"
+	"github.com/lczm/as/lexer"
+	"github.com/lczm/as/object"
+	"github.com/lczm/as/parser"
+)
+
+func TestSyntaxErrors(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedLines   []int
+		expectedColumns []int
+	}{
+		{
+			`var a = 10 +;`,
+			[]int{1},
+			[]int{13},
+		},
+		{ // Errors in one statement do not stop the others from being checked
+			"var a = ;\nvar b = 2;\nfunction 1(a) {}",
+			[]int{1, 3},
+			[]int{9, 10},
+		},
+		{ // Missing semicolons point to the end of the previous line
+			"var a = 1\nvar b = 2;",
+			[]int{1},
+			[]int{9},
+		},
+		{
+			`1 = 2;`,
+			[]int{1},
+			[]int{3},
+		},
+		{
+			`a & b;`,
+			[]int{1, 1},
+			[]int{3, 5},
+		},
+		{ // Unterminated blocks
+			`if (true) { var a = 1;`,
+			[]int{1},
+			[]int{23},
+		},
+		{
+			`struct Test { 1 }`,
+			[]int{1},
+			[]int{15},
+		},
+		{ // Errors within interpolated expressions point into the string
+			`var a = "x ${1 + }";`,
+			[]int{1},
+			[]int{18},
+		},
+		{ // break and continue only work within loops
+			"break;\nwhile (true) {\n  function f() { continue; }\n}",
+			[]int{1, 3},
+			[]int{1, 18},
+		},
+		{ // Imports are only allowed at the top level
+			"if (true) {\n  import \"a.as\";\n}\nimport a \"b.as\";\nimport 5;",
+			[]int{2, 4, 5},
+			[]int{3, 8, 8},
+		},
+		{ // try needs a catch or a finally, and throw needs a value
+			"try {}\nvar a = 1;\nthrow;\ntry {} catch (1) {}",
+			[]int{2, 3, 4},
+			[]int{1, 6, 15},
+		},
+	}
+
+	lexer := lexer.New()
+	for i, test := range tests {
+		tokens := lexer.Scan(test.input)
+		parser := parser.New(tokens)
+		parser.Parse()
+		errorList := append(lexer.Errors, parser.Errors...)
+
+		if len(errorList) != len(test.expectedLines) {
+			t.Fatalf("Test : [%d] - Mismatch amount of errors, expected=%d, got=%d (%v)",
+				i, len(test.expectedLines), len(errorList), errorList)
+		}
+
+		for j, err := range errorList {
+			if err.Kind() != errors.SYNTAX_ERROR {
+				t.Fatalf("Test : [%d - %d] - Wrong kind, expected=%s, got=%s",
+					i, j, errors.SYNTAX_ERROR, err.Kind())
+			}
+			if err.Span().Line != test.expectedLines[j] || err.Span().Column != test.expectedColumns[j] {
+				t.Fatalf("Test : [%d - %d] - Wrong position, expected=%d:%d, got=%d:%d",
+					i, j, test.expectedLines[j], test.expectedColumns[j],
+					err.Span().Line, err.Span().Column)
+			}
+		}
+	}
+}
+
+func TestSemanticErrors(t *testing.T) {
+	tests := []struct {
+		input            string
+		expectedMessages []string
+		expectedLines    []int
+		expectedColumns  []int
+	}{
+		{
+			"var a = 1;\nprint(b);",
+			[]string{"Undefined variable 'b'"},
+			[]int{2},
+			[]int{7},
+		},
+		{
+			"print(a);\nvar a = 1;",
+			[]string{"Cannot use 'a' before it is declared"},
+			[]int{1},
+			[]int{7},
+		},
+		{ // Locals cannot be used before they are declared either
+			"{\n  a = 2;\n  var a = 1;\n}",
+			[]string{"Cannot use 'a' before it is declared"},
+			[]int{2},
+			[]int{3},
+		},
+		{ // Functions are only run later on, so they can use what is declared after them
+			"function f() { return g() + b; }\nfunction g() { return 1; }\nvar b = 2;",
+			[]string{},
+			[]int{},
+			[]int{},
+		},
+		{
+			"{\n  function f() { return a; }\n  var a = 1;\n}",
+			[]string{},
+			[]int{},
+			[]int{},
+		},
+		{ // Parameters, loop variables, this and super are all known
+			`
+			struct A { get() { return 1; } }
+			struct B : A {
+				var list = [];
+				get(n) { return super.get() + this.list[n]; }
+			}
+			for (i, x in [1, 2]) { print(i + x); }
+			for (var i = 0; i < 2; i++) { print(i); }
+			`,
+			[]string{},
+			[]int{},
+			[]int{},
+		},
+		{ // Variables do not leak out of the scope they are declared in
+			"{\n  var a = 1;\n}\nprint(a);\nfunction f(x) {}\nprint(x);",
+			[]string{"Undefined variable 'a'", "Undefined variable 'x'"},
+			[]int{4, 6},
+			[]int{7, 7},
+		},
+		{
+			"struct A : A {}",
+			[]string{"Cannot use 'A' before it is declared"},
+			[]int{1},
+			[]int{12},
+		},
+		{
+			"var f = x => x + y;\nvar s = \"${z}\";",
+			[]string{"Undefined variable 'y'", "Undefined variable 'z'"},
+			[]int{1, 2},
+			[]int{18, 12},
+		},
+	}
+
+	lexer := lexer.New()
+	for i, test := range tests {
+		tokens := lexer.Scan(test.input)
+		parser := parser.New(tokens)
+		statements := parser.Parse()
+		semanticAnalyzer := analysis.New(statements)
+		semanticAnalyzer.Analyze()
+		errorList := semanticAnalyzer.Errors
+
+		if len(errorList) != len(test.expectedMessages) {
+			t.Fatalf("Test : [%d] - Mismatch amount of errors, expected=%d, got=%d (%v)",
+				i, len(test.expectedMessages), len(errorList), errorList)
+		}
+
+		for j, err := range errorList {
+			if err.Kind() != errors.SEMANTIC_ERROR {
+				t.Fatalf("Test : [%d - %d] - Wrong kind, expected=%s, got=%s",
+					i, j, errors.SEMANTIC_ERROR, err.Kind())
+			}
+			if err.Message() != test.expectedMessages[j] {
+				t.Fatalf("Test : [%d - %d] - Wrong message, expected=%q, got=%q",
+					i, j, test.expectedMessages[j], err.Message())
+			}
+			if err.Span().Line != test.expectedLines[j] || err.Span().Column != test.expectedColumns[j] {
+				t.Fatalf("Test : [%d - %d] - Wrong position, expected=%d:%d, got=%d:%d",
+					i, j, test.expectedLines[j], test.expectedColumns[j],
+					err.Span().Line, err.Span().Column)
+			}
+		}
+	}
+}
+
+func TestShadowWarnings(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedLines []int
+	}{
+		{"var a = 1;\nvar a = 2;", []int{2}},
+		{"function f(a) {\n  var a = 2;\n}", []int{2}},
+		// Declaring a name again in an inner scope is not a redeclaration
+		{"var a = 1;\n{\n  var a = 2;\n}", []int{}},
+		{"for (var i = 0; i < 1; i++) {}\nfor (var i = 0; i < 1; i++) {}", []int{}},
+	}
+
+	lexer := lexer.New()
+	for i, test := range tests {
+		tokens := lexer.Scan(test.input)
+		parser := parser.New(tokens)
+		statements := parser.Parse()
+		semanticAnalyzer := analysis.New(statements)
+		semanticAnalyzer.Analyze()
+		warningList := semanticAnalyzer.Warnings
+
+		if len(warningList) != len(test.expectedLines) {
+			t.Fatalf("Test : [%d] - Mismatch amount of warnings, expected=%d, got=%d (%v)",
+				i, len(test.expectedLines), len(warningList), warningList)
+		}
+		for j, warning := range warningList {
+			if warning.Kind() != errors.SHADOW_WARNING {
+				t.Fatalf("Test : [%d - %d] - Wrong kind, expected=%s, got=%s",
+					i, j, errors.SHADOW_WARNING, warning.Kind())
+			}
+			if warning.Span().Line != test.expectedLines[j] {
+				t.Fatalf("Test : [%d - %d] - Wrong line, expected=%d, got=%d",
+					i, j, test.expectedLines[j], warning.Span().Line)
+			}
+		}
+	}
+}
+
+func TestRuntimeErrors(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+		expectedLine    int
+		expectedColumn  int
+	}{
+		{
+			"var a = 1;\nprint(b);",
+			"Undefined variable 'b'",
+			2, 7,
+		},
+		{
+			"function f() {\n  var get = () => later;\n  get();\n  var later = 5;\n}\nf();",
+			"Undefined variable 'later'",
+			2, 19,
+		},
+		{
+			"function f() {\n  var set = () => { later = 1; };\n  set();\n  var later = 5;\n}\nf();",
+			"Undefined variable 'later'",
+			2, 21,
+		},
+		{
+			`var a = 1 + "a";`,
+			"Unsupported operand types for '+' : INTEGER and STRING",
+			1, 11,
+		},
+		{
+			`var a = 10 / 0;`,
+			"Division by zero",
+			1, 12,
+		},
+		{
+			`var a = [1, 2]; var b = a[2];`,
+			"List index 2 is out of range",
+			1, 26,
+		},
+		{
+			`var a = len(1);`,
+			"len() cannot be used on <type: INTEGER>",
+			1, 12,
+		},
+		{
+			`var a = 1; a();`,
+			"Object of INTEGER cannot be called",
+			1, 13,
+		},
+		{
+			`var l = [1]; l();`,
+			"Object of LIST cannot be called",
+			1, 15,
+		},
+		{
+			`var s = "abc"; s();`,
+			"Object of STRING cannot be called",
+			1, 17,
+		},
+		{
+			"struct A {}\nvar a = A();\na();",
+			"Can only call structs and functions",
+			3, 2,
+		},
+		{
+			"function f(a) { return a; }\nf(1, 2);",
+			"Function : <f> expected 1 arguments but got 2",
+			2, 2,
+		},
+		{ // Errors inside of loops and functions stop the program
+			`
+			function f() {
+				var i = 0;
+				while (true) {
+					i++;
+					if (i == 3) {
+						i = i + "a";
+					}
+				}
+			}
+			f();
+			`,
+			"Unsupported operand types for '+' : INTEGER and STRING",
+			7, 13,
+		},
+		{
+			"function f() {\n  return this;\n}\nf();",
+			"Cannot use 'this' outside of a method",
+			2, 10,
+		},
+		{
+			"struct A {}\nvar a = A();\na.missing();",
+			"Undefined method 'missing' on Struct: <A>",
+			3, 3,
+		},
+		{
+			"struct A {\n  init(a) {}\n}\nvar a = A(1, 2);",
+			"Struct: <A> expected 1 arguments but got 2",
+			4, 10,
+		},
+		{
+			"struct A {}\nvar a = A(1);",
+			"Struct: <A> expected 0 arguments but got 1",
+			2, 10,
+		},
+		{
+			"var a = 1;\nstruct B : a {}",
+			"Parent of a struct has to be a struct, not INTEGER",
+			2, 12,
+		},
+		{
+			"struct A {}\nstruct B : A {\n  f() { return super.g(); }\n}\nB().f();",
+			"Undefined method 'g' on Struct: <A>",
+			3, 22,
+		},
+		{
+			`var a = "value: ${b}";`,
+			"Undefined variable 'b'",
+			1, 19,
+		},
+		{
+			"for (x in 5) {}",
+			"Object of INTEGER cannot be iterated over",
+			1, 8,
+		},
+		{
+			"var a = range(-9223372036854775807, 9223372036854775807);",
+			"range() from -9223372036854775807 to 9223372036854775807 has too many integers",
+			1, 14,
+		},
+		{
+			"var a = range(0, 10, 0);",
+			"range() step cannot be 0",
+			1, 14,
+		},
+		{
+			"var a = upper(1);",
+			"upper() can only be used on strings, not <type: INTEGER>",
+			1, 14,
+		},
+		{
+			"var a = join(\"a\", \",\");",
+			"join() can only join a list, not <type: STRING>",
+			1, 13,
+		},
+		{
+			"var a = substring(\"abc\", 2, 5);",
+			"substring() range 2 to 5 is out of range for a string of length 3",
+			1, 18,
+		},
+		{
+			"var a = repeat(\"ab\", 9223372036854775807);",
+			"repeat() of 2 characters 9223372036854775807 times is too long",
+			1, 15,
+		},
+		{
+			"var a = repeat(\"a\", -1);",
+			"repeat() count cannot be negative",
+			1, 15,
+		},
+		{
+			"var a = format(\"%d\", \"a\");",
+			"format() %d has to be given an integer, not <type: STRING>",
+			1, 15,
+		},
+		{
+			"var a = format(\"%s %s\", 1);",
+			"format() is missing a value for %s",
+			1, 15,
+		},
+		{
+			"var a = format(\"%s\", 1, 2);",
+			"format() was given 2 values but only uses 1",
+			1, 15,
+		},
+		{
+			"var a = ord(\"ab\");",
+			"ord() expected a single character, but got a string of length 2",
+			1, 12,
+		},
+		{
+			"var a = chr(-1);",
+			"chr() -1 is not a valid character",
+			1, 12,
+		},
+		{
+			"var a = sqrt(-1);",
+			"sqrt() cannot be used on a negative number",
+			1, 13,
+		},
+		{
+			"var a = log(0);",
+			"log() can only be used on a positive number",
+			1, 12,
+		},
+		{
+			"var a = max(1, \"2\");",
+			"max() can only be used on numbers, not <type: STRING>",
+			1, 12,
+		},
+		{
+			"var a = min([]);",
+			"min() takes in at least one number, or a list of numbers.",
+			1, 12,
+		},
+		{
+			"var a = pow(2, 1000);",
+			"pow() of 2 and 1000 is too large to be an integer",
+			1, 12,
+		},
+		{
+			"var a = randomInt(5, 5);",
+			"randomInt() range 5 to 5 is empty",
+			1, 18,
+		},
+		{
+			"seed(1.5);",
+			"seed() has to be given an integer, not <type: FLOAT>",
+			1, 5,
+		},
+	}
+
+	lexer := lexer.New()
+	for i, test := range tests {
+		tokens := lexer.Scan(test.input)
+		parser := parser.New(tokens)
+		statements := parser.Parse()
+
+		for _, engine := range engines {
+			interpreter := engine.new(statements, "")
+			err := interpreter.Start()
+			if err == nil {
+				t.Fatalf(engine.name+" : Test : [%d] - Expected an error, got none", i)
+			}
+
+			runtimeError := err.(errors.Error)
+			if runtimeError.Kind() != errors.RUNTIME_ERROR {
+				t.Fatalf(engine.name+" : Test : [%d] - Wrong kind, expected=%s, got=%s",
+					i, errors.RUNTIME_ERROR, runtimeError.Kind())
+			}
+			if runtimeError.Message() != test.expectedMessage {
+				t.Fatalf(engine.name+" : Test : [%d] - Wrong message, expected=%q, got=%q",
+					i, test.expectedMessage, runtimeError.Message())
+			}
+			span := runtimeError.Span()
+			if span.Line != test.expectedLine || span.Column != test.expectedColumn {
+				t.Fatalf(engine.name+" : Test : [%d] - Wrong position, expected=%d:%d, got=%d:%d",
+					i, test.expectedLine, test.expectedColumn, span.Line, span.Column)
+			}
+		}
+	}
+}
+
+func TestCallDepth(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError bool
+	}{
+		{"function f(n) {\n  return f(n + 1);\n}\nf(0);", true},
+		// Calls can be nested right up to the limit
+		{"function f(n) {\n  if (n == 1) { return 1; }\n  return 1 + f(n - 1);\n}\nf(10000);", false},
+		// Going over the limit cannot be caught
+		{"function f(n) {\n  return f(n + 1);\n}\ntry { f(0); } catch {}", true},
+	}
+
+	lexer := lexer.New()
+	for i, test := range tests {
+		tokens := lexer.Scan(test.input)
+		parser := parser.New(tokens)
+		statements := parser.Parse()
+
+		for _, engine := range engines {
+			interpreter := engine.new(statements, "")
+			err := interpreter.Start()
+			if !test.expectedError {
+				if err != nil {
+					t.Fatalf(engine.name+" : Test : [%d] - Unexpected error : %s", i, err)
+				}
+				continue
+			}
+
+			limitError, ok := err.(errors.Error)
+			if !ok || limitError.Kind() != errors.LIMIT_EXCEEDED {
+				t.Fatalf(engine.name+" : Test : [%d] - Expected the call depth to be exceeded, got=%v", i, err)
+			}
+			if limitError.Span().Line != 2 || limitError.Span().Column != 11 {
+				t.Fatalf(engine.name+" : Test : [%d] - Wrong position, expected=2:11, got=%d:%d",
+					i, limitError.Span().Line, limitError.Span().Column)
+			}
+		}
+	}
+}
+
+func TestThrownErrors(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+		expectedLine    int
+		expectedColumn  int
+	}{
+		{`throw "oops";`, "oops", 1, 1},
+		{"function f() {\n  throw [1, 2];\n}\nf();", "[1, 2]", 2, 3},
+		// catch only catches what is raised within try
+		{"try {\n} catch (e) {\n  throw e;\n}\nthrow 1;", "1", 5, 1},
+		{"try {\n  throw 1;\n} catch (e) {\n  throw 2;\n}", "2", 4, 3},
+	}
+
+	lexer := lexer.New()
+	for i, test := range tests {
+		tokens := lexer.Scan(test.input)
+		parser := parser.New(tokens)
+		statements := parser.Parse()
+
+		for _, engine := range engines {
+			interpreter := engine.new(statements, "")
+			err := interpreter.Start()
+
+			thrownError, ok := err.(errors.Error)
+			if !ok || thrownError.Kind() != errors.THROWN_ERROR {
+				t.Fatalf(engine.name+" : Test : [%d] - Expected a thrown error, got=%v", i, err)
+			}
+			if thrownError.Message() != test.expectedMessage {
+				t.Fatalf(engine.name+" : Test : [%d] - Wrong message, expected=%q, got=%q",
+					i, test.expectedMessage, thrownError.Message())
+			}
+			span := thrownError.Span()
+			if span.Line != test.expectedLine || span.Column != test.expectedColumn {
+				t.Fatalf(engine.name+" : Test : [%d] - Wrong position, expected=%d:%d, got=%d:%d",
+					i, test.expectedLine, test.expectedColumn, span.Line, span.Column)
+			}
+		}
+	}
+}
+
+func TestAssertionErrors(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+		expectedLine    int
+		expectedColumn  int
+	}{
+		{"assert(1 > 2);", "assert() failed", 1, 7},
+		{"var a = 1;\nassert(a == 2, \"a is not 2\");", "a is not 2", 2, 7},
+		{`assertEqual(1 + 1, 3);`, "assertEqual() failed : expected 3, got 2", 1, 12},
+		{
+			`assertEqual([1, 2], [1, 2, 3]);`,
+			"assertEqual() failed : expected [1, 2, 3], got [1, 2]", 1, 12,
+		},
+		{`assertEqual("1", 1);`, `assertEqual() failed : expected 1, got "1"`, 1, 12},
+		{
+			"function f() {\n  assertThrows(function() { return 1; });\n}\nf();",
+			"assertThrows() failed : nothing was thrown", 2, 15,
+		},
+		{
+			`assertThrows(function() { throw "a"; }, "b");`,
+			`assertThrows() failed : expected "b" to be thrown, got "a"`, 1, 13,
+		},
+		// Failed assertions can be caught like any other error
+		{
+			"try {\n  assert(false);\n} catch (e) {\n  assert(e.kind == \"RuntimeError\", e.kind);\n}",
+			"AssertionError", 4, 9,
+		},
+	}
+
+	lexer := lexer.New()
+	for i, test := range tests {
+		tokens := lexer.Scan(test.input)
+		parser := parser.New(tokens)
+		statements := parser.Parse()
+
+		for _, engine := range engines {
+			interpreter := engine.new(statements, "")
+			err := interpreter.Start()
+
+			assertionError, ok := err.(errors.Error)
+			if !ok || assertionError.Kind() != errors.ASSERTION_ERROR {
+				t.Fatalf(engine.name+" : Test : [%d] - Expected an assertion error, got=%v", i, err)
+			}
+			if assertionError.Message() != test.expectedMessage {
+				t.Fatalf(engine.name+" : Test : [%d] - Wrong message, expected=%q, got=%q",
+					i, test.expectedMessage, assertionError.Message())
+			}
+			span := assertionError.Span()
+			if span.Line != test.expectedLine || span.Column != test.expectedColumn {
+				t.Fatalf(engine.name+" : Test : [%d] - Wrong position, expected=%d:%d, got=%d:%d",
+					i, test.expectedLine, test.expectedColumn, span.Line, span.Column)
+			}
+		}
+	}
+}
+
+func TestTraceback(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedTrace []object.Frame
+	}{
+		// Errors at the top level are not within any calls
+		{"var a = 1 / 0;", nil},
+		{
+			"function divide(a, b) {\n  return a / b;\n}\nfunction half(a) {\n  return divide(a, 0);\n}\nhalf(1);",
+			[]object.Frame{
+				{Function: "half", Line: 7, File: "main.as"},
+				{Function: "divide", Line: 5, File: "main.as"},
+			},
+		},
+		{ // Functions that builtins call are part of the trace
+			"function f(x) {\n  return x[1];\n}\nmap([[1]], f);",
+			[]object.Frame{{Function: "f", Line: 0, File: "main.as"}},
+		},
+		{ // Attributes are not initialized by a call of their own
+			"struct P {\n  var x = 1 / 0;\n}\nfunction make() {\n  return P();\n}\nmake();",
+			[]object.Frame{{Function: "make", Line: 7, File: "main.as"}},
+		},
+		{ // Only the calls that the error is raised within
+			"function f() {}\nfunction g() {\n  f();\n  throw 1;\n}\ng();",
+			[]object.Frame{{Function: "g", Line: 6, File: "main.as"}},
+		},
+	}
+
+	lexer := lexer.New()
+	for i, test := range tests {
+		tokens := lexer.Scan(test.input)
+		parser := parser.New(tokens)
+		statements := parser.Parse()
+
+		for _, engine := range engines {
+			interpreter := engine.new(statements, "main.as")
+			if err := interpreter.Start(); err == nil {
+				t.Fatalf(engine.name+" : Test : [%d] - Expected an error, got none", i)
+			}
+
+			trace := interpreter.Trace()
+			if len(trace) != len(test.expectedTrace) {
+				t.Fatalf(engine.name+" : Test : [%d] - Mismatch amount of frames, expected=%d, got=%d (%v)",
+					i, len(test.expectedTrace), len(trace), trace)
+			}
+			for j, frame := range trace {
+				if frame != test.expectedTrace[j] {
+					t.Fatalf(engine.name+" : Test : [%d - %d] - Wrong frame, expected=%v, got=%v",
+						i, j, test.expectedTrace[j], frame)
+				}
+			}
+		}
+	}
+}
+
+func TestTracebackString(t *testing.T) {
+	recursion := []object.Frame{{Function: "main", Line: 9, File: "main.as"}}
+	for i := 0; i < 500; i++ {
+		recursion = append(recursion, object.Frame{Function: "f", Line: 2, File: "main.as"})
+	}
+	alternating := make([]object.Frame, 0)
+	for i := 0; i < 30; i++ {
+		alternating = append(alternating, object.Frame{Function: "f", Line: i})
+	}
+
+	tests := []struct {
+		trace    []object.Frame
+		expected string
+	}{
+		{nil, ""},
+		{
+			recursion,
+			"Traceback (most recent call last):\n" +
+				"  main() called from main.as, line 9\n" +
+				"  f() called from main.as, line 2\n" +
+				"  ... repeated 499 more times\n",
+		},
+		{ // Only the ends of long tracebacks are shown
+			alternating,
+			"Traceback (most recent call last):\n" +
+				"  f()\n  f() called from line 1\n  f() called from line 2\n" +
+				"  f() called from line 3\n  f() called from line 4\n  f() called from line 5\n" +
+				"  f() called from line 6\n  f() called from line 7\n  f() called from line 8\n" +
+				"  f() called from line 9\n" +
+				"  ... 10 more calls\n" +
+				"  f() called from line 20\n  f() called from line 21\n  f() called from line 22\n" +
+				"  f() called from line 23\n  f() called from line 24\n  f() called from line 25\n" +
+				"  f() called from line 26\n  f() called from line 27\n  f() called from line 28\n" +
+				"  f() called from line 29\n",
+		},
+	}
+
+	for i, test := range tests {
+		if traceback := object.Traceback(test.trace); traceback != test.expected {
+			t.Fatalf("Test : [%d] - Wrong traceback, expected=%q, got=%q", i, test.expected, traceback)
+		}
+	}
+}
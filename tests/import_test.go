@@ -0,0 +1,205 @@
+package tests
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lczm/as/errors"
+	"github.com/lczm/as/lexer"
+	"github.com/lczm/as/parser"
+)
+
+// Imports are resolved relative to this file, it does not have to exist
+const importingFile = "testdata/imports/test.as"
+
+func TestImports(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedOutput string
+	}{
+		{`import "geometry.as"; var output = geometry.area(2);`, "12"},
+		{`import "geometry.as"; var output = geometry.scale;`, "3"},
+		{`import g from "geometry.as"; var output = g.Point(1, 2).sum();`, "3"},
+		{`import "geometry.as"; var output = geometry;`, "Module: <geometry.as>"},
+		{`import "lib/text.as"; var output = text.shout("hi");`, "HI!"},
+		{`import "./lib/../geometry.as"; var output = geometry.area(1);`, "3"},
+		{`
+		import "counter.as";
+		import c from "counter.as";
+		counter.increment();
+		c.increment();
+		var output = [counter.count, c.loads];
+		`, "[2, 1]"},
+		{`
+		import "counter.as";
+		var from = 1;
+		var output = from;
+		`, "1"},
+	}
+
+	outputVariable := "output"
+	lexer := lexer.New()
+
+	for i, test := range tests {
+		tokens := lexer.Scan(test.input)
+		parser := parser.New(tokens)
+		statements := parser.Parse()
+
+		for _, engine := range engines {
+			interpreter := engine.new(statements, importingFile)
+			if err := interpreter.Start(); err != nil {
+				t.Fatalf(engine.name+" : Test: [%d] - Unexpected error : %s", i, err)
+			}
+
+			obj := interpreter.Environment.Get(outputVariable)
+			if obj == nil || obj.String() != test.expectedOutput {
+				t.Fatalf(engine.name+" : Test: [%d] - Incorrect value, expected=%s, got=%v",
+					i, test.expectedOutput, obj)
+			}
+		}
+	}
+}
+
+func TestImportFromFile(t *testing.T) {
+	name := "testdata/imports/main.as"
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	statements := parser.New(lexer.New().Scan(string(data))).Parse()
+	for _, engine := range engines {
+		interpreter := engine.new(statements, name)
+		if err := interpreter.Start(); err != nil {
+			t.Fatalf(engine.name+" : Unexpected error : %s", err)
+		}
+
+		obj := interpreter.Environment.Get("output")
+		if obj == nil || obj.String() != "12" {
+			t.Fatalf(engine.name+" : Incorrect value, expected=12, got=%v", obj)
+		}
+	}
+}
+
+func TestImportPath(t *testing.T) {
+	directory, err := filepath.Abs("testdata/aspath")
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := os.Getenv("AS_PATH")
+	os.Setenv("AS_PATH", "does-not-exist"+string(os.PathListSeparator)+directory)
+	defer os.Setenv("AS_PATH", previous)
+
+	statements := parser.New(lexer.New().Scan(`import "extra.as"; var output = extra.answer;`)).Parse()
+	for _, engine := range engines {
+		interpreter := engine.new(statements, importingFile)
+		if err := interpreter.Start(); err != nil {
+			t.Fatalf(engine.name+" : Unexpected error : %s", err)
+		}
+
+		obj := interpreter.Environment.Get("output")
+		if obj == nil || obj.String() != "42" {
+			t.Fatalf(engine.name+" : Incorrect value, expected=42, got=%v", obj)
+		}
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{
+			`import "missing.as";`,
+			`Cannot find module "missing.as"`,
+		},
+		{
+			`import "cycle_a.as";`,
+			`Error in "cycle_a.as" : Runtime Error at line '1', column '8' : ` +
+				`Error in "cycle_b.as" : Runtime Error at line '1', column '8' : ` +
+				`Import cycle : cycle_a.as -> cycle_b.as -> cycle_a.as`,
+		},
+		{
+			`import "broken.as";`,
+			`Error in "broken.as" : Syntax Error at line '2', column '12' : Expect expression`,
+		},
+		{
+			`import "failing.as";`,
+			`Error in "failing.as" : Runtime Error at line '2', column '9' : Undefined variable 'missing'`,
+		},
+		{
+			`import "geometry.as"; geometry.perimeter(1);`,
+			`Undefined name 'perimeter' in Module: <geometry.as>`,
+		},
+		{
+			`import "geometry.as"; geometry.scale = 1;`,
+			`Cannot set attribute 'scale' on MODULE`,
+		},
+		{
+			`import "lib";`,
+			`Cannot find module "lib"`,
+		},
+		{
+			`import "cycle-a.as";`,
+			`Cannot name a module after "cycle-a.as", use 'import name from "cycle-a.as";'`,
+		},
+	}
+
+	lexer := lexer.New()
+	for i, test := range tests {
+		tokens := lexer.Scan(test.input)
+		parser := parser.New(tokens)
+		statements := parser.Parse()
+
+		for _, engine := range engines {
+			interpreter := engine.new(statements, importingFile)
+			err := interpreter.Start()
+			if err == nil {
+				t.Fatalf(engine.name+" : Test : [%d] - Expected an error, got none", i)
+			}
+
+			runtimeError := err.(errors.Error)
+			if runtimeError.Message() != test.expectedMessage {
+				t.Fatalf(engine.name+" : Test : [%d] - Wrong message, expected=%q, got=%q",
+					i, test.expectedMessage, runtimeError.Message())
+			}
+		}
+	}
+}
+
+// Importing the file that is running is a cycle as well
+func TestImportSelf(t *testing.T) {
+	statements := parser.New(lexer.New().Scan(`import "cycle_a.as";`)).Parse()
+	expected := `Error in "cycle_a.as" : Runtime Error at line '1', column '8' : ` +
+		`Import cycle : cycle_b.as -> cycle_a.as -> cycle_b.as`
+	for _, engine := range engines {
+		interpreter := engine.new(statements, "testdata/imports/cycle_b.as")
+		err := interpreter.Start()
+		if err == nil {
+			t.Fatalf(engine.name + " : Expected an error, got none")
+		}
+		if err.(errors.Error).Message() != expected {
+			t.Fatalf(engine.name+" : Wrong message, expected=%q, got=%q",
+				expected, err.(errors.Error).Message())
+		}
+	}
+}
+
+// Going over a limit within a module stops the program the same as it
+// would in the file importing it
+func TestImportLimits(t *testing.T) {
+	statements := parser.New(lexer.New().Scan(`import "recursive.as";`)).Parse()
+	for _, engine := range engines {
+		interpreter := engine.new(statements, importingFile)
+		err := interpreter.Start()
+		if err == nil {
+			t.Fatalf(engine.name + " : Expected an error, got none")
+		}
+		if err.(errors.Error).Kind() != errors.LIMIT_EXCEEDED {
+			t.Fatalf(engine.name+" : Wrong kind, expected=%s, got=%s",
+				errors.LIMIT_EXCEEDED, err.(errors.Error).Kind())
+		}
+	}
+}
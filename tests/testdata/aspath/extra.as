@@ -0,0 +1 @@
+var answer = 42;
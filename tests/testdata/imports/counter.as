@@ -0,0 +1,7 @@
+var count = 0;
+var loads = 0;
+loads++;
+
+function increment() {
+    count++;
+}
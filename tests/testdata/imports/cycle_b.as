@@ -0,0 +1 @@
+import "cycle_a.as";
@@ -0,0 +1,19 @@
+var scale = 3;
+
+function area(r) {
+    return r * r * scale;
+}
+
+struct Point {
+    var x;
+    var y;
+
+    init(x, y) {
+        this.x = x;
+        this.y = y;
+    }
+
+    sum() {
+        return this.x + this.y;
+    }
+}
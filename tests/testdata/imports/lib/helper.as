@@ -0,0 +1,3 @@
+function suffix(s) {
+    return s + "!";
+}
@@ -0,0 +1,5 @@
+import "helper.as";
+
+function shout(s) {
+    return helper.suffix(upper(s));
+}
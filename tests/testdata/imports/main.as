@@ -0,0 +1,2 @@
+import "geometry.as";
+var output = geometry.area(2);
@@ -5,6 +5,10 @@ type Token struct {
 	Type    TokenType
 	Literal string
 	Line    int
+	// Column is 1-based and counted in bytes from the start of the line,
+	// Offset is the byte offset of the token from the start of the source.
+	Column int
+	Offset int
 }
 
 // Available Tokens
@@ -61,15 +65,26 @@ const (
 	INCREMENT = "++"
 	DECREMENT = "--"
 
+	// Arrow functions, (a, b) => a + b
+	ARROW = "=>"
+
 	// Booleans
 	TRUE  = "TRUE"
 	FALSE = "FALSE"
 
 	// Numeric values and identifiers
 	NUMBER     = "NUMBER"
+	FLOAT      = "FLOAT"
 	STRING     = "STRING"
 	IDENTIFIER = "IDENTIFIER"
 
+	// The parts of a string around interpolated expressions,
+	// "a ${b} c ${d} e" is split into the start "a ", the middle " c "
+	// and the end " e"
+	INTERPOLATION        = "INTERPOLATION"
+	INTERPOLATION_MIDDLE = "INTERPOLATION_MIDDLE"
+	INTERPOLATION_END    = "INTERPOLATION_END"
+
 	// Statements
 	PRINT    = "PRINT"
 	VAR      = "VAR"
@@ -77,11 +92,22 @@ const (
 	ELSE     = "ELSE"
 	WHILE    = "WHILE"
 	FOR      = "FOR"
+	IN       = "IN"
 	FUNCTION = "FUNCTION"
 	STRUCT   = "STRUCT"
 	RETURN   = "RETURN"
+	BREAK    = "BREAK"
+	CONTINUE = "CONTINUE"
 	THIS     = "THIS"
+	SUPER    = "SUPER"
+	NULL     = "NULL"
+	IMPORT   = "IMPORT"
+	TRY      = "TRY"
+	CATCH    = "CATCH"
+	FINALLY  = "FINALLY"
+	THROW    = "THROW"
 
 	// Misc
-	EOF = "EOF"
+	ILLEGAL = "ILLEGAL"
+	EOF     = "EOF"
 )
@@ -0,0 +1,32 @@
+package vm
+
+import (
+	"github.com/lczm/as/ast"
+	"github.com/lczm/as/environment"
+	"github.com/lczm/as/object"
+	"github.com/lczm/as/token"
+)
+
+// The name that the module is defined under is worked out by the
+// compiler, tok is the path of the import.
+func (vm *VM) importModule(importPath string, tok token.Token) object.Object {
+	return vm.modules.Import(vm.File, importPath, tok,
+		func(path string, statements []ast.Statement) (map[string]object.Object, error) {
+			moduleVM := vm.newModuleVM(path, statements)
+			if err := moduleVM.Start(); err != nil {
+				return nil, err
+			}
+			return moduleVM.Environment.Values, nil
+		})
+}
+
+// Modules are run in an environment of their own, the builtins are kept
+// in the parent environment so that only what the module declares is
+// part of the module.
+func (vm *VM) newModuleVM(path string, statements []ast.Statement) *VM {
+	moduleVM := New(statements)
+	moduleVM.Environment = environment.NewChildEnvironment(moduleVM.Environment)
+	moduleVM.File = path
+	moduleVM.modules = vm.modules
+	return moduleVM
+}
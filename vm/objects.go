@@ -0,0 +1,93 @@
+package vm
+
+import (
+	"fmt"
+
+	"github.com/lczm/as/compiler"
+	"github.com/lczm/as/environment"
+	"github.com/lczm/as/object"
+)
+
+// A compiled function together with the variables it captured.
+// Globals is the environment of the file the function was declared in,
+// functions imported from a module still refer to the globals of that module.
+// File is the file that the function was declared in.
+type Closure struct {
+	Function *compiler.CompiledFunction
+	Upvalues []*Upvalue
+	Globals  *environment.Environment
+	File     string
+}
+
+func (c *Closure) RawType() string {
+	return object.FUNCTION
+}
+
+func (c *Closure) Type() string {
+	return fmt.Sprintf("<type: %s>", object.FUNCTION)
+}
+
+func (c *Closure) String() string {
+	return c.Function.String()
+}
+
+func (c *Closure) FormattedString() string {
+	return c.Function.String()
+}
+
+// A variable captured by a closure. It refers to the slot on the stack
+// while the variable is still in scope, and holds on to the value once
+// the variable goes out of scope.
+type Upvalue struct {
+	index  int
+	open   bool
+	closed object.Object
+}
+
+// A method that has been accessed from an instance, the instance is
+// put into the first slot of the method when it is called.
+type BoundMethod struct {
+	Receiver *object.Struct
+	Method   *Closure
+}
+
+func (bm *BoundMethod) RawType() string {
+	return object.FUNCTION
+}
+
+func (bm *BoundMethod) Type() string {
+	return fmt.Sprintf("<type: %s>", object.FUNCTION)
+}
+
+func (bm *BoundMethod) String() string {
+	return bm.Method.String()
+}
+
+func (bm *BoundMethod) FormattedString() string {
+	return bm.Method.String()
+}
+
+// The iterator of a for-in loop, it is kept on the stack while the loop runs.
+type iteratorState struct {
+	iterator object.Iterator
+	// Whether the loop takes in both the key and the value
+	keyed bool
+	// A single name over a hashmap gets the keys
+	keysOnly bool
+}
+
+func (is *iteratorState) RawType() string {
+	return "ITERATOR"
+}
+
+func (is *iteratorState) Type() string {
+	return "<type: ITERATOR>"
+}
+
+func (is *iteratorState) String() string {
+	return "Iterator"
+}
+
+func (is *iteratorState) FormattedString() string {
+	return "Iterator"
+}
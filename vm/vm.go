@@ -0,0 +1,756 @@
+package vm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lczm/as/ast"
+	"github.com/lczm/as/builtin"
+	"github.com/lczm/as/compiler"
+	"github.com/lczm/as/environment"
+	"github.com/lczm/as/errors"
+	"github.com/lczm/as/modules"
+	"github.com/lczm/as/object"
+	"github.com/lczm/as/token"
+)
+
+// Calls that are nested deeper than this are stopped, the same
+// as the interpreter does by default
+const MAX_FRAMES = 10000
+
+// A function call that is running, base is where the slots of the
+// function start on the stack. The first slot holds the function that
+// is being called, or the instance for methods. line is where it was
+// called from.
+type frame struct {
+	closure *Closure
+	ip      int
+	base    int
+	line    int
+}
+
+// Set by try, errors that are raised while it is set jump to ip within
+// the frame it was set in, with the stack cut back to the slot the
+// error is put into
+type handler struct {
+	frames int
+	ip     int
+	stack  int
+}
+
+// Runs the bytecode from the compiler, it is a stack machine that gives
+// back the same results as the tree-walking interpreter.
+type VM struct {
+	// Globals and the builtin functions
+	Environment *environment.Environment
+	Statements  []ast.Statement
+	// The file that the statements come from, imports are looked for
+	// relative to it.
+	File string
+
+	stack    []object.Object
+	frames   []*frame
+	handlers []handler
+	// Upvalues that still refer to a slot on the stack
+	openUpvalues []*Upvalue
+	// The last value that was popped off of the stack, for testing the
+	// value of an expression statement
+	lastPopped object.Object
+	// Modules that have been imported so far
+	modules *modules.Modules
+	// The calls that the error that stopped Start() was raised within
+	Trace []object.Frame
+}
+
+func New(statements []ast.Statement) *VM {
+	environment := environment.New()
+
+	vm := &VM{
+		Statements:  statements,
+		Environment: environment,
+		stack:       make([]object.Object, 0, 256),
+		frames:      make([]*frame, 0, 64),
+		modules:     modules.New(),
+	}
+
+	// The vm is passed in for the builtins that call functions
+	builtin.PopulateEnvironment(environment, vm)
+	return vm
+}
+
+// Compiles the statements and runs them, stopping at the first runtime error.
+func (vm *VM) Start() error {
+	function, err := compiler.Compile(vm.Statements)
+	if err != nil {
+		return err
+	}
+
+	vm.Trace = nil
+	script := &Closure{Function: function, Globals: vm.Environment, File: vm.File}
+	if errorObj, ok := vm.call(script, nil, token.Token{}).(*object.Error); ok {
+		vm.Trace = errorObj.Trace
+		return errorObj.Err
+	}
+	return nil
+}
+
+func (vm *VM) LastPopped() object.Object {
+	return vm.lastPopped
+}
+
+// This is for builtin functions that take in functions as arguments,
+// i.e. map(list, function(x) { return x * 2; })
+// This implements object.Caller
+func (vm *VM) CallFunction(function object.Object, arguments []object.Object) object.Object {
+	return vm.call(function, arguments, token.Token{})
+}
+
+// The calls that are running, outermost first, the first frame is the
+// top level of the file and not a call. Attribute initializers are run
+// as functions, but are not calls that the script made.
+// This implements object.Caller
+func (vm *VM) CallStack() []object.Frame {
+	calls := make([]object.Frame, 0, len(vm.frames))
+	for index := 1; index < len(vm.frames); index++ {
+		if vm.frames[index].closure.Function.Attributes {
+			continue
+		}
+		name := vm.frames[index].closure.Function.Name
+		if name == "" {
+			name = "anonymous"
+		}
+		calls = append(calls, object.Frame{
+			Function: name,
+			Line:     vm.frames[index].line,
+			File:     vm.frames[index-1].closure.File,
+		})
+	}
+	return calls
+}
+
+// Holds on to the calls that an error was raised within, before the
+// frames are unwound. Errors that pass through builtins already have them.
+func (vm *VM) trace(errorObj *object.Error) {
+	if errorObj.Trace == nil {
+		errorObj.Trace = vm.CallStack()
+	}
+}
+
+// Calls a function and runs it until it returns, tok is where the function
+// is being called from. Whatever the call left on the stack is taken off
+// of it if it fails.
+func (vm *VM) call(function object.Object, arguments []object.Object, tok token.Token) object.Object {
+	base := len(vm.stack)
+	depth := len(vm.frames)
+
+	vm.push(function)
+	for _, argument := range arguments {
+		vm.push(argument)
+	}
+
+	err := vm.callValue(len(arguments), tok)
+	if err == nil && len(vm.frames) > depth {
+		err = vm.run(depth)
+	}
+	if err != nil {
+		vm.closeUpvalues(base)
+		vm.frames = vm.frames[:depth]
+		vm.stack = vm.stack[:base]
+		return err
+	}
+	return vm.pop()
+}
+
+var operators = map[compiler.Opcode]token.Token{
+	compiler.OpAdd:          {Type: token.PLUS, Literal: token.PLUS},
+	compiler.OpSubtract:     {Type: token.MINUS, Literal: token.MINUS},
+	compiler.OpMultiply:     {Type: token.ASTERISK, Literal: token.ASTERISK},
+	compiler.OpDivide:       {Type: token.SLASH, Literal: token.SLASH},
+	compiler.OpModulus:      {Type: token.MODULUS, Literal: token.MODULUS},
+	compiler.OpEqual:        {Type: token.EQ, Literal: token.EQ},
+	compiler.OpNotEqual:     {Type: token.NOT_EQ, Literal: token.NOT_EQ},
+	compiler.OpGreater:      {Type: token.GT, Literal: token.GT},
+	compiler.OpGreaterEqual: {Type: token.GT_EQ, Literal: token.GT_EQ},
+	compiler.OpLess:         {Type: token.LT, Literal: token.LT},
+	compiler.OpLessEqual:    {Type: token.LT_EQ, Literal: token.LT_EQ},
+	compiler.OpMinus:        {Type: token.MINUS, Literal: token.MINUS},
+	compiler.OpNot:          {Type: token.BANG, Literal: token.BANG},
+}
+
+// Runs instructions until the frames return back down to depth.
+// Errors raised within a try are caught by it, the rest are returned
+// as an *object.Error, and leave the stack as it was when the error
+// took place.
+func (vm *VM) run(depth int) *object.Error {
+	for {
+		err := vm.execute(depth)
+		if err == nil {
+			return nil
+		}
+		if !vm.catch(err, depth) {
+			vm.trace(err)
+			return err
+		}
+	}
+}
+
+// Unwinds to the innermost handler that was set above depth, and puts
+// the error into its slot. The handlers below depth belong to the run
+// that called the builtin that this run is in.
+func (vm *VM) catch(err *object.Error, depth int) bool {
+	count := len(vm.handlers)
+	if count == 0 || vm.handlers[count-1].frames <= depth {
+		return false
+	}
+	if !err.Catchable() {
+		for count > 0 && vm.handlers[count-1].frames > depth {
+			count--
+		}
+		vm.handlers = vm.handlers[:count]
+		return false
+	}
+
+	handler := vm.handlers[count-1]
+	vm.handlers = vm.handlers[:count-1]
+	vm.frames = vm.frames[:handler.frames]
+	vm.closeUpvalues(handler.stack)
+	vm.stack = vm.stack[:handler.stack]
+	vm.push(object.NewException(err))
+	vm.frames[handler.frames-1].ip = handler.ip
+	return true
+}
+
+func (vm *VM) execute(depth int) *object.Error {
+	frame := vm.frames[len(vm.frames)-1]
+
+	for {
+		function := frame.closure.Function
+		ins := function.Instructions
+		ip := frame.ip
+		op := compiler.Opcode(ins[ip])
+		frame.ip++
+
+		switch op {
+		case compiler.OpConstant:
+			vm.push(function.Constants[vm.readOperand(frame)])
+		case compiler.OpNull:
+			vm.push(object.NullValue)
+		case compiler.OpUndefined:
+			vm.push(nil)
+		case compiler.OpTrue:
+			vm.push(&object.Bool{Value: true})
+		case compiler.OpFalse:
+			vm.push(&object.Bool{Value: false})
+		case compiler.OpPop:
+			vm.lastPopped = vm.pop()
+
+		// --- Variables
+		case compiler.OpDefineGlobal:
+			name := vm.readName(frame)
+			frame.closure.Globals.Define(name, vm.pop())
+		case compiler.OpGetGlobal:
+			name := vm.readName(frame)
+			value := frame.closure.Globals.Get(name)
+			if value == nil {
+				return newError(function.Position(ip), "Undefined variable '%s'", name)
+			}
+			vm.push(value)
+		case compiler.OpSetGlobal:
+			name := vm.readName(frame)
+			if !frame.closure.Globals.Set(name, vm.peek()) {
+				return newError(function.Position(ip), "Undefined variable '%s'", name)
+			}
+		case compiler.OpGetLocal:
+			vm.push(vm.stack[frame.base+vm.readOperand(frame)])
+		case compiler.OpSetLocal:
+			vm.stack[frame.base+vm.readOperand(frame)] = vm.peek()
+		case compiler.OpGetUpvalue:
+			upvalue := frame.closure.Upvalues[vm.readOperand(frame)]
+			value := upvalue.closed
+			if upvalue.open {
+				value = vm.stack[upvalue.index]
+			}
+			if value == nil {
+				return newError(function.Position(ip), "Undefined variable '%s'", function.Position(ip).Literal)
+			}
+			vm.push(value)
+		case compiler.OpSetUpvalue:
+			upvalue := frame.closure.Upvalues[vm.readOperand(frame)]
+			current := upvalue.closed
+			if upvalue.open {
+				current = vm.stack[upvalue.index]
+			}
+			if current == nil {
+				return newError(function.Position(ip), "Undefined variable '%s'", function.Position(ip).Literal)
+			}
+			if upvalue.open {
+				vm.stack[upvalue.index] = vm.peek()
+			} else {
+				upvalue.closed = vm.peek()
+			}
+		case compiler.OpCloseUpvalue:
+			vm.closeUpvalues(len(vm.stack) - 1)
+			vm.pop()
+
+		// --- Operators
+		case compiler.OpAdd, compiler.OpSubtract, compiler.OpMultiply, compiler.OpDivide,
+			compiler.OpModulus, compiler.OpEqual, compiler.OpNotEqual, compiler.OpGreater,
+			compiler.OpGreaterEqual, compiler.OpLess, compiler.OpLessEqual:
+			right := vm.pop()
+			left := vm.pop()
+			result, message := object.BinaryOperation(operators[op], left, right)
+			if message != "" {
+				return newError(function.Position(ip), "%s", message)
+			}
+			vm.push(result)
+		case compiler.OpMinus, compiler.OpNot:
+			result, message := object.UnaryOperation(operators[op], vm.pop())
+			if message != "" {
+				return newError(function.Position(ip), "%s", message)
+			}
+			vm.push(result)
+		case compiler.OpTruthy:
+			vm.push(&object.Bool{Value: object.IsTruthy(vm.pop())})
+
+		// --- Jumps
+		case compiler.OpJump:
+			frame.ip = vm.readOperand(frame)
+		case compiler.OpJumpIfFalse:
+			target := vm.readOperand(frame)
+			if !object.IsTruthy(vm.pop()) {
+				frame.ip = target
+			}
+
+		// --- Functions
+		case compiler.OpCall:
+			arguments := int(ins[frame.ip])
+			frame.ip++
+			if err := vm.callValue(arguments, function.Position(ip)); err != nil {
+				return err
+			}
+			frame = vm.frames[len(vm.frames)-1]
+		case compiler.OpReturn:
+			result := vm.pop()
+			vm.closeUpvalues(frame.base)
+			vm.stack = vm.stack[:frame.base]
+			vm.frames = vm.frames[:len(vm.frames)-1]
+			vm.push(result)
+			if len(vm.frames) == depth {
+				return nil
+			}
+			frame = vm.frames[len(vm.frames)-1]
+		case compiler.OpClosure:
+			compiled := function.Constants[vm.readOperand(frame)].(*compiler.CompiledFunction)
+			closure := &Closure{
+				Function: compiled,
+				Upvalues: make([]*Upvalue, vm.readOperand(frame)),
+				Globals:  frame.closure.Globals,
+				File:     frame.closure.File,
+			}
+			for i := range closure.Upvalues {
+				isLocal := ins[frame.ip] == 1
+				index := int(compiler.ReadUint16(ins[frame.ip+1:]))
+				frame.ip += 3
+				if isLocal {
+					closure.Upvalues[i] = vm.captureUpvalue(frame.base + index)
+				} else {
+					closure.Upvalues[i] = frame.closure.Upvalues[index]
+				}
+			}
+			vm.push(closure)
+
+		// --- Containers
+		case compiler.OpList:
+			count := vm.readOperand(frame)
+			elements := make([]object.Object, count)
+			copy(elements, vm.stack[len(vm.stack)-count:])
+			vm.stack = vm.stack[:len(vm.stack)-count]
+			vm.push(&object.List{Value: elements})
+		case compiler.OpHashMap:
+			count := vm.readOperand(frame)
+			values := vm.stack[len(vm.stack)-count*2:]
+			hashMap := make(map[object.HashKey]object.HashValue)
+			for i := 0; i < count*2; i += 2 {
+				hashable, ok := values[i].(object.Hashable)
+				if !ok {
+					return newError(function.Position(ip), "Object of %s cannot be used as a hashmap key",
+						values[i].RawType())
+				}
+				hashMap[hashable.Hash()] = object.HashValue{Key: values[i], Value: values[i+1]}
+			}
+			vm.stack = vm.stack[:len(vm.stack)-count*2]
+			vm.push(&object.HashMap{Value: hashMap})
+		case compiler.OpInterpolate:
+			count := vm.readOperand(frame)
+			var value strings.Builder
+			for _, part := range vm.stack[len(vm.stack)-count:] {
+				value.WriteString(part.String())
+			}
+			vm.stack = vm.stack[:len(vm.stack)-count]
+			vm.push(&object.String{Value: value.String()})
+		case compiler.OpSetIndex:
+			container := vm.pop()
+			index := vm.pop()
+			if message := object.SetIndexOperation(container, index, vm.peek()); message != "" {
+				return newError(function.Position(ip), "%s", message)
+			}
+
+		// --- Structs
+		case compiler.OpStruct:
+			vm.push(&object.Struct{
+				Name:       vm.readName(frame),
+				Attributes: make(map[string]object.Object),
+				Methods:    make(map[string]object.Object),
+			})
+		case compiler.OpInherit:
+			structObject := vm.pop().(*object.Struct)
+			parent, ok := vm.peek().(*object.Struct)
+			if !ok {
+				return newError(function.Position(ip), "Parent of a struct has to be a struct, not %s",
+					vm.peek().RawType())
+			}
+			structObject.Parent = parent
+			structObject.HasInit = parent.HasInit
+		case compiler.OpMethod:
+			name := vm.readName(frame)
+			method := vm.pop()
+			structObject := vm.peek().(*object.Struct)
+			structObject.Methods[name] = method
+			if name == "init" {
+				structObject.HasInit = true
+			}
+		case compiler.OpAttributes:
+			initialize := vm.pop()
+			vm.peek().(*object.Struct).Initialize = initialize
+		case compiler.OpGetAttribute:
+			name := vm.readName(frame)
+			isMethod := ins[frame.ip] == 1
+			frame.ip++
+			result := vm.getAttribute(vm.pop(), name, isMethod, function.Position(ip))
+			if errorObj, ok := result.(*object.Error); ok {
+				return errorObj
+			}
+			vm.push(result)
+		case compiler.OpSetAttribute:
+			name := vm.readName(frame)
+			obj := vm.pop()
+			structObject, ok := obj.(*object.Struct)
+			if !ok {
+				return newError(function.Position(ip), "Cannot set attribute '%s' on %s",
+					name, obj.RawType())
+			}
+			structObject.Attributes[name] = vm.peek()
+		case compiler.OpGetSuper:
+			name := vm.readName(frame)
+			instance := vm.pop().(*object.Struct)
+			parent := vm.pop().(*object.Struct)
+			method, ok := parent.FindMethod(name)
+			if !ok {
+				return newError(function.Position(ip), "Undefined method '%s' on %s",
+					name, parent.String())
+			}
+			vm.push(&BoundMethod{Receiver: instance, Method: method.(*Closure)})
+
+		// --- for-in loops
+		case compiler.OpIterator:
+			keyed := ins[frame.ip] == 1
+			frame.ip++
+			iterable := vm.pop()
+			iterator, err := vm.iterator(iterable, function.Position(ip))
+			if err != nil {
+				return err
+			}
+			_, isHashMap := iterable.(*object.HashMap)
+			vm.push(&iteratorState{iterator: iterator, keyed: keyed, keysOnly: isHashMap && !keyed})
+		case compiler.OpIterate:
+			target := vm.readOperand(frame)
+			state := vm.pop().(*iteratorState)
+			key, value, ok := state.iterator.Next()
+			if !ok {
+				frame.ip = target
+				continue
+			}
+			if errorObj, ok := value.(*object.Error); ok {
+				return errorObj
+			}
+			if state.keyed {
+				vm.push(key)
+				vm.push(value)
+			} else if state.keysOnly {
+				vm.push(key)
+			} else {
+				vm.push(value)
+			}
+
+		case compiler.OpImport:
+			path := function.Constants[vm.readOperand(frame)].(*object.String).Value
+			module := vm.importModule(path, function.Position(ip))
+			if errorObj, ok := module.(*object.Error); ok {
+				return errorObj
+			}
+			vm.push(module)
+		case compiler.OpTry:
+			target := vm.readOperand(frame)
+			slot := vm.readOperand(frame)
+			vm.handlers = append(vm.handlers, handler{
+				frames: len(vm.frames),
+				ip:     target,
+				stack:  frame.base + slot,
+			})
+		case compiler.OpEndTry:
+			vm.handlers = vm.handlers[:len(vm.handlers)-1]
+		case compiler.OpThrow:
+			return object.Throw(vm.pop(), function.Position(ip))
+		case compiler.OpError:
+			message := function.Constants[vm.readOperand(frame)].(*object.String).Value
+			return newError(function.Position(ip), "%s", message)
+		default:
+			return newError(function.Position(ip), "Unknown opcode %d", op)
+		}
+	}
+}
+
+// Calls the value that is below the arguments on the stack. Functions
+// get a frame of their own, everything else is called straight away and
+// replaced with its result.
+func (vm *VM) callValue(count int, tok token.Token) *object.Error {
+	base := len(vm.stack) - count - 1
+	arguments := vm.stack[base+1:]
+
+	var result object.Object
+	switch callee := vm.stack[base].(type) {
+	case *Closure:
+		return vm.callClosure(callee, base, count, tok)
+	case *BoundMethod:
+		vm.stack[base] = callee.Receiver
+		return vm.callClosure(callee.Method, base, count, tok)
+	case *object.BuiltinFunction:
+		// The arguments are copied, as builtins can call functions that
+		// grow the stack from underneath them
+		copied := make([]object.Object, count)
+		copy(copied, arguments)
+		result = callee.Fn(copied...)
+
+		// Builtin functions do not know where they are called from,
+		// so point their errors to the call instead
+		if errorObj, ok := result.(*object.Error); ok {
+			if errorObj.Err.Span().Line == 0 {
+				return &object.Error{Err: errors.Locate(errorObj.Err, tok)}
+			}
+			return errorObj
+		}
+		if returnObj, ok := result.(*object.Return); ok {
+			result = returnObj.Value
+		}
+	case *object.Struct:
+		if callee.Instance {
+			return newError(tok, "Can only call structs and functions")
+		}
+		copied := make([]object.Object, count)
+		copy(copied, arguments)
+		result = vm.instantiate(callee, copied, tok)
+		if errorObj, ok := result.(*object.Error); ok {
+			return errorObj
+		}
+	// Indexing is parsed as a call, (List)[1]
+	case *object.List, *object.HashMap, *object.String:
+		if count == 0 {
+			return newError(tok, "Object of %s cannot be called", callee.RawType())
+		}
+		obj, message := object.IndexOperation(callee, arguments[0])
+		if message != "" {
+			return newError(tok, "%s", message)
+		}
+		result = obj
+	default:
+		if tok.Type == token.LBRACKET {
+			return newError(tok, "Object of %s cannot be indexed", callee.RawType())
+		}
+		return newError(tok, "Object of %s cannot be called", callee.RawType())
+	}
+
+	if result == nil {
+		result = object.NullValue
+	}
+	vm.stack = vm.stack[:base]
+	vm.push(result)
+	return nil
+}
+
+func (vm *VM) callClosure(closure *Closure, base int, count int, tok token.Token) *object.Error {
+	if count != closure.Function.NumParams {
+		return newError(tok, "%s expected %d arguments but got %d",
+			closure.String(), closure.Function.NumParams, count)
+	}
+	// The first frame is the top level of the file, and not a call
+	if len(vm.frames) > MAX_FRAMES {
+		return &object.Error{
+			Err: errors.NewLimitExceeded(tok, fmt.Sprintf("Exceeded the limit of %d nested calls", MAX_FRAMES)),
+		}
+	}
+	vm.frames = append(vm.frames, &frame{closure: closure, base: base, line: tok.Line})
+	return nil
+}
+
+func (vm *VM) getAttribute(obj object.Object, name string, isMethod bool, tok token.Token) object.Object {
+	switch obj := obj.(type) {
+	case *object.Struct:
+		// Attributes take precedence over methods, so that a function
+		// stored in an attribute can still be called
+		if value, ok := obj.Attributes[name]; ok {
+			return value
+		}
+
+		// Methods are bound to the instance that they are accessed from
+		if method, ok := obj.FindMethod(name); ok {
+			return &BoundMethod{Receiver: obj, Method: method.(*Closure)}
+		}
+
+		if isMethod {
+			return newError(tok, "Undefined method '%s' on %s", name, obj.String())
+		}
+		return newError(tok, "Undefined attribute '%s' on %s", name, obj.String())
+	case *object.Module:
+		if value, ok := obj.Values[name]; ok {
+			return value
+		}
+		return newError(tok, "Undefined name '%s' in %s", name, obj.String())
+	case *object.Exception:
+		if value, ok := obj.Attribute(name); ok {
+			return value
+		}
+		return newError(tok, "Undefined attribute '%s' on %s", name, obj.RawType())
+	default:
+		return newError(tok, "Object of %s has no attribute '%s'", obj.RawType(), name)
+	}
+}
+
+// Creates a new instance of a struct, tok is where the struct is
+// being instantiated from.
+func (vm *VM) instantiate(structObject *object.Struct,
+	arguments []object.Object, tok token.Token) object.Object {
+
+	// The number of arguments has to match up with the init method,
+	// structs without one do not take in any arguments
+	expected := 0
+	initMethod, hasInit := structObject.FindMethod("init")
+	if hasInit {
+		expected = initMethod.(*Closure).Function.NumParams
+	}
+	if len(arguments) != expected {
+		return newError(tok, "%s expected %d arguments but got %d",
+			structObject.String(), expected, len(arguments))
+	}
+
+	instance := &object.Struct{
+		Name:       structObject.Name,
+		Parent:     structObject.Parent,
+		HasInit:    structObject.HasInit,
+		Attributes: make(map[string]object.Object),
+		Methods:    structObject.Methods,
+		Instance:   true,
+	}
+
+	if obj := vm.initializeAttributes(instance, structObject, tok); obj != nil {
+		return obj
+	}
+
+	// Whatever init returns is discarded, the instance is always
+	// what is given back
+	if hasInit {
+		obj := vm.call(&BoundMethod{Receiver: instance, Method: initMethod.(*Closure)}, arguments, tok)
+		if isError(obj) {
+			return obj
+		}
+	}
+	return instance
+}
+
+// Runs the attribute initializers of a struct for a new instance.
+// The parent attributes are initialized first so that the struct can
+// override them.
+func (vm *VM) initializeAttributes(instance *object.Struct, structObject *object.Struct,
+	tok token.Token) object.Object {
+
+	if structObject.Parent != nil {
+		if obj := vm.initializeAttributes(instance, structObject.Parent, tok); obj != nil {
+			return obj
+		}
+	}
+
+	initialize, ok := structObject.Initialize.(*Closure)
+	if !ok {
+		return nil
+	}
+	obj := vm.call(&BoundMethod{Receiver: instance, Method: initialize}, nil, tok)
+	if isError(obj) {
+		return obj
+	}
+	return nil
+}
+
+// --- Upvalues
+
+func (vm *VM) captureUpvalue(index int) *Upvalue {
+	for _, upvalue := range vm.openUpvalues {
+		if upvalue.index == index {
+			return upvalue
+		}
+	}
+
+	upvalue := &Upvalue{index: index, open: true}
+	vm.openUpvalues = append(vm.openUpvalues, upvalue)
+	return upvalue
+}
+
+// Moves the values of the upvalues at or above the slot off of the stack
+func (vm *VM) closeUpvalues(from int) {
+	open := vm.openUpvalues[:0]
+	for _, upvalue := range vm.openUpvalues {
+		if upvalue.index >= from {
+			upvalue.closed = vm.stack[upvalue.index]
+			upvalue.open = false
+			continue
+		}
+		open = append(open, upvalue)
+	}
+	vm.openUpvalues = open
+}
+
+// --- Utility functions
+
+func (vm *VM) push(obj object.Object) {
+	vm.stack = append(vm.stack, obj)
+}
+
+func (vm *VM) pop() object.Object {
+	obj := vm.stack[len(vm.stack)-1]
+	vm.stack = vm.stack[:len(vm.stack)-1]
+	return obj
+}
+
+func (vm *VM) peek() object.Object {
+	return vm.stack[len(vm.stack)-1]
+}
+
+func (vm *VM) readOperand(frame *frame) int {
+	operand := int(compiler.ReadUint16(frame.closure.Function.Instructions[frame.ip:]))
+	frame.ip += 2
+	return operand
+}
+
+func (vm *VM) readName(frame *frame) string {
+	return frame.closure.Function.Constants[vm.readOperand(frame)].(*object.String).Value
+}
+
+// Creates a runtime error that points to the token it took place at
+func newError(tok token.Token, format string, a ...interface{}) *object.Error {
+	return &object.Error{
+		Err: errors.NewRuntimeError(tok, fmt.Sprintf(format, a...)),
+	}
+}
+
+func isError(obj object.Object) bool {
+	_, ok := obj.(*object.Error)
+	return ok
+}